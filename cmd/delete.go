@@ -2,11 +2,12 @@ package main
 
 import (
 	"context"
-	"time"
+	"fmt"
 
-	"github.com/adelowo/sdump"
 	"github.com/adelowo/sdump/config"
 	sdumpSql "github.com/adelowo/sdump/datastore/sql"
+	"github.com/adelowo/sdump/server/httpd"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -15,20 +16,34 @@ func createDeleteCommand(rootCmd *cobra.Command, cfg *config.Config) {
 		Use:     "delete-http",
 		Aliases: []string{"d"},
 		Short:   "Deletes all old HTTP requests to preserve DB space",
+		Long: `Deletes all old HTTP requests to preserve DB space. The HTTP server already does
+this every cron.interval so this is only needed if it is disabled`,
 		RunE: func(_ *cobra.Command, _ []string) error {
 			db, err := sdumpSql.New(cfg.HTTP.Database)
 			if err != nil {
 				return err
 			}
 
-			ingestStore := sdumpSql.NewIngestRepository(db)
+			defer db.Close()
 
-			before := time.Now().Add(-1 * cfg.Cron.TTL)
+			pruner := httpd.NewPruner(cfg.Cron,
+				sdumpSql.NewIngestRepository(db),
+				sdumpSql.NewPlanRepositoryTable(db),
+				sdumpSql.NewURLRepositoryTable(db),
+				sdumpSql.NewLocker(db),
+				logrus.WithField("module", "delete-http"))
 
-			return ingestStore.Delete(context.Background(), &sdump.DeleteIngestedRequestOptions{
-				Before:         before,
-				UseSoftDeletes: cfg.Cron.SoftDeletes,
-			})
+			// the lock keeps this from racing an HTTP server that is
+			// pruning at the same time
+			result, err := pruner.PruneWithLock(context.Background())
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("deleted %d requests past the ttl, %d past their plan's retention, %d past their endpoint's retention and purged %d\n",
+				result.TTL, result.Plan, result.Endpoint, result.Purged)
+
+			return nil
 		},
 	}
 
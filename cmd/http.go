@@ -2,18 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/adelowo/sdump"
 	"github.com/adelowo/sdump/config"
 	sdumpSql "github.com/adelowo/sdump/datastore/sql"
+	"github.com/adelowo/sdump/internal/pubsub"
 	"github.com/adelowo/sdump/server/httpd"
 	"github.com/r3labs/sse/v2"
+	"github.com/sethvargo/go-limiter"
 	"github.com/sethvargo/go-limiter/memorystore"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/uptrace/bun"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -28,6 +34,10 @@ func createHTTPCommand(cmd *cobra.Command, cfg *config.Config) {
 		Use:   "http",
 		Short: "Start/run the HTTP server",
 		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := cfg.HTTP.Validate(); err != nil {
+				return err
+			}
+
 			sig := make(chan os.Signal, 1)
 
 			signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
@@ -53,26 +63,20 @@ func createHTTPCommand(cmd *cobra.Command, cfg *config.Config) {
 			logrus.SetOutput(os.Stdout)
 			logrus.SetLevel(lvl)
 
-			var tokensPerMinute uint64 = 60
-			if cfg.HTTP.RateLimit.RequestsPerMinute > 0 {
-				tokensPerMinute = cfg.HTTP.RateLimit.RequestsPerMinute
-			}
-
-			ratelimitStore, err := memorystore.New(&memorystore.Config{
-				Tokens:   tokensPerMinute,
-				Interval: time.Minute,
-			})
+			db, err := sdumpSql.New(cfg.HTTP.Database)
 			if err != nil {
 				return err
 			}
 
-			db, err := sdumpSql.New(cfg.HTTP.Database)
-			if err != nil {
-				return err
+			// the database is opened first so an in-memory sqlite database
+			// outlives the migrator's connection
+			if cfg.HTTP.Database.AutoMigrate {
+				if err := withMigrator(cfg, func(migrator *sdumpSql.Migrator) error {
+					return migrator.Up()
+				}); err != nil {
+					return err
+				}
 			}
-			urlStore := sdumpSql.NewURLRepositoryTable(db)
-			ingestStore := sdumpSql.NewIngestRepository(db)
-			userStore := sdumpSql.NewUserRepositoryTable(db)
 
 			hostName, err := os.Hostname()
 			if err != nil {
@@ -82,34 +86,19 @@ func createHTTPCommand(cmd *cobra.Command, cfg *config.Config) {
 			logger := logrus.WithField("host", hostName).
 				WithField("module", "http.server")
 
-			sseServer := sse.New()
-
-			httpServer := httpd.New(*cfg, urlStore, ingestStore,
-				userStore, logger, sseServer, ratelimitStore)
-
-			go func() {
-				logger.Debug("starting HTTP server")
-				if err := httpServer.ListenAndServe(); err != nil {
-					logger.WithError(err).Fatal("could not start http server")
-				}
-			}()
+			stopHTTPServer := startHTTPServer(cfg, db, newPubSub(cfg, db), logger)
 
 			<-sig
 			if err := cleanupFn(context.Background()); err != nil {
 				logger.WithError(err).Error("could not properly shut down OTEL")
 			}
 
-			if err := httpServer.Shutdown(context.Background()); err != nil {
-				logger.WithError(err).Error("could not shut down http server")
-			}
+			stopHTTPServer()
 
 			if err := db.Close(); err != nil {
 				logger.WithError(err).Error("could not shut down database connection")
 			}
 
-			sseServer.Close()
-			ratelimitStore.Close(context.Background())
-
 			return nil
 		},
 	}
@@ -117,6 +106,99 @@ func createHTTPCommand(cmd *cobra.Command, cfg *config.Config) {
 	cmd.AddCommand(command)
 }
 
+// forwardDrainTimeout is how long shutting down waits for queued requests
+// to be forwarded
+const forwardDrainTimeout = 30 * time.Second
+
+// startHTTPServer serves the API, the endpoints and the SSE stream and
+// prunes old requests in the background. The returned function shuts the
+// server down along with what was started with it
+func startHTTPServer(cfg *config.Config, db *bun.DB, pubSub sdump.PubSub, logger *logrus.Entry) func() {
+	rateLimiter := httpd.NewRateLimiter(func(tokensPerMinute uint64) (limiter.Store, error) {
+		return newRateLimitStore(cfg, db, tokensPerMinute)
+	})
+
+	urlStore := sdumpSql.NewURLRepositoryTable(db)
+	ingestStore := sdumpSql.NewIngestRepository(db)
+	userStore := sdumpSql.NewUserRepositoryTable(db)
+	apiTokenStore := sdumpSql.NewAPITokenRepositoryTable(db)
+	planStore := sdumpSql.NewPlanRepositoryTable(db)
+
+	sseServer := sse.New()
+
+	forwarder := httpd.NewForwarder(cfg.HTTP.Forwarding.Workers,
+		cfg.HTTP.Forwarding.QueueSize)
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+
+	go func() {
+		if err := httpd.Relay(relayCtx, pubSub, sseServer, ingestStore, logger); err != nil {
+			logger.WithError(err).Fatal("could not subscribe to ingested requests")
+		}
+	}()
+
+	pruneCtx, stopPruner := context.WithCancel(context.Background())
+
+	go httpd.NewPruner(cfg.Cron, ingestStore, planStore, urlStore,
+		sdumpSql.NewLocker(db), logger).Run(pruneCtx)
+
+	httpServer := httpd.New(*cfg, urlStore, ingestStore,
+		userStore, apiTokenStore, planStore, logger, sseServer, pubSub, rateLimiter, forwarder)
+
+	go func() {
+		logger.Debug("starting HTTP server")
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.WithError(err).Fatal("could not start http server")
+		}
+	}()
+
+	return func() {
+		if err := httpServer.Shutdown(context.Background()); err != nil {
+			logger.WithError(err).Error("could not shut down http server")
+		}
+
+		// queued requests are forwarded before the relay stops so TUI
+		// sessions still see how the targets responded
+		drainCtx, cancel := context.WithTimeout(context.Background(), forwardDrainTimeout)
+		defer cancel()
+
+		if err := forwarder.Close(drainCtx); err != nil {
+			logger.WithError(err).Error("could not forward every queued request before shutting down")
+		}
+
+		stopRelay()
+		stopPruner()
+
+		if err := rateLimiter.Close(context.Background()); err != nil {
+			logger.WithError(err).Error("could not stop rate limiter")
+		}
+
+		sseServer.Close()
+	}
+}
+
+// newRateLimitStore picks where the rate limit buckets are kept
+func newRateLimitStore(cfg *config.Config, db *bun.DB, tokensPerMinute uint64) (limiter.Store, error) {
+	if cfg.HTTP.RateLimit.Driver == config.RateLimitDriverPostgres {
+		return sdumpSql.NewRateLimitStore(db, tokensPerMinute, time.Minute), nil
+	}
+
+	return memorystore.New(&memorystore.Config{
+		Tokens:   tokensPerMinute,
+		Interval: time.Minute,
+	})
+}
+
+// newPubSub picks how ingested requests reach TUI sessions connected to
+// other HTTP servers
+func newPubSub(cfg *config.Config, db *bun.DB) sdump.PubSub {
+	if cfg.HTTP.PubSub.Driver == config.PubSubDriverPostgres {
+		return sdumpSql.NewPostgresPubSub(db)
+	}
+
+	return pubsub.NewMemory()
+}
+
 func initTracer(cfg *config.Config) (func(context.Context) error, error) {
 	secureOption := otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
 	if !cfg.HTTP.OTEL.UseTLS {
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/adelowo/sdump/config"
+	sdumpSql "github.com/adelowo/sdump/datastore/sql"
+	"github.com/spf13/cobra"
+)
+
+func createMigrateCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or roll back the database migrations embedded in this binary",
+	}
+
+	createMigrateUpCommand(cmd, cfg)
+	createMigrateDownCommand(cmd, cfg)
+	createMigrateStatusCommand(cmd, cfg)
+	createMigrateVersionCommand(cmd, cfg)
+
+	rootCmd.AddCommand(cmd)
+}
+
+// withMigrator runs fn against http.database and releases the connection
+// afterwards
+func withMigrator(cfg *config.Config, fn func(*sdumpSql.Migrator) error) error {
+	migrator, err := sdumpSql.NewMigrator(cfg.HTTP.Database)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(migrator); err != nil {
+		_ = migrator.Close()
+		return err
+	}
+
+	return migrator.Close()
+}
+
+func createMigrateUpCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply every pending migration",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return withMigrator(cfg, func(migrator *sdumpSql.Migrator) error {
+				return migrator.Up()
+			})
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createMigrateDownCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:     "down [steps]",
+		Short:   "Roll back the last applied migrations. Only the last one is rolled back by default",
+		Example: `sdump migrate down 2`,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			steps := 1
+
+			if len(args) == 1 {
+				var err error
+
+				steps, err = strconv.Atoi(args[0])
+				if err != nil || steps <= 0 {
+					return fmt.Errorf("steps must be a positive number, got %q", args[0])
+				}
+			}
+
+			return withMigrator(cfg, func(migrator *sdumpSql.Migrator) error {
+				return migrator.Down(steps)
+			})
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createMigrateStatusCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and whether they have been applied",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return withMigrator(cfg, func(migrator *sdumpSql.Migrator) error {
+				statuses, err := migrator.Status()
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+				fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+
+				for _, status := range statuses {
+					fmt.Fprintf(w, "%d\t%s\t%t\n", status.Version, status.Name, status.IsApplied)
+				}
+
+				return w.Flush()
+			})
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createMigrateVersionCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the last applied migration",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return withMigrator(cfg, func(migrator *sdumpSql.Migrator) error {
+				version, dirty, err := migrator.Version()
+				if err != nil {
+					return err
+				}
+
+				if dirty {
+					fmt.Printf("%d (dirty, the migration failed halfway and has to be fixed by hand)\n", version)
+					return nil
+				}
+
+				fmt.Println(version)
+				return nil
+			})
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	sdumpSql "github.com/adelowo/sdump/datastore/sql"
+	"github.com/spf13/cobra"
+)
+
+func createPlansCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "plans",
+		Short: "Manage plans and the limits they put on users",
+	}
+
+	createListPlansCommand(cmd, cfg)
+	createCreatePlanCommand(cmd, cfg)
+	createUpdatePlanCommand(cmd, cfg)
+
+	rootCmd.AddCommand(cmd)
+}
+
+// addPlanLimitFlags binds the flags shared by plans create and plans update.
+// A zero limit does not restrict the resource
+func addPlanLimitFlags(cmd *cobra.Command, plan *sdump.Plan) {
+	cmd.Flags().Int64Var(&plan.MaxEndpoints, "max-endpoints", 0,
+		"Number of endpoints a user can have")
+	cmd.Flags().Int64Var(&plan.MaxRequestsPerDay, "max-requests-per-day", 0,
+		"Number of requests a user's endpoints can receive daily")
+	cmd.Flags().Int64Var(&plan.RetentionDays, "retention-days", 0,
+		"Days ingested requests are kept. Cannot be more than cron.ttl")
+	cmd.Flags().Int64Var(&plan.MaxRequestBodySize, "max-request-body-size", 0,
+		"Largest request body in bytes. Cannot be more than http.max_request_body_size")
+	cmd.Flags().Int64Var(&plan.IngestRequestsPerMinute, "ingest-requests-per-minute", 0,
+		"Requests a user's endpoints can receive per minute. Replaces http.rate_limit.requests_per_minute")
+	cmd.Flags().Int64Var(&plan.APIRequestsPerMinute, "api-requests-per-minute", 0,
+		"Requests a user can make to the API per minute. Replaces http.rate_limit.api.requests_per_minute")
+}
+
+func createListPlansCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List plans",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			db, err := sdumpSql.New(cfg.HTTP.Database)
+			if err != nil {
+				return err
+			}
+
+			plans, err := sdumpSql.NewPlanRepositoryTable(db).List(context.Background())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+			fmt.Fprintln(w, "NAME\tDEFAULT\tENDPOINTS\tREQUESTS PER DAY\tRETENTION DAYS\tMAX BODY SIZE\tINGEST PER MINUTE\tAPI PER MINUTE")
+
+			for _, plan := range plans {
+				fmt.Fprintf(w, "%s\t%t\t%d\t%d\t%d\t%d\t%d\t%d\n", plan.Name, plan.IsDefault,
+					plan.MaxEndpoints, plan.MaxRequestsPerDay, plan.RetentionDays,
+					plan.MaxRequestBodySize, plan.IngestRequestsPerMinute, plan.APIRequestsPerMinute)
+			}
+
+			return w.Flush()
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createCreatePlanCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	plan := new(sdump.Plan)
+
+	cmd := &cobra.Command{
+		Use:     "create <name>",
+		Short:   "Create a plan",
+		Example: `sdump plans create pro --max-endpoints 10 --max-requests-per-day 10000`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			db, err := sdumpSql.New(cfg.HTTP.Database)
+			if err != nil {
+				return err
+			}
+
+			plan.Name = args[0]
+
+			return sdumpSql.NewPlanRepositoryTable(db).Create(context.Background(), plan)
+		},
+	}
+
+	addPlanLimitFlags(cmd, plan)
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createUpdatePlanCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	limits := new(sdump.Plan)
+
+	cmd := &cobra.Command{
+		Use:     "update <name>",
+		Short:   "Change the limits of a plan. Only the provided limits are updated",
+		Example: `sdump plans update free --max-requests-per-day 500`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := sdumpSql.New(cfg.HTTP.Database)
+			if err != nil {
+				return err
+			}
+
+			planStore := sdumpSql.NewPlanRepositoryTable(db)
+
+			plan, err := planStore.Find(context.Background(), &sdump.FindPlanOptions{
+				Name: args[0],
+			})
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("max-endpoints") {
+				plan.MaxEndpoints = limits.MaxEndpoints
+			}
+
+			if cmd.Flags().Changed("max-requests-per-day") {
+				plan.MaxRequestsPerDay = limits.MaxRequestsPerDay
+			}
+
+			if cmd.Flags().Changed("retention-days") {
+				plan.RetentionDays = limits.RetentionDays
+			}
+
+			if cmd.Flags().Changed("max-request-body-size") {
+				plan.MaxRequestBodySize = limits.MaxRequestBodySize
+			}
+
+			if cmd.Flags().Changed("ingest-requests-per-minute") {
+				plan.IngestRequestsPerMinute = limits.IngestRequestsPerMinute
+			}
+
+			if cmd.Flags().Changed("api-requests-per-minute") {
+				plan.APIRequestsPerMinute = limits.APIRequestsPerMinute
+			}
+
+			return planStore.Update(context.Background(), plan)
+		},
+	}
+
+	addPlanLimitFlags(cmd, limits)
+
+	rootCmd.AddCommand(cmd)
+}
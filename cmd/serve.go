@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/adelowo/sdump/config"
+	sdumpSql "github.com/adelowo/sdump/datastore/sql"
+	"github.com/adelowo/sdump/internal/pubsub"
+	"github.com/adelowo/sdump/internal/tui"
+	"github.com/charmbracelet/ssh"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func createServeCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	var dbPath string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP and SSH servers in a single process backed by SQLite",
+		Long: `Run the HTTP and SSH servers in a single process backed by a SQLite database.
+Migrations are applied on start and TUI sessions receive requests without going
+through the HTTP server. Only one process can use the database so this cannot be
+scaled out, use the http and ssh commands with Postgres for that`,
+		Example: `sdump serve --db /var/lib/sdump/sdump.db`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cfg.HTTP.Database = config.DatabaseConfig{
+				Driver:      config.DatabaseTypeSqlite,
+				DSN:         sqliteDSN(dbPath),
+				LogQueries:  cfg.HTTP.Database.LogQueries,
+				AutoMigrate: true,
+			}
+
+			// both need Postgres and there is a single process to
+			// share state with anyway
+			cfg.HTTP.PubSub.Driver = config.PubSubDriverMemory
+			cfg.HTTP.RateLimit.Driver = config.RateLimitDriverMemory
+
+			lvl, err := logrus.ParseLevel(cfg.LogLevel)
+			if err != nil {
+				return err
+			}
+
+			logrus.SetOutput(os.Stdout)
+			logrus.SetLevel(lvl)
+
+			logger := logrus.WithField("module", "serve")
+
+			// the TUI is the only client that needs it and it runs in
+			// this process
+			if cfg.HTTP.AdminSecret == "" {
+				cfg.HTTP.AdminSecret, err = newAdminSecret()
+				if err != nil {
+					return err
+				}
+
+				logger.Info("http.admin_secret is not set, a random secret was generated for this process")
+			}
+
+			if err := cfg.HTTP.Validate(); err != nil {
+				return err
+			}
+
+			db, err := sdumpSql.New(cfg.HTTP.Database)
+			if err != nil {
+				return err
+			}
+
+			if err := withMigrator(cfg, func(migrator *sdumpSql.Migrator) error {
+				return migrator.Up()
+			}); err != nil {
+				return err
+			}
+
+			pubSub := pubsub.NewMemory()
+
+			stopHTTPServer := startHTTPServer(cfg, db, pubSub,
+				logger.WithField("module", "http.server"))
+
+			s, err := newSSHServer(cfg,
+				tui.WithAPIURL(fmt.Sprintf("http://127.0.0.1:%d", cfg.HTTP.Port)),
+				tui.WithSubscriber(pubSub))
+			if err != nil {
+				stopHTTPServer()
+				return err
+			}
+
+			done := make(chan os.Signal, 1)
+			signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+			logger.WithField("port", cfg.SSH.Port).Info("starting SSH server")
+
+			go func() {
+				if err := s.ListenAndServe(); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
+					logger.WithError(err).Error("could not start SSH server")
+					done <- nil
+				}
+			}()
+
+			<-done
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := s.Shutdown(ctx); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
+				logger.WithError(err).Error("could not shut down SSH server")
+			}
+
+			stopHTTPServer()
+
+			return db.Close()
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "sdump.db", "Path to the SQLite database. It is created if it does not exist")
+
+	rootCmd.AddCommand(cmd)
+}
+
+// sqliteDSN opens the database in WAL mode so the HTTP server and TUI
+// sessions can read while a request is being written
+func sqliteDSN(path string) string {
+	return fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+}
+
+func newAdminSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/adelowo/sdump/config"
 	"github.com/adelowo/sdump/internal/tui"
+	"github.com/adelowo/sdump/internal/tunnel"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
 	"github.com/charmbracelet/ssh"
@@ -26,31 +27,14 @@ func createSSHCommand(rootCmd *cobra.Command, cfg *config.Config) {
 		Use:   "ssh",
 		Short: "Start/run the TUI app",
 		RunE: func(_ *cobra.Command, _ []string) error {
-			s, err := wish.NewServer(
-				wish.WithAddress(fmt.Sprintf("%s:%d", cfg.SSH.Host, cfg.SSH.Port)),
-				validateSSHPublicKey(cfg),
-				wish.WithMiddleware(
-					bm.Middleware(teaHandler(cfg)),
-					lm.Middleware(),
-				),
-			)
-			if err != nil {
+			// requests to the HTTP server are signed with the admin secret
+			if err := cfg.HTTP.Validate(); err != nil {
 				return err
 			}
 
-			for _, v := range cfg.SSH.Identities {
-
-				pemBytes, err := os.ReadFile(v)
-				if err != nil {
-					return err
-				}
-
-				signer, err := gossh.ParsePrivateKey(pemBytes)
-				if err != nil {
-					return err
-				}
-
-				s.AddHostKey(signer)
+			s, err := newSSHServer(cfg)
+			if err != nil {
+				return err
 			}
 
 			done := make(chan os.Signal, 1)
@@ -80,6 +64,40 @@ func createSSHCommand(rootCmd *cobra.Command, cfg *config.Config) {
 	rootCmd.AddCommand(cmd)
 }
 
+// newSSHServer sets up the SSH server the TUI is served from. opts are
+// passed to every TUI session
+func newSSHServer(cfg *config.Config, opts ...tui.Option) (*ssh.Server, error) {
+	s, err := wish.NewServer(
+		wish.WithAddress(fmt.Sprintf("%s:%d", cfg.SSH.Host, cfg.SSH.Port)),
+		validateSSHPublicKey(cfg),
+		tunnel.NewHandler(cfg.SSH.Tunnel).Option(),
+		wish.WithMiddleware(
+			bm.Middleware(teaHandler(cfg, opts...)),
+			lm.Middleware(),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range cfg.SSH.Identities {
+
+		pemBytes, err := os.ReadFile(v)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, err := gossh.ParsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		s.AddHostKey(signer)
+	}
+
+	return s, nil
+}
+
 func validateSSHPublicKey(cfg *config.Config) ssh.Option {
 	sshKeys := make(map[string]gossh.PublicKey, len(cfg.SSH.AllowList))
 
@@ -112,7 +130,7 @@ func validateSSHPublicKey(cfg *config.Config) ssh.Option {
 	})
 }
 
-func teaHandler(cfg *config.Config) func(s ssh.Session) (tea.Model, []tea.ProgramOption) {
+func teaHandler(cfg *config.Config, serverOpts ...tui.Option) func(s ssh.Session) (tea.Model, []tea.ProgramOption) {
 	return func(s ssh.Session) (tea.Model, []tea.ProgramOption) {
 		pty, _, active := s.Pty()
 		if !active {
@@ -122,12 +140,27 @@ func teaHandler(cfg *config.Config) func(s ssh.Session) (tea.Model, []tea.Progra
 
 		sshFingerPrint := gossh.FingerprintSHA256(s.PublicKey())
 
-		tuiModel, err := tui.New(cfg,
+		var banned *tui.BannedError
+		if err := tui.CheckBan(cfg, sshFingerPrint, serverOpts...); errors.As(err, &banned) {
+			wish.Fatalln(s, banned.Error())
+			return nil, nil
+		}
+
+		opts := []tui.Option{
 			tui.WithWidth(pty.Window.Width),
 			tui.WithHeight(pty.Window.Height),
 			tui.WithSSHFingerPrint(sshFingerPrint),
 			tui.WithColorscheme(cfg.TUI.ColorScheme),
-		)
+			tui.WithContext(s.Context()),
+		}
+
+		opts = append(opts, serverOpts...)
+
+		if cfg.SSH.Tunnel.IsEnabled {
+			opts = append(opts, tui.WithTunnel(tunnel.FromContext(s.Context())))
+		}
+
+		tuiModel, err := tui.New(cfg, opts...)
 		if err != nil {
 			wish.Fatalln(s, fmt.Errorf("%v...Could not set up TUI session", err))
 			return nil, nil
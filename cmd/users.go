@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	sdumpSql "github.com/adelowo/sdump/datastore/sql"
+	"github.com/spf13/cobra"
+)
+
+func createUsersCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "Manage users",
+	}
+
+	createBanUserCommand(cmd, cfg)
+	createUnbanUserCommand(cmd, cfg)
+	createListUsersCommand(cmd, cfg)
+	createSetUserPlanCommand(cmd, cfg)
+	createSetUserRateLimitCommand(cmd, cfg)
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createBanUserCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:     "ban <ssh fingerprint>",
+		Short:   "Ban a user from creating urls, using the TUI and receiving requests",
+		Example: `sdump users ban SHA256:Jn3kyWeH4PaT/7v8T1SgVCh8MXm8b4LgFrV1zU/TJ5k --reason "spam"`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return updateUser(cfg, args[0], func(user *sdump.User) {
+				user.Ban(reason)
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&reason, "reason", "r", "", "Reason for the ban. It is shown to the user")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createUnbanUserCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:   "unban <ssh fingerprint>",
+		Short: "Lift a user's ban",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return updateUser(cfg, args[0], func(user *sdump.User) {
+				user.Unban()
+			})
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createListUsersCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	var onlyBanned bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List users",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			db, err := sdumpSql.New(cfg.HTTP.Database)
+			if err != nil {
+				return err
+			}
+
+			users, err := sdumpSql.NewUserRepositoryTable(db).List(context.Background(),
+				&sdump.ListUserOptions{
+					OnlyBanned: onlyBanned,
+				})
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+			fmt.Fprintln(w, "FINGERPRINT\tBANNED\tREASON\tCREATED AT")
+
+			for _, user := range users {
+				fmt.Fprintf(w, "%s\t%t\t%s\t%s\n", user.SSHFingerPrint, user.IsBanned,
+					user.BanReason, user.CreatedAt.Format(time.RFC3339))
+			}
+
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&onlyBanned, "banned", "b", false, "Only list banned users")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createSetUserPlanCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	cmd := &cobra.Command{
+		Use:     "plan <ssh fingerprint> <plan name>",
+		Short:   "Move a user to another plan",
+		Example: `sdump users plan SHA256:Jn3kyWeH4PaT/7v8T1SgVCh8MXm8b4LgFrV1zU/TJ5k pro`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			db, err := sdumpSql.New(cfg.HTTP.Database)
+			if err != nil {
+				return err
+			}
+
+			plan, err := sdumpSql.NewPlanRepositoryTable(db).Find(context.Background(),
+				&sdump.FindPlanOptions{
+					Name: args[1],
+				})
+			if err != nil {
+				return err
+			}
+
+			return updateUser(cfg, args[0], func(user *sdump.User) {
+				user.PlanID = plan.ID
+			})
+		},
+	}
+
+	rootCmd.AddCommand(cmd)
+}
+
+func createSetUserRateLimitCommand(rootCmd *cobra.Command, cfg *config.Config) {
+	limits := new(sdump.User)
+
+	cmd := &cobra.Command{
+		Use:     "rate-limit <ssh fingerprint>",
+		Short:   "Give a user their own rate limits instead of their plan's. Only the provided limits are updated",
+		Example: `sdump users rate-limit SHA256:Jn3kyWeH4PaT/7v8T1SgVCh8MXm8b4LgFrV1zU/TJ5k --ingest-requests-per-minute 600`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateUser(cfg, args[0], func(user *sdump.User) {
+				if cmd.Flags().Changed("ingest-requests-per-minute") {
+					user.IngestRequestsPerMinute = limits.IngestRequestsPerMinute
+				}
+
+				if cmd.Flags().Changed("api-requests-per-minute") {
+					user.APIRequestsPerMinute = limits.APIRequestsPerMinute
+				}
+			})
+		},
+	}
+
+	// a zero limit falls back to the plan's
+	cmd.Flags().Int64Var(&limits.IngestRequestsPerMinute, "ingest-requests-per-minute", 0,
+		"Requests the user's endpoints can receive per minute. 0 uses the plan's limit")
+	cmd.Flags().Int64Var(&limits.APIRequestsPerMinute, "api-requests-per-minute", 0,
+		"Requests the user can make to the API per minute. 0 uses the plan's limit")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func updateUser(cfg *config.Config, fingerprint string, fn func(*sdump.User)) error {
+	db, err := sdumpSql.New(cfg.HTTP.Database)
+	if err != nil {
+		return err
+	}
+
+	userStore := sdumpSql.NewUserRepositoryTable(db)
+
+	user, err := userStore.Find(context.Background(), &sdump.FindUserOptions{
+		SSHKeyFingerprint: fingerprint,
+	})
+	if err != nil {
+		return err
+	}
+
+	fn(user)
+
+	return userStore.Update(context.Background(), user)
+}
@@ -1,12 +1,89 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
+var (
+	ErrMissingAdminSecret     = errors.New("http.admin_secret must be provided")
+	ErrSampleAdminSecret      = errors.New("http.admin_secret cannot be the sample value from the docs")
+	ErrPubSubNeedsPostgres    = errors.New("http.pubsub.driver postgres cannot be used with a sqlite database")
+	ErrRateLimitNeedsPostgres = errors.New("http.rate_limit.driver postgres cannot be used with a sqlite database")
+)
+
+// sampleAdminSecret is the admin secret earlier versions of the sample
+// config shipped with. It is publicly known so it is never accepted
+const sampleAdminSecret = "sdump-admin-secret"
+
 // ENUM(psql, sqlite)
 type DatabaseType string
 
+// ENUM(memory, postgres)
+type PubSubDriver string
+
+// ENUM(memory, postgres)
+type RateLimitDriver string
+
+// ENUM(endpoint, ip, user)
+type RateLimitKey string
+
+// TunnelConfig configures delivering ingested requests to a user's machine
+// through a reverse SSH tunnel ( ssh -R 0:localhost:3000 )
+type TunnelConfig struct {
+	IsEnabled bool `mapstructure:"is_enabled" json:"is_enabled,omitempty" yaml:"is_enabled"`
+
+	// BindHost is the interface tunnel listeners are created on
+	BindHost string `mapstructure:"bind_host" json:"bind_host,omitempty" yaml:"bind_host"`
+
+	// AdvertiseHost is the host the HTTP server uses to reach the tunnel
+	// listeners. If both servers run on different machines, this should be
+	// the private address of the SSH server
+	AdvertiseHost string `mapstructure:"advertise_host" json:"advertise_host,omitempty" yaml:"advertise_host"`
+
+	// MinPort and MaxPort are the ports tunnel listeners are opened on. The
+	// HTTP server refuses to deliver requests to any other port
+	MinPort int `mapstructure:"min_port" json:"min_port,omitempty" yaml:"min_port"`
+	MaxPort int `mapstructure:"max_port" json:"max_port,omitempty" yaml:"max_port"`
+
+	// MaxForwards is how many tunnels a single SSH connection can open
+	MaxForwards int `mapstructure:"max_forwards" json:"max_forwards,omitempty" yaml:"max_forwards"`
+}
+
+const (
+	defaultTunnelMinPort     = 40000
+	defaultTunnelMaxPort     = 40999
+	defaultTunnelMaxForwards = 3
+)
+
+// PortRange returns the ports tunnel listeners can be opened on. The
+// default range is used if none or an invalid one is configured
+func (t TunnelConfig) PortRange() (int, int) {
+	if t.MinPort <= 0 || t.MaxPort < t.MinPort || t.MaxPort > 65535 {
+		return defaultTunnelMinPort, defaultTunnelMaxPort
+	}
+
+	return t.MinPort, t.MaxPort
+}
+
+// IsTunnelPort reports whether a tunnel listener can be opened on port
+func (t TunnelConfig) IsTunnelPort(port int) bool {
+	minPort, maxPort := t.PortRange()
+	return port >= minPort && port <= maxPort
+}
+
+// ForwardsPerConnection is how many tunnels a single SSH connection can
+// open
+func (t TunnelConfig) ForwardsPerConnection() int {
+	if t.MaxForwards <= 0 {
+		return defaultTunnelMaxForwards
+	}
+
+	return t.MaxForwards
+}
+
 type SSHConfig struct {
 	// Port defines where the ssh server runs at
 	Port int `mapstructure:"port" json:"port,omitempty" yaml:"port"`
@@ -19,12 +96,17 @@ type SSHConfig struct {
 	// AllowList is a list of paths to public keys that should be accepted when connecting to the sshssh/i
 	// server
 	AllowList []string `json:"allow_list,omitempty" mapstructure:"allow_list" yaml:"allow_list"`
+
+	Tunnel TunnelConfig `json:"tunnel,omitempty" mapstructure:"tunnel" yaml:"tunnel"`
 }
 
 type DatabaseConfig struct {
 	DSN        string       `mapstructure:"dsn" json:"dsn,omitempty" yaml:"dsn"`
 	LogQueries bool         `mapstructure:"log_queries" json:"log_queries,omitempty" yaml:"log_queries"`
 	Driver     DatabaseType `mapstructure:"driver" json:"driver,omitempty" yaml:"driver,omitempty"`
+
+	// AutoMigrate applies pending migrations when the HTTP server starts
+	AutoMigrate bool `mapstructure:"auto_migrate" json:"auto_migrate,omitempty" yaml:"auto_migrate"`
 }
 
 type HTTPConfig struct {
@@ -36,7 +118,8 @@ type HTTPConfig struct {
 	// only ran by an admin
 	// Endpoints to create a new url as an example should only be ran by an admin
 	// or the ssh server ( after it has verified we have a verified connection)
-	// If empty, server would crash
+	// The ssh server signs its requests with it. Both the http and ssh
+	// servers refuse to start if it is empty
 	AdminSecret string `mapstructure:"admin_secret" json:"admin_secret,omitempty" yaml:"admin_secret"`
 
 	Database DatabaseConfig `mapstructure:"database" json:"database,omitempty" yaml:"database"`
@@ -59,8 +142,50 @@ type HTTPConfig struct {
 		IsEnabled bool   `json:"is_enabled,omitempty" mapstructure:"is_enabled" yaml:"is_enabled"`
 	} `json:"prometheus,omitempty" mapstructure:"prometheus" yaml:"prometheus"`
 
+	// Forwarding configures how ingested requests are relayed to an
+	// endpoint's forward target
+	Forwarding struct {
+		Timeout time.Duration `json:"timeout,omitempty" mapstructure:"timeout" yaml:"timeout"`
+
+		// AllowPrivateNetworks allows relaying requests to loopback and private
+		// network addresses. This should only be enabled when running locally
+		AllowPrivateNetworks bool `json:"allow_private_networks,omitempty" mapstructure:"allow_private_networks" yaml:"allow_private_networks"`
+
+		// Workers is how many requests are forwarded at once. Requests
+		// are queued up to QueueSize once every worker is busy, after
+		// which they are not forwarded
+		Workers   int `json:"workers,omitempty" mapstructure:"workers" yaml:"workers"`
+		QueueSize int `json:"queue_size,omitempty" mapstructure:"queue_size" yaml:"queue_size"`
+	} `json:"forwarding,omitempty" mapstructure:"forwarding" yaml:"forwarding"`
+
+	// PubSub configures how ingested requests reach the TUI sessions
+	// connected to other HTTP servers. The memory driver only works with a
+	// single HTTP server, the postgres driver uses LISTEN/NOTIFY on the
+	// database
+	PubSub struct {
+		Driver PubSubDriver `json:"driver,omitempty" mapstructure:"driver" yaml:"driver"`
+	} `json:"pubsub,omitempty" mapstructure:"pubsub" yaml:"pubsub"`
+
 	RateLimit struct {
+		// RequestsPerMinute limits the requests ingested by endpoints. Plans
+		// and users can override it. Zero disables the limit
 		RequestsPerMinute uint64 `json:"requests_per_minute,omitempty" mapstructure:"requests_per_minute"`
+
+		// Keys are what ingested requests are counted by. Combining keys
+		// such as endpoint and ip gives every client of an endpoint its own
+		// limit
+		Keys []RateLimitKey `json:"keys,omitempty" mapstructure:"keys" yaml:"keys"`
+
+		// API limits the requests made to /api/v1 by authenticated users
+		API struct {
+			RequestsPerMinute uint64         `json:"requests_per_minute,omitempty" mapstructure:"requests_per_minute" yaml:"requests_per_minute"`
+			Keys              []RateLimitKey `json:"keys,omitempty" mapstructure:"keys" yaml:"keys"`
+		} `json:"api,omitempty" mapstructure:"api" yaml:"api"`
+
+		// Driver is where rate limit buckets are kept. The memory driver
+		// keeps them per HTTP server so the limit grows with every server
+		// added, the postgres driver shares them through the database
+		Driver RateLimitDriver `json:"driver,omitempty" mapstructure:"driver" yaml:"driver"`
 	} `json:"rate_limit,omitempty" mapstructure:"rate_limit"`
 }
 
@@ -71,8 +196,24 @@ type TUIConfig struct {
 type CronConfig struct {
 	// SoftDeletes determines how to delete the DB content. It is set to
 	// false by default which means the content would actually be deleted completely from the database
-	SoftDeletes bool          `mapstructure:"soft_deletes" json:"soft_deletes,omitempty" yaml:"soft_deletes"`
-	TTL         time.Duration `mapstructure:"ttl" yaml:"ttl" json:"ttl,omitempty"`
+	SoftDeletes bool `mapstructure:"soft_deletes" json:"soft_deletes,omitempty" yaml:"soft_deletes"`
+
+	// TTL is how long ingested requests are kept for. Plans and endpoints
+	// can only lower it
+	TTL time.Duration `mapstructure:"ttl" yaml:"ttl" json:"ttl,omitempty"`
+
+	// Interval is how often the HTTP server prunes old requests. Pruning
+	// is left to the delete-http command if it is zero
+	Interval time.Duration `mapstructure:"interval" yaml:"interval" json:"interval,omitempty"`
+
+	// BatchSize is how many requests are deleted per query so large tables
+	// are not locked for long. Everything is deleted at once if it is zero
+	BatchSize int `mapstructure:"batch_size" yaml:"batch_size" json:"batch_size,omitempty"`
+
+	// PurgeAfter is how long soft deleted requests and the requests of
+	// deleted endpoints are kept before they are removed for good. They
+	// are kept forever if it is zero
+	PurgeAfter time.Duration `mapstructure:"purge_after" yaml:"purge_after" json:"purge_after,omitempty"`
 }
 
 type Config struct {
@@ -82,3 +223,44 @@ type Config struct {
 	TUI      TUIConfig  `mapstructure:"tui" json:"tui,omitempty" yaml:"tui"`
 	Cron     CronConfig `mapstructure:"cron" yaml:"cron" json:"cron,omitempty"`
 }
+
+// Validate checks the values the http and ssh servers cannot run without
+func (h HTTPConfig) Validate() error {
+	if strings.TrimSpace(h.AdminSecret) == "" {
+		return ErrMissingAdminSecret
+	}
+
+	if h.AdminSecret == sampleAdminSecret {
+		return ErrSampleAdminSecret
+	}
+
+	if h.PubSub.Driver != "" && !h.PubSub.Driver.IsValid() {
+		return fmt.Errorf("http.pubsub.driver: %w", ErrInvalidPubSubDriver)
+	}
+
+	if h.PubSub.Driver == PubSubDriverPostgres && h.Database.Driver == DatabaseTypeSqlite {
+		return ErrPubSubNeedsPostgres
+	}
+
+	if h.RateLimit.Driver != "" && !h.RateLimit.Driver.IsValid() {
+		return fmt.Errorf("http.rate_limit.driver: %w", ErrInvalidRateLimitDriver)
+	}
+
+	if h.RateLimit.Driver == RateLimitDriverPostgres && h.Database.Driver == DatabaseTypeSqlite {
+		return ErrRateLimitNeedsPostgres
+	}
+
+	for _, key := range h.RateLimit.Keys {
+		if !key.IsValid() {
+			return fmt.Errorf("http.rate_limit.keys: %w", ErrInvalidRateLimitKey)
+		}
+	}
+
+	for _, key := range h.RateLimit.API.Keys {
+		if !key.IsValid() {
+			return fmt.Errorf("http.rate_limit.api.keys: %w", ErrInvalidRateLimitKey)
+		}
+	}
+
+	return nil
+}
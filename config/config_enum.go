@@ -44,3 +44,108 @@ func ParseDatabaseType(name string) (DatabaseType, error) {
 	}
 	return DatabaseType(""), fmt.Errorf("%s is %w", name, ErrInvalidDatabaseType)
 }
+
+const (
+	// PubSubDriverMemory is a PubSubDriver of type memory.
+	PubSubDriverMemory PubSubDriver = "memory"
+	// PubSubDriverPostgres is a PubSubDriver of type postgres.
+	PubSubDriverPostgres PubSubDriver = "postgres"
+)
+
+var ErrInvalidPubSubDriver = errors.New("not a valid PubSubDriver")
+
+// String implements the Stringer interface.
+func (x PubSubDriver) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x PubSubDriver) IsValid() bool {
+	_, err := ParsePubSubDriver(string(x))
+	return err == nil
+}
+
+var _PubSubDriverValue = map[string]PubSubDriver{
+	"memory":   PubSubDriverMemory,
+	"postgres": PubSubDriverPostgres,
+}
+
+// ParsePubSubDriver attempts to convert a string to a PubSubDriver.
+func ParsePubSubDriver(name string) (PubSubDriver, error) {
+	if x, ok := _PubSubDriverValue[name]; ok {
+		return x, nil
+	}
+	return PubSubDriver(""), fmt.Errorf("%s is %w", name, ErrInvalidPubSubDriver)
+}
+
+const (
+	// RateLimitDriverMemory is a RateLimitDriver of type memory.
+	RateLimitDriverMemory RateLimitDriver = "memory"
+	// RateLimitDriverPostgres is a RateLimitDriver of type postgres.
+	RateLimitDriverPostgres RateLimitDriver = "postgres"
+)
+
+var ErrInvalidRateLimitDriver = errors.New("not a valid RateLimitDriver")
+
+// String implements the Stringer interface.
+func (x RateLimitDriver) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x RateLimitDriver) IsValid() bool {
+	_, err := ParseRateLimitDriver(string(x))
+	return err == nil
+}
+
+var _RateLimitDriverValue = map[string]RateLimitDriver{
+	"memory":   RateLimitDriverMemory,
+	"postgres": RateLimitDriverPostgres,
+}
+
+// ParseRateLimitDriver attempts to convert a string to a RateLimitDriver.
+func ParseRateLimitDriver(name string) (RateLimitDriver, error) {
+	if x, ok := _RateLimitDriverValue[name]; ok {
+		return x, nil
+	}
+	return RateLimitDriver(""), fmt.Errorf("%s is %w", name, ErrInvalidRateLimitDriver)
+}
+
+const (
+	// RateLimitKeyEndpoint is a RateLimitKey of type endpoint.
+	RateLimitKeyEndpoint RateLimitKey = "endpoint"
+	// RateLimitKeyIp is a RateLimitKey of type ip.
+	RateLimitKeyIp RateLimitKey = "ip"
+	// RateLimitKeyUser is a RateLimitKey of type user.
+	RateLimitKeyUser RateLimitKey = "user"
+)
+
+var ErrInvalidRateLimitKey = errors.New("not a valid RateLimitKey")
+
+// String implements the Stringer interface.
+func (x RateLimitKey) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x RateLimitKey) IsValid() bool {
+	_, err := ParseRateLimitKey(string(x))
+	return err == nil
+}
+
+var _RateLimitKeyValue = map[string]RateLimitKey{
+	"endpoint": RateLimitKeyEndpoint,
+	"ip":       RateLimitKeyIp,
+	"user":     RateLimitKeyUser,
+}
+
+// ParseRateLimitKey attempts to convert a string to a RateLimitKey.
+func ParseRateLimitKey(name string) (RateLimitKey, error) {
+	if x, ok := _RateLimitKeyValue[name]; ok {
+		return x, nil
+	}
+	return RateLimitKey(""), fmt.Errorf("%s is %w", name, ErrInvalidRateLimitKey)
+}
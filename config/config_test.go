@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPConfig_Validate(t *testing.T) {
+	require.ErrorIs(t, HTTPConfig{}.Validate(), ErrMissingAdminSecret)
+	require.ErrorIs(t, HTTPConfig{AdminSecret: "   "}.Validate(), ErrMissingAdminSecret)
+	require.ErrorIs(t, HTTPConfig{AdminSecret: "sdump-admin-secret"}.Validate(), ErrSampleAdminSecret)
+	require.NoError(t, HTTPConfig{AdminSecret: "f0e4c2f24c9d5d0b"}.Validate())
+
+	cfg := HTTPConfig{AdminSecret: "f0e4c2f24c9d5d0b"}
+
+	cfg.PubSub.Driver = "redis"
+	require.ErrorIs(t, cfg.Validate(), ErrInvalidPubSubDriver)
+
+	cfg.PubSub.Driver = PubSubDriverPostgres
+	require.NoError(t, cfg.Validate())
+
+	cfg.Database.Driver = DatabaseTypeSqlite
+	require.ErrorIs(t, cfg.Validate(), ErrPubSubNeedsPostgres)
+
+	cfg = HTTPConfig{AdminSecret: "f0e4c2f24c9d5d0b"}
+
+	cfg.RateLimit.Driver = "redis"
+	require.ErrorIs(t, cfg.Validate(), ErrInvalidRateLimitDriver)
+
+	cfg.RateLimit.Driver = RateLimitDriverPostgres
+	require.NoError(t, cfg.Validate())
+
+	cfg.Database.Driver = DatabaseTypeSqlite
+	require.ErrorIs(t, cfg.Validate(), ErrRateLimitNeedsPostgres)
+
+	cfg = HTTPConfig{AdminSecret: "f0e4c2f24c9d5d0b"}
+
+	cfg.RateLimit.Keys = []RateLimitKey{RateLimitKeyEndpoint, RateLimitKeyIp}
+	cfg.RateLimit.API.Keys = []RateLimitKey{RateLimitKeyUser}
+	require.NoError(t, cfg.Validate())
+
+	cfg.RateLimit.Keys = []RateLimitKey{"country"}
+	require.ErrorIs(t, cfg.Validate(), ErrInvalidRateLimitKey)
+
+	cfg.RateLimit.Keys = nil
+	cfg.RateLimit.API.Keys = []RateLimitKey{"country"}
+	require.ErrorIs(t, cfg.Validate(), ErrInvalidRateLimitKey)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 
 	"github.com/adelowo/sdump/config"
+	"github.com/google/uuid"
 	"github.com/oiime/logrusbun"
 	"github.com/sirupsen/logrus"
 	"github.com/uptrace/bun"
@@ -45,6 +46,14 @@ func newSqlite(cfg config.DatabaseConfig) (*bun.DB, error) {
 	return db, db.Ping()
 }
 
+// newID generates the id of a row about to be created. sqlite has no
+// uuid_generate_v4() so ids cannot be left to the database
+func newID(id *uuid.UUID) {
+	if *id == uuid.Nil {
+		*id = uuid.New()
+	}
+}
+
 func New(cfg config.DatabaseConfig) (*bun.DB, error) {
 	if cfg.Driver == config.DatabaseTypeSqlite {
 		return newSqlite(cfg)
@@ -2,9 +2,19 @@ package sql
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+	"github.com/uptrace/bun/dialect/pgdialect"
 )
 
 type ingestRepository struct {
@@ -20,28 +30,389 @@ func NewIngestRepository(db *bun.DB) sdump.IngestRepository {
 func (u *ingestRepository) Create(ctx context.Context,
 	model *sdump.IngestHTTPRequest,
 ) error {
+	newID(&model.ID)
+
+	// sqlite's current_timestamp only has second resolution which leaves
+	// requests ingested in the same second in no particular order
+	if model.CreatedAt.IsZero() {
+		model.CreatedAt = time.Now()
+	}
+
+	// Postgres does not allow NUL characters in jsonb strings
+	if !model.Request.IsBase64Encoded && json.Valid([]byte(model.Request.Body)) &&
+		!strings.Contains(model.Request.Body, `\u0000`) {
+		model.BodyJSON = json.RawMessage(model.Request.Body)
+	}
+
 	_, err := bun.NewInsertQuery(u.inner).Model(model).
 		Exec(ctx)
 	return err
 }
 
+func (u *ingestRepository) Update(ctx context.Context,
+	model *sdump.IngestHTTPRequest,
+) error {
+	model.UpdatedAt = time.Now()
+
+	_, err := bun.NewUpdateQuery(u.inner).Model(model).
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (u *ingestRepository) List(ctx context.Context,
+	opts *sdump.ListIngestedRequestOptions,
+) ([]sdump.IngestHTTPRequest, error) {
+	return u.Search(ctx, &sdump.SearchIngestedRequestOptions{
+		URLID:  opts.URLID,
+		Cursor: opts.Cursor,
+		Limit:  opts.Limit,
+		Method: opts.Method,
+		Path:   opts.Path,
+		Since:  opts.Since,
+		Until:  opts.Until,
+	})
+}
+
+func (u *ingestRepository) Search(ctx context.Context,
+	opts *sdump.SearchIngestedRequestOptions,
+) ([]sdump.IngestHTTPRequest, error) {
+	if u.inner.Dialect().Name() == dialect.SQLite && opts.IPNetwork != nil {
+		return u.searchNetwork(ctx, opts)
+	}
+
+	return u.search(ctx, opts)
+}
+
+func (u *ingestRepository) search(ctx context.Context,
+	opts *sdump.SearchIngestedRequestOptions,
+) ([]sdump.IngestHTTPRequest, error) {
+	var requests []sdump.IngestHTTPRequest
+
+	query := bun.NewSelectQuery(u.inner).Model(&requests).
+		Where("url_id = ?", opts.URLID).
+		Order("created_at DESC", "id DESC").
+		Limit(opts.Limit)
+
+	if opts.Cursor != uuid.Nil {
+		query = query.Where("(created_at, id) < (SELECT created_at, id FROM ingests WHERE id = ?)",
+			opts.Cursor)
+	}
+
+	if opts.Method != "" {
+		query = query.Where("request->>'method' = ?", strings.ToUpper(opts.Method))
+	}
+
+	if opts.Path != "" {
+		query = query.Where("request->>'path' LIKE ? ESCAPE '\\'", likePrefix(opts.Path))
+	}
+
+	if !opts.Since.IsZero() {
+		query = query.Where("created_at >= ?", opts.Since)
+	}
+
+	if !opts.Until.IsZero() {
+		query = query.Where("created_at <= ?", opts.Until)
+	}
+
+	if u.inner.Dialect().Name() == dialect.SQLite {
+		query = sqliteSearchFilters(query, opts)
+	} else {
+		query = postgresSearchFilters(query, opts)
+	}
+
+	return requests, query.Scan(ctx)
+}
+
+func postgresSearchFilters(query *bun.SelectQuery,
+	opts *sdump.SearchIngestedRequestOptions,
+) *bun.SelectQuery {
+	if opts.HeaderName != "" {
+		name := http.CanonicalHeaderKey(opts.HeaderName)
+
+		if opts.HeaderValue == "" {
+			query = query.Where("request->'headers' \\? ?", name)
+		} else {
+			// containment can make use of the gin index on the headers
+			b, _ := json.Marshal(map[string][]string{name: {opts.HeaderValue}})
+			query = query.Where("request->'headers' @> ?::jsonb", string(b))
+		}
+	}
+
+	if opts.IPNetwork != nil {
+		query = query.Where("NULLIF(request->>'ip_address', '')::inet <<= ?::inet",
+			opts.IPNetwork.String())
+	}
+
+	if opts.JSONPath != "" {
+		query = query.Where("body_json #>> ? = ?",
+			pgdialect.Array(strings.Split(opts.JSONPath, ".")), opts.JSONValue)
+	}
+
+	// the trigram index on the start of the body is used for words of at
+	// least 3 characters
+	for _, word := range strings.Fields(opts.Text) {
+		query = query.Where("left(request->>'body', ?) ILIKE ? ESCAPE '\\'",
+			sdump.TextSearchLimit, likeContains(word))
+	}
+
+	return query
+}
+
+// sqliteSearchFilters matches the same requests as the postgres filters
+// with sqlite's json functions. There is no text index so every word of
+// the text is looked up in the body
+func sqliteSearchFilters(query *bun.SelectQuery,
+	opts *sdump.SearchIngestedRequestOptions,
+) *bun.SelectQuery {
+	if opts.HeaderName != "" {
+		path := sqliteJSONPath("headers", http.CanonicalHeaderKey(opts.HeaderName))
+
+		if opts.HeaderValue == "" {
+			query = query.Where("json_type(request, ?) IS NOT NULL", path)
+		} else {
+			query = query.Where("EXISTS (SELECT 1 FROM json_each(request, ?) WHERE json_each.value = ?)",
+				path, opts.HeaderValue)
+		}
+	}
+
+	if opts.JSONPath != "" {
+		path := sqliteJSONPath(strings.Split(opts.JSONPath, ".")...)
+
+		// booleans are returned as 1 and 0 by json_extract
+		query = query.Where(`(CASE json_type(body_json, ?)
+			WHEN 'true' THEN 'true'
+			WHEN 'false' THEN 'false'
+			ELSE CAST(json_extract(body_json, ?) AS TEXT) END) = ?`,
+			path, path, opts.JSONValue)
+	}
+
+	for _, word := range strings.Fields(opts.Text) {
+		query = query.Where("instr(lower(substr(request->>'body', 1, ?)), lower(?)) > 0",
+			sdump.TextSearchLimit, word)
+	}
+
+	return query
+}
+
+// sqliteJSONPath builds a json path such as $."headers"."Content-Type" or
+// $."items"[0]."id"
+func sqliteJSONPath(keys ...string) string {
+	var path strings.Builder
+
+	path.WriteString("$")
+
+	for _, key := range keys {
+		if _, err := strconv.ParseUint(key, 10, 64); err == nil {
+			path.WriteString("[" + key + "]")
+			continue
+		}
+
+		// sqlite json paths cannot escape quotes
+		path.WriteString(`."` + strings.ReplaceAll(key, `"`, "") + `"`)
+	}
+
+	return path.String()
+}
+
+// searchNetwork matches ip ranges outside of the database since sqlite does
+// not have a network type. Pages are fetched until enough requests match
+func (u *ingestRepository) searchNetwork(ctx context.Context,
+	opts *sdump.SearchIngestedRequestOptions,
+) ([]sdump.IngestHTTPRequest, error) {
+	var matches []sdump.IngestHTTPRequest
+
+	batchOpts := *opts
+
+	for {
+		requests, err := u.search(ctx, &batchOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, request := range requests {
+			if !opts.IPNetwork.Contains(request.Request.IPAddress) {
+				continue
+			}
+
+			matches = append(matches, request)
+			if len(matches) == opts.Limit {
+				return matches, nil
+			}
+		}
+
+		if opts.Limit <= 0 || len(requests) < opts.Limit {
+			return matches, nil
+		}
+
+		batchOpts.Cursor = requests[len(requests)-1].ID
+	}
+}
+
+// likePrefix matches values starting with s, treating LIKE wildcards in s
+// as literal characters
+func likePrefix(s string) string {
+	return escapeLike(s) + "%"
+}
+
+// likeContains matches values containing s
+func likeContains(s string) string {
+	return "%" + escapeLike(s) + "%"
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+func (u *ingestRepository) Get(ctx context.Context,
+	opts *sdump.FindIngestedRequestOptions,
+) (*sdump.IngestHTTPRequest, error) {
+	res := new(sdump.IngestHTTPRequest)
+
+	query := bun.NewSelectQuery(u.inner).Model(res).
+		Where("id = ?", opts.ID)
+
+	if opts.URLID != uuid.Nil {
+		query = query.Where("url_id = ?", opts.URLID)
+	}
+
+	err := query.Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, sdump.ErrIngestedRequestNotFound
+	}
+
+	return res, err
+}
+
 func (u *ingestRepository) Delete(ctx context.Context,
 	opts *sdump.DeleteIngestedRequestOptions,
-) error {
+) (int64, error) {
 	// This prevents us from deleting the entire database
-	// so enforce a time limit is available
-	if opts == nil {
-		return nil
+	// so enforce a time limit or specific requests are available
+	if opts == nil ||
+		(opts.Before.IsZero() && opts.ID == uuid.Nil && opts.URLID == uuid.Nil) {
+		return 0, nil
+	}
+
+	where := func(q bun.QueryBuilder) bun.QueryBuilder {
+		if !opts.Before.IsZero() {
+			q = q.Where("created_at < ?", opts.Before)
+		}
+
+		if opts.ID != uuid.Nil {
+			q = q.Where("id = ?", opts.ID)
+		}
+
+		if opts.URLID != uuid.Nil {
+			q = q.Where("url_id = ?", opts.URLID)
+		}
+
+		if opts.Plan != nil {
+			q = q.Where("url_id IN (?)", planEndpoints(u.inner, opts.Plan))
+		}
+
+		return q
 	}
 
 	deleteQuery := bun.NewDeleteQuery(u.inner).
-		Model((*sdump.IngestHTTPRequest)(nil)).
-		Where("created_at < ?", opts.Before)
+		Model((*sdump.IngestHTTPRequest)(nil))
+
+	if opts.Limit > 0 {
+		batch := bun.NewSelectQuery(u.inner).
+			Model((*sdump.IngestHTTPRequest)(nil)).
+			Column("id").
+			ApplyQueryBuilder(where).
+			Limit(opts.Limit)
+
+		// soft deleted requests are removed for good as well
+		if !opts.UseSoftDeletes {
+			batch = batch.WhereAllWithDeleted()
+		}
+
+		deleteQuery = deleteQuery.Where("id IN (?)", batch)
+	} else {
+		deleteQuery = deleteQuery.ApplyQueryBuilder(where)
+	}
 
 	if !opts.UseSoftDeletes {
 		deleteQuery = deleteQuery.ForceDelete()
 	}
 
-	_, err := deleteQuery.Exec(ctx)
-	return err
+	res, err := deleteQuery.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (u *ingestRepository) Purge(ctx context.Context,
+	opts *sdump.PurgeIngestedRequestOptions,
+) (int64, error) {
+	if opts == nil || opts.Before.IsZero() {
+		return 0, nil
+	}
+
+	deletedEndpoints := bun.NewSelectQuery(u.inner).
+		Table("urls").
+		Column("id").
+		Where("deleted_at IS NOT NULL").
+		Where("deleted_at < ?", opts.Before)
+
+	batch := bun.NewSelectQuery(u.inner).
+		Model((*sdump.IngestHTTPRequest)(nil)).
+		Column("id").
+		WhereAllWithDeleted().
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("deleted_at IS NOT NULL AND deleted_at < ?", opts.Before).
+				WhereOr("url_id IN (?)", deletedEndpoints)
+		})
+
+	if opts.Limit > 0 {
+		batch = batch.Limit(opts.Limit)
+	}
+
+	res, err := bun.NewDeleteQuery(u.inner).
+		Model((*sdump.IngestHTTPRequest)(nil)).
+		Where("id IN (?)", batch).
+		ForceDelete().
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// planEndpoints selects the ids of the endpoints owned by users on the
+// plan. Users without a plan are on the default plan
+func planEndpoints(db *bun.DB, plan *sdump.Plan) *bun.SelectQuery {
+	query := bun.NewSelectQuery(db).
+		Table("urls").
+		Column("urls.id").
+		Join("JOIN users ON users.id = urls.user_id")
+
+	if plan.IsDefault {
+		return query.Where("(users.plan_id = ? OR users.plan_id IS NULL)", plan.ID)
+	}
+
+	return query.Where("users.plan_id = ?", plan.ID)
+}
+
+func (u *ingestRepository) Count(ctx context.Context,
+	opts *sdump.CountIngestedRequestOptions,
+) (int64, error) {
+	// soft deleted requests and the requests of deleted endpoints are
+	// counted until they are purged. Requests removed for good, which is
+	// what deleting does unless cron.soft_deletes is on, are not
+	count, err := bun.NewSelectQuery(u.inner).
+		Model((*sdump.IngestHTTPRequest)(nil)).
+		WhereAllWithDeleted().
+		Where("url_id IN (?)", bun.NewSelectQuery(u.inner).
+			Table("urls").
+			Column("id").
+			Where("user_id = ?", opts.UserID)).
+		Where("created_at >= ?", opts.Since).
+		Count(ctx)
+	return int64(count), err
 }
@@ -5,10 +5,14 @@ package sql
 
 import (
 	"context"
+	"net"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/adelowo/sdump"
 	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
 )
 
 func TestIngestRepository_Create(t *testing.T) {
@@ -31,3 +35,444 @@ func TestIngestRepository_Create(t *testing.T) {
 		},
 	}))
 }
+
+func TestIngestRepository_Update(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	ingestStore := NewIngestRepository(client)
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	ingestedRequest := &sdump.IngestHTTPRequest{
+		UrlID: endpoint.ID,
+		Request: sdump.RequestDefinition{
+			Body: "{}",
+		},
+	}
+
+	require.NoError(t, ingestStore.Create(context.Background(), ingestedRequest))
+
+	ingestedRequest.Forward = &sdump.ForwardResult{
+		URL:        "http://localhost:3000",
+		StatusCode: 200,
+	}
+
+	require.NoError(t, ingestStore.Update(context.Background(), ingestedRequest))
+}
+
+func TestIngestRepository_List(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	ingestStore := NewIngestRepository(client)
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ingestStore.Create(context.Background(), &sdump.IngestHTTPRequest{
+			UrlID: endpoint.ID,
+			Request: sdump.RequestDefinition{
+				Body: "{}",
+			},
+		}))
+	}
+
+	firstPage, err := ingestStore.List(context.Background(), &sdump.ListIngestedRequestOptions{
+		URLID: endpoint.ID,
+		Limit: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, firstPage, 2)
+
+	secondPage, err := ingestStore.List(context.Background(), &sdump.ListIngestedRequestOptions{
+		URLID:  endpoint.ID,
+		Limit:  2,
+		Cursor: firstPage[1].ID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, secondPage)
+
+	for _, req := range secondPage {
+		require.NotEqual(t, firstPage[0].ID, req.ID)
+		require.NotEqual(t, firstPage[1].ID, req.ID)
+		require.False(t, req.CreatedAt.After(firstPage[1].CreatedAt))
+	}
+}
+
+func TestIngestRepository_Search(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	testIngestRepositorySearch(t, client)
+}
+
+// sqlite has its own search filters and matches ip ranges outside of the
+// database so the same cases are run against it
+func TestIngestRepository_Search_Sqlite(t *testing.T) {
+	client, teardownFunc := setupSqliteDatabase(t)
+	defer teardownFunc()
+
+	testIngestRepositorySearch(t, client)
+}
+
+func testIngestRepositorySearch(t *testing.T, client *bun.DB) {
+	t.Helper()
+
+	ingestStore := NewIngestRepository(client)
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	requests := []sdump.RequestDefinition{
+		{
+			Method:    http.MethodPost,
+			Path:      "/github",
+			Headers:   http.Header{"X-Github-Event": []string{"push"}},
+			IPAddress: net.ParseIP("10.0.1.20"),
+			Body:      `{"action" : "opened", "repository" : {"id" : 42, "name" : "sdump"}}`,
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/stripe",
+			Headers:   http.Header{"Stripe-Signature": []string{"t=1"}},
+			IPAddress: net.ParseIP("192.168.0.2"),
+			Body:      `{"type" : "customer.created", "data" : {"object" : {"id" : "cus_1"}}}`,
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/health",
+			IPAddress: net.ParseIP("10.0.1.21"),
+			Body:      "the service is healthy",
+		},
+	}
+
+	for _, request := range requests {
+		require.NoError(t, ingestStore.Create(context.Background(), &sdump.IngestHTTPRequest{
+			UrlID:   endpoint.ID,
+			Request: request,
+		}))
+	}
+
+	_, network, err := net.ParseCIDR("10.0.0.0/16")
+	require.NoError(t, err)
+
+	tt := []struct {
+		name     string
+		opts     sdump.SearchIngestedRequestOptions
+		expected []string
+	}{
+		{
+			name:     "method",
+			opts:     sdump.SearchIngestedRequestOptions{Method: "post"},
+			expected: []string{"/stripe", "/github"},
+		},
+		{
+			name:     "path",
+			opts:     sdump.SearchIngestedRequestOptions{Path: "/str"},
+			expected: []string{"/stripe"},
+		},
+		{
+			name:     "header is present",
+			opts:     sdump.SearchIngestedRequestOptions{HeaderName: "stripe-signature"},
+			expected: []string{"/stripe"},
+		},
+		{
+			name: "header value",
+			opts: sdump.SearchIngestedRequestOptions{
+				HeaderName:  "X-GitHub-Event",
+				HeaderValue: "push",
+			},
+			expected: []string{"/github"},
+		},
+		{
+			name:     "ip range",
+			opts:     sdump.SearchIngestedRequestOptions{IPNetwork: network},
+			expected: []string{"/health", "/github"},
+		},
+		{
+			name: "json path",
+			opts: sdump.SearchIngestedRequestOptions{
+				JSONPath:  "data.object.id",
+				JSONValue: "cus_1",
+			},
+			expected: []string{"/stripe"},
+		},
+		{
+			name: "json path number",
+			opts: sdump.SearchIngestedRequestOptions{
+				JSONPath:  "repository.id",
+				JSONValue: "42",
+			},
+			expected: []string{"/github"},
+		},
+		{
+			name:     "full text",
+			opts:     sdump.SearchIngestedRequestOptions{Text: "service healthy"},
+			expected: []string{"/health"},
+		},
+		{
+			name:     "text matches part of a word",
+			opts:     sdump.SearchIngestedRequestOptions{Text: "SERV heal"},
+			expected: []string{"/health"},
+		},
+		{
+			name: "every filter must match",
+			opts: sdump.SearchIngestedRequestOptions{
+				Method:    http.MethodGet,
+				IPNetwork: network,
+				Text:      "opened",
+			},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			v.opts.URLID = endpoint.ID
+			v.opts.Limit = 10
+
+			found, err := ingestStore.Search(context.Background(), &v.opts)
+			require.NoError(t, err)
+
+			paths := []string{}
+			for _, req := range found {
+				paths = append(paths, req.Request.Path)
+			}
+
+			if v.expected == nil {
+				v.expected = []string{}
+			}
+
+			require.Equal(t, v.expected, paths)
+		})
+	}
+
+	firstPage, err := ingestStore.Search(context.Background(), &sdump.SearchIngestedRequestOptions{
+		URLID:     endpoint.ID,
+		Limit:     1,
+		IPNetwork: network,
+	})
+	require.NoError(t, err)
+	require.Len(t, firstPage, 1)
+
+	secondPage, err := ingestStore.Search(context.Background(), &sdump.SearchIngestedRequestOptions{
+		URLID:     endpoint.ID,
+		Limit:     1,
+		IPNetwork: network,
+		Cursor:    firstPage[0].ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, secondPage, 1)
+	require.Equal(t, "/github", secondPage[0].Request.Path)
+}
+
+func TestIngestRepository_Get(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	ingestStore := NewIngestRepository(client)
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	ingestedRequest := &sdump.IngestHTTPRequest{
+		UrlID: endpoint.ID,
+		Request: sdump.RequestDefinition{
+			Body: "{}",
+		},
+	}
+
+	require.NoError(t, ingestStore.Create(context.Background(), ingestedRequest))
+
+	found, err := ingestStore.Get(context.Background(), &sdump.FindIngestedRequestOptions{
+		ID:    ingestedRequest.ID,
+		URLID: endpoint.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "{}", found.Request.Body)
+
+	_, err = ingestStore.Delete(context.Background(), &sdump.DeleteIngestedRequestOptions{
+		ID: ingestedRequest.ID,
+	})
+	require.NoError(t, err)
+
+	_, err = ingestStore.Get(context.Background(), &sdump.FindIngestedRequestOptions{
+		ID: ingestedRequest.ID,
+	})
+	require.ErrorIs(t, err, sdump.ErrIngestedRequestNotFound)
+}
+
+func TestIngestRepository_Count(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	ingestStore := NewIngestRepository(client)
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	since := time.Now().Add(-time.Minute)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ingestStore.Create(context.Background(), &sdump.IngestHTTPRequest{
+			UrlID: endpoint.ID,
+			Request: sdump.RequestDefinition{
+				Body: "{}",
+			},
+		}))
+	}
+
+	count, err := ingestStore.Count(context.Background(), &sdump.CountIngestedRequestOptions{
+		UserID: endpoint.UserID,
+		Since:  since,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	// soft deleted requests still count towards the quota
+	_, err = ingestStore.Delete(context.Background(), &sdump.DeleteIngestedRequestOptions{
+		URLID:          endpoint.ID,
+		UseSoftDeletes: true,
+	})
+	require.NoError(t, err)
+
+	count, err = ingestStore.Count(context.Background(), &sdump.CountIngestedRequestOptions{
+		UserID: endpoint.UserID,
+		Since:  since,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	// while requests removed for good give it back
+	_, err = ingestStore.Delete(context.Background(), &sdump.DeleteIngestedRequestOptions{
+		URLID: endpoint.ID,
+	})
+	require.NoError(t, err)
+
+	count, err = ingestStore.Count(context.Background(), &sdump.CountIngestedRequestOptions{
+		UserID: endpoint.UserID,
+		Since:  since,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+}
+
+func TestIngestRepository_Delete(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	ingestStore := NewIngestRepository(client)
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, ingestStore.Create(context.Background(), &sdump.IngestHTTPRequest{
+			UrlID: endpoint.ID,
+			Request: sdump.RequestDefinition{
+				Body: "{}",
+			},
+		}))
+	}
+
+	opts := &sdump.DeleteIngestedRequestOptions{
+		URLID:          endpoint.ID,
+		UseSoftDeletes: true,
+		Limit:          2,
+	}
+
+	// soft deleted requests are not picked again by the next batch
+	for _, expected := range []int64{2, 2, 1, 0} {
+		deleted, err := ingestStore.Delete(context.Background(), opts)
+		require.NoError(t, err)
+		require.Equal(t, expected, deleted)
+	}
+
+	// soft deleted requests are removed for good by hard deletes
+	deleted, err := ingestStore.Delete(context.Background(), &sdump.DeleteIngestedRequestOptions{
+		URLID: endpoint.ID,
+		Limit: 10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), deleted)
+}
+
+func TestIngestRepository_Purge(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	ingestStore := NewIngestRepository(client)
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ingestStore.Create(context.Background(), &sdump.IngestHTTPRequest{
+			UrlID: endpoint.ID,
+			Request: sdump.RequestDefinition{
+				Body: "{}",
+			},
+		}))
+	}
+
+	before := time.Now().Add(time.Minute)
+
+	// nothing has been deleted yet
+	purged, err := ingestStore.Purge(context.Background(), &sdump.PurgeIngestedRequestOptions{
+		Before: before,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), purged)
+
+	_, err = ingestStore.Delete(context.Background(), &sdump.DeleteIngestedRequestOptions{
+		URLID:          endpoint.ID,
+		UseSoftDeletes: true,
+		Limit:          1,
+	})
+	require.NoError(t, err)
+
+	purged, err = ingestStore.Purge(context.Background(), &sdump.PurgeIngestedRequestOptions{
+		Before: before,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+
+	// the requests left are orphaned once the endpoint is deleted
+	require.NoError(t, urlStore.Delete(context.Background(), endpoint))
+
+	purged, err = ingestStore.Purge(context.Background(), &sdump.PurgeIngestedRequestOptions{
+		Before: before,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+}
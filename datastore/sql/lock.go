@@ -0,0 +1,50 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/adelowo/sdump"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+type locker struct {
+	inner *bun.DB
+}
+
+// NewLocker uses postgres advisory locks. They belong to the connection
+// that took them so a lock is released if its server goes away
+func NewLocker(db *bun.DB) sdump.Locker {
+	return &locker{
+		inner: db,
+	}
+}
+
+func (l *locker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	// only a single process can use a sqlite database
+	if l.inner.Dialect().Name() == dialect.SQLite {
+		return func() {}, true, nil
+	}
+
+	conn, err := l.inner.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var locked bool
+	if err := conn.NewRaw("SELECT pg_try_advisory_lock(hashtext(?))", name).
+		Scan(ctx, &locked); err != nil {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	if !locked {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext(?))", name)
+		_ = conn.Close()
+	}, true, nil
+}
@@ -0,0 +1,165 @@
+package sql
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+
+	"github.com/adelowo/sdump/config"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// migrations are embedded so the binary can migrate the database without
+// the migrate CLI. Postgres uses the top level directory, sqlite has its own
+// since the schemas differ
+//
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrations embed.FS
+
+// Migrator applies the embedded migrations to a database
+type Migrator struct {
+	inner  *migrate.Migrate
+	source source.Driver
+}
+
+// MigrationStatus describes a single embedded migration
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	IsApplied bool
+}
+
+// NewMigrator opens its own connection to the database since the migrate
+// drivers close it once done. Call Close to release it. Postgres is migrated
+// through lib/pq which is what the migrate driver expects
+func NewMigrator(cfg config.DatabaseConfig) (*Migrator, error) {
+	path, driverName := "migrations", "postgres"
+	if cfg.Driver == config.DatabaseTypeSqlite {
+		path, driverName = "migrations/sqlite", sqliteshim.ShimName
+	}
+
+	db, err := sql.Open(driverName, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := iofs.New(migrations, path)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	var driver database.Driver
+
+	if cfg.Driver == config.DatabaseTypeSqlite {
+		driver, err = sqlite.WithInstance(db, &sqlite.Config{})
+	} else {
+		driver, err = postgres.WithInstance(db, &postgres.Config{})
+	}
+
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	inner, err := migrate.NewWithInstance("iofs", src, string(cfg.Driver), driver)
+	if err != nil {
+		_ = driver.Close()
+		return nil, err
+	}
+
+	return &Migrator{
+		inner:  inner,
+		source: src,
+	}, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	err := m.inner.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+// Down rolls back the last n applied migrations
+func (m *Migrator) Down(n int) error {
+	err := m.inner.Steps(-n)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+// Version is the last applied migration. It is zero if none has been
+// applied. dirty is true when a migration failed halfway and the database
+// has to be fixed by hand
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.inner.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// Status lists every embedded migration and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	current, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+
+	version, err := m.source.First()
+	for err == nil {
+		var name string
+
+		name, err = m.migrationName(version)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      name,
+			IsApplied: version <= current,
+		})
+
+		version, err = m.source.Next(version)
+	}
+
+	// the source reports there is no migration after the last one as
+	// not existing
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) migrationName(version uint) (string, error) {
+	r, name, err := m.source.ReadUp(version)
+	if err != nil {
+		return "", err
+	}
+
+	return name, r.Close()
+}
+
+// Close releases the connection opened by NewMigrator
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.inner.Close()
+	return errors.Join(sourceErr, dbErr)
+}
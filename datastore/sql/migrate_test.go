@@ -0,0 +1,78 @@
+//go:build integration
+// +build integration
+
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/adelowo/sdump/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testMigrator(t *testing.T, cfg config.DatabaseConfig) {
+	t.Helper()
+
+	migrator, err := NewMigrator(cfg)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, migrator.Close())
+	}()
+
+	version, dirty, err := migrator.Version()
+	require.NoError(t, err)
+	require.Zero(t, version)
+	require.False(t, dirty)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+
+	for _, status := range statuses {
+		require.False(t, status.IsApplied)
+	}
+
+	require.NoError(t, migrator.Up())
+
+	// nothing left to apply
+	require.NoError(t, migrator.Up())
+
+	latest := statuses[len(statuses)-1]
+
+	version, _, err = migrator.Version()
+	require.NoError(t, err)
+	require.Equal(t, latest.Version, version)
+
+	statuses, err = migrator.Status()
+	require.NoError(t, err)
+
+	for _, status := range statuses {
+		require.True(t, status.IsApplied)
+	}
+
+	require.NoError(t, migrator.Down(len(statuses)))
+
+	version, _, err = migrator.Version()
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	require.NoError(t, migrator.Up())
+}
+
+func TestMigrator_Sqlite(t *testing.T) {
+	testMigrator(t, config.DatabaseConfig{
+		DSN:    "file:" + filepath.Join(t.TempDir(), "sdump.db"),
+		Driver: config.DatabaseTypeSqlite,
+	})
+}
+
+func TestMigrator_Postgres(t *testing.T) {
+	dsn, teardownFunc := startPostgresContainer(t)
+	defer teardownFunc()
+
+	testMigrator(t, config.DatabaseConfig{
+		DSN: dsn,
+	})
+}
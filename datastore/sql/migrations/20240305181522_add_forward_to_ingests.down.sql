@@ -0,0 +1 @@
+ALTER TABLE ingests DROP COLUMN forward;
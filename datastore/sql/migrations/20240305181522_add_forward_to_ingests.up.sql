@@ -0,0 +1 @@
+ALTER TABLE ingests ADD forward jsonb;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD ban_reason TEXT;
+ALTER TABLE users ADD banned_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS plans(
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR (100) UNIQUE NOT NULL,
+    max_endpoints BIGINT NOT NULL DEFAULT 0,
+    max_requests_per_day BIGINT NOT NULL DEFAULT 0,
+    retention_days BIGINT NOT NULL DEFAULT 0,
+    max_request_body_size BIGINT NOT NULL DEFAULT 0,
+    is_default BOOLEAN DEFAULT FALSE NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS plans_is_default_idx ON plans (is_default) WHERE is_default;
+
+INSERT INTO plans (name, is_default) VALUES ('free', TRUE);
+
+ALTER TABLE users ADD plan_id uuid REFERENCES plans(id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS urls_user_id_name_idx;
+DROP INDEX IF EXISTS urls_reference_idx;
+ALTER TABLE urls DROP COLUMN name;
@@ -0,0 +1,4 @@
+ALTER TABLE urls ADD name VARCHAR (100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS urls_reference_idx ON urls (reference) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS urls_user_id_name_idx ON urls (user_id, name) WHERE deleted_at IS NULL AND name IS NOT NULL;
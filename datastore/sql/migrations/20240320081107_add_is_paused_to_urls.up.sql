@@ -0,0 +1 @@
+ALTER TABLE urls ADD is_paused BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS ingests_body_text_idx;
+DROP INDEX IF EXISTS ingests_headers_idx;
+DROP INDEX IF EXISTS ingests_method_idx;
+DROP INDEX IF EXISTS ingests_url_id_created_at_id_idx;
+
+ALTER TABLE ingests DROP COLUMN body_json;
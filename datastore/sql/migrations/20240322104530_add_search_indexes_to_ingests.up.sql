@@ -0,0 +1,25 @@
+ALTER TABLE ingests ADD body_json jsonb;
+
+-- requests stored before body_json existed. Bodies that are not valid json
+-- are left out just like new requests are
+CREATE OR REPLACE FUNCTION pg_temp.try_jsonb(value text) RETURNS jsonb AS $$
+BEGIN
+    RETURN value::jsonb;
+EXCEPTION WHEN others THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE ingests SET body_json = pg_temp.try_jsonb(request->>'body')
+WHERE body_json IS NULL AND request->>'is_base64_encoded' IS NULL;
+
+-- trusted since Postgres 13, the database owner can create it
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS ingests_url_id_created_at_id_idx ON ingests (url_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS ingests_method_idx ON ingests ((request->>'method'));
+CREATE INDEX IF NOT EXISTS ingests_headers_idx ON ingests USING GIN ((request->'headers'));
+
+-- only the start of the body is indexed so large bodies stay cheap to
+-- insert. The length has to match sdump.TextSearchLimit
+CREATE INDEX IF NOT EXISTS ingests_body_text_idx ON ingests USING GIN ((left(request->>'body', 65536)) gin_trgm_ops);
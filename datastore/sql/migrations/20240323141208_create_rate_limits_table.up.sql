@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rate_limits(
+    key TEXT PRIMARY KEY,
+    tokens BIGINT NOT NULL,
+    interval_ms BIGINT NOT NULL,
+    available BIGINT NOT NULL,
+    reset_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_reset_at_idx ON rate_limits(reset_at);
@@ -0,0 +1,4 @@
+ALTER TABLE plans DROP COLUMN ingest_requests_per_minute;
+ALTER TABLE plans DROP COLUMN api_requests_per_minute;
+ALTER TABLE users DROP COLUMN ingest_requests_per_minute;
+ALTER TABLE users DROP COLUMN api_requests_per_minute;
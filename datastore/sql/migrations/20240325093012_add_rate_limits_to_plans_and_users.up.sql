@@ -0,0 +1,4 @@
+ALTER TABLE plans ADD ingest_requests_per_minute BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE plans ADD api_requests_per_minute BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD ingest_requests_per_minute BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD api_requests_per_minute BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS ingests;
+DROP TABLE IF EXISTS urls;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS plans;
@@ -0,0 +1,85 @@
+-- the sqlite schema starts at the version of the postgres migrations it
+-- mirrors. ids are random uuids since sqlite has no uuid_generate_v4()
+
+CREATE TABLE IF NOT EXISTS plans(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    name VARCHAR (100) UNIQUE NOT NULL,
+    max_endpoints BIGINT NOT NULL DEFAULT 0,
+    max_requests_per_day BIGINT NOT NULL DEFAULT 0,
+    retention_days BIGINT NOT NULL DEFAULT 0,
+    max_request_body_size BIGINT NOT NULL DEFAULT 0,
+    ingest_requests_per_minute BIGINT NOT NULL DEFAULT 0,
+    api_requests_per_minute BIGINT NOT NULL DEFAULT 0,
+    is_default BOOLEAN DEFAULT FALSE NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS plans_is_default_idx ON plans (is_default) WHERE is_default;
+
+INSERT INTO plans (name, is_default) VALUES ('free', TRUE);
+
+CREATE TABLE IF NOT EXISTS users(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    ssh_finger_print VARCHAR (200) UNIQUE NOT NULL,
+    is_banned BOOLEAN DEFAULT FALSE NOT NULL,
+    ban_reason TEXT,
+    banned_at TIMESTAMP,
+    plan_id TEXT REFERENCES plans(id),
+    ingest_requests_per_minute BIGINT NOT NULL DEFAULT 0,
+    api_requests_per_minute BIGINT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS urls(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    reference VARCHAR (200) NOT NULL,
+    name VARCHAR (100),
+    user_id TEXT NOT NULL REFERENCES users(id),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    is_paused BOOLEAN NOT NULL DEFAULT FALSE,
+
+    metadata TEXT NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS urls_reference_idx ON urls (reference) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS urls_user_id_name_idx ON urls (user_id, name) WHERE deleted_at IS NULL AND name IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS ingests(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    url_id TEXT NOT NULL REFERENCES urls(id),
+
+    request TEXT NOT NULL DEFAULT '{}',
+    forward TEXT,
+    tunnel TEXT,
+    body_json TEXT,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ingests_url_id_created_at_id_idx ON ingests (url_id, created_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS api_tokens(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    user_id TEXT NOT NULL REFERENCES users(id),
+    name VARCHAR (50) NOT NULL,
+    hash VARCHAR (64) UNIQUE NOT NULL,
+    prefix VARCHAR (20) NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id_idx ON api_tokens(user_id);
@@ -0,0 +1,77 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type planRepositoryTable struct {
+	inner *bun.DB
+}
+
+func NewPlanRepositoryTable(db *bun.DB) sdump.PlanRepository {
+	return &planRepositoryTable{
+		inner: db,
+	}
+}
+
+func (p *planRepositoryTable) Create(ctx context.Context,
+	model *sdump.Plan,
+) error {
+	newID(&model.ID)
+
+	_, err := bun.NewInsertQuery(p.inner).Model(model).
+		Exec(ctx)
+	return err
+}
+
+func (p *planRepositoryTable) Update(ctx context.Context,
+	model *sdump.Plan,
+) error {
+	model.UpdatedAt = time.Now()
+
+	_, err := bun.NewUpdateQuery(p.inner).Model(model).
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (p *planRepositoryTable) Find(ctx context.Context,
+	opts *sdump.FindPlanOptions,
+) (*sdump.Plan, error) {
+	res := new(sdump.Plan)
+
+	query := bun.NewSelectQuery(p.inner).Model(res)
+
+	switch {
+	case opts.ID != uuid.Nil:
+		query = query.Where("id = ?", opts.ID)
+	case opts.Name != "":
+		query = query.Where("name = ?", opts.Name)
+	default:
+		query = query.Where("is_default = ?", true)
+	}
+
+	err := query.Limit(1).Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, sdump.ErrPlanNotFound
+	}
+
+	return res, err
+}
+
+func (p *planRepositoryTable) List(ctx context.Context) ([]sdump.Plan, error) {
+	var plans []sdump.Plan
+
+	err := bun.NewSelectQuery(p.inner).Model(&plans).
+		Order("created_at ASC").
+		Scan(ctx)
+	return plans, err
+}
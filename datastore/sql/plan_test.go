@@ -0,0 +1,52 @@
+//go:build integration
+// +build integration
+
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRepository_Find(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	planStore := NewPlanRepositoryTable(client)
+
+	// seeded by the migration
+	plan, err := planStore.Find(context.Background(), &sdump.FindPlanOptions{})
+	require.NoError(t, err)
+	require.True(t, plan.IsDefault)
+
+	_, err = planStore.Find(context.Background(), &sdump.FindPlanOptions{
+		ID: uuid.New(),
+	})
+	require.ErrorIs(t, err, sdump.ErrPlanNotFound)
+
+	pro := &sdump.Plan{
+		Name:              "pro",
+		MaxEndpoints:      10,
+		MaxRequestsPerDay: 10000,
+	}
+
+	require.NoError(t, planStore.Create(context.Background(), pro))
+
+	pro.RetentionDays = 30
+	require.NoError(t, planStore.Update(context.Background(), pro))
+
+	found, err := planStore.Find(context.Background(), &sdump.FindPlanOptions{
+		Name: "pro",
+	})
+	require.NoError(t, err)
+	require.Equal(t, pro.ID, found.ID)
+	require.Equal(t, int64(30), found.RetentionDays)
+
+	plans, err := planStore.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, plans, 2)
+}
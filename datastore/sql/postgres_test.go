@@ -11,9 +11,6 @@ import (
 
 	"github.com/adelowo/sdump/config"
 	testfixtures "github.com/go-testfixtures/testfixtures/v3"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -25,21 +22,18 @@ func preparePostgresTestDatabase(t *testing.T, dsn string) {
 
 	var err error
 
-	db, err := sql.Open("postgres", dsn)
+	migrator, err := NewMigrator(config.DatabaseConfig{
+		DSN: dsn,
+	})
 	require.NoError(t, err)
 
-	require.NoError(t, db.Ping())
+	require.NoError(t, migrator.Up())
+	require.NoError(t, migrator.Close())
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	require.NoError(t, err)
-
-	migrator, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", "migrations"), "postgres", driver)
+	db, err := sql.Open("postgres", dsn)
 	require.NoError(t, err)
 
-	if err := migrator.Up(); err != nil && err != migrate.ErrNoChange {
-		require.NoError(t, err)
-	}
+	require.NoError(t, db.Ping())
 
 	fixtures, err := testfixtures.New(
 		testfixtures.Database(db),
@@ -51,13 +45,11 @@ func preparePostgresTestDatabase(t *testing.T, dsn string) {
 	require.NoError(t, fixtures.Load())
 }
 
-// setupPostgresDatabase spins up a new Postgres container and returns a closure
-// please always make sure to call the closure as it is the teardown function
-func setupPostgresDatabase(t *testing.T) (*bun.DB, func()) {
+// startPostgresContainer spins up a new Postgres container without running
+// the migrations. The returned closure is the teardown function
+func startPostgresContainer(t *testing.T) (string, func()) {
 	t.Helper()
 
-	var dsn string
-
 	containerReq := testcontainers.ContainerRequest{
 		Image:        "postgres:latest",
 		ExposedPorts: []string{"5432/tcp"},
@@ -81,9 +73,22 @@ func setupPostgresDatabase(t *testing.T) (*bun.DB, func()) {
 	port, err := dbContainer.MappedPort(context.Background(), "5432")
 	require.NoError(t, err)
 
-	dsn = fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", "sdump", "sdump",
+	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", "sdump", "sdump",
 		fmt.Sprintf("localhost:%s", port.Port()), "sdumptest")
 
+	return dsn, func() {
+		err := dbContainer.Terminate(context.Background())
+		require.NoError(t, err)
+	}
+}
+
+// setupPostgresDatabase spins up a new Postgres container and returns a closure
+// please always make sure to call the closure as it is the teardown function
+func setupPostgresDatabase(t *testing.T) (*bun.DB, func()) {
+	t.Helper()
+
+	dsn, teardownFunc := startPostgresContainer(t)
+
 	preparePostgresTestDatabase(t, dsn)
 
 	client, err := New(config.DatabaseConfig{
@@ -91,8 +96,5 @@ func setupPostgresDatabase(t *testing.T) (*bun.DB, func()) {
 	})
 	require.NoError(t, err)
 
-	return client, func() {
-		err := dbContainer.Terminate(context.Background())
-		require.NoError(t, err)
-	}
+	return client, teardownFunc
 }
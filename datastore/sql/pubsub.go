@@ -0,0 +1,79 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/adelowo/sdump"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// pubsubChannel is the postgres channel every HTTP server listens on
+const pubsubChannel = "sdump_events"
+
+// maxNotifyPayloadSize is the largest payload postgres accepts in a NOTIFY
+const maxNotifyPayloadSize = 7999
+
+type postgresPubSub struct {
+	inner *bun.DB
+}
+
+// NewPostgresPubSub delivers events to every HTTP server connected to the
+// same database with LISTEN/NOTIFY
+func NewPostgresPubSub(db *bun.DB) sdump.PubSub {
+	return &postgresPubSub{
+		inner: db,
+	}
+}
+
+func (p *postgresPubSub) Publish(ctx context.Context, event *sdump.Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// subscribers load large requests from the database instead
+	if len(b) > maxNotifyPayloadSize {
+		b, err = json.Marshal(&sdump.Event{
+			Channel: event.Channel,
+			ID:      event.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return pgdriver.Notify(ctx, p.inner, pubsubChannel, string(b))
+}
+
+func (p *postgresPubSub) Subscribe(ctx context.Context, fn func(*sdump.Event)) error {
+	ln := pgdriver.NewListener(p.inner)
+	defer ln.Close()
+
+	if err := ln.Listen(ctx, pubsubChannel); err != nil {
+		return err
+	}
+
+	// the listener reconnects on its own if the connection is lost
+	notifications := ln.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case notification, ok := <-notifications:
+			if !ok {
+				return nil
+			}
+
+			event := new(sdump.Event)
+			if err := json.Unmarshal([]byte(notification.Payload), event); err != nil {
+				continue
+			}
+
+			fn(event)
+		}
+	}
+}
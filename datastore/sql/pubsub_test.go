@@ -0,0 +1,141 @@
+//go:build integration
+// +build integration
+
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/signature"
+	"github.com/adelowo/sdump/server/httpd"
+	"github.com/r3labs/sse/v2"
+	"github.com/sethvargo/go-limiter"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// newTestServer runs an HTTP server with its own database connection, as
+// another replica would
+func newTestServer(t *testing.T, ctx context.Context, cfg config.Config, db *bun.DB) *httptest.Server {
+	t.Helper()
+
+	rateLimiter := httpd.NewRateLimiter(func(tokens uint64) (limiter.Store, error) {
+		return NewRateLimitStore(db, tokens, time.Minute), nil
+	})
+
+	forwarder := httpd.NewForwarder(1, 1)
+
+	logrus.SetOutput(io.Discard)
+	logger := logrus.WithField("module", "test")
+
+	ingestStore := NewIngestRepository(db)
+	pubSub := NewPostgresPubSub(db)
+	sseServer := sse.New()
+
+	go func() {
+		_ = httpd.Relay(ctx, pubSub, sseServer, ingestStore, logger)
+	}()
+
+	server := httptest.NewServer(httpd.New(cfg, NewURLRepositoryTable(db), ingestStore,
+		NewUserRepositoryTable(db), NewAPITokenRepositoryTable(db), NewPlanRepositoryTable(db),
+		logger, sseServer, pubSub, rateLimiter, forwarder).Handler)
+
+	t.Cleanup(func() {
+		server.Close()
+		sseServer.Close()
+		_ = rateLimiter.Close(context.Background())
+		_ = forwarder.Close(context.Background())
+	})
+
+	return server
+}
+
+func TestPostgresPubSub(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.Config{
+		HTTP: config.HTTPConfig{
+			AdminSecret:        "sdump-admin-secret",
+			MaxRequestBodySize: 1024 * 100,
+		},
+	}
+
+	ingestServer := newTestServer(t, ctx, cfg, client)
+	streamServer := newTestServer(t, ctx, cfg, bun.NewDB(client.DB, pgdialect.New()))
+
+	endpoint, err := NewURLRepositoryTable(client).Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	token, err := signature.NewToken(cfg.HTTP.AdminSecret, signature.Claims{
+		Subject:  endpoint.UserID.String(),
+		Audience: endpoint.PubChannel(),
+	}, time.Minute, time.Now())
+	require.NoError(t, err)
+
+	received := make(chan *sse.Event, 10)
+
+	sseClient := sse.NewClient(streamServer.URL + "/events")
+	sseClient.Headers["X-Stream-Token"] = token
+
+	go func() {
+		_ = sseClient.SubscribeWithContext(ctx, endpoint.PubChannel(), func(msg *sse.Event) {
+			received <- msg
+		})
+	}()
+
+	// NOTIFY cannot carry this body so it has to be loaded from the database
+	largeBody := `{"data" : "` + strings.Repeat("a", 10_000) + `"}`
+
+	for _, body := range []string{`{"event" : "push"}`, largeBody} {
+		var event struct {
+			ID      string                  `json:"id"`
+			Request sdump.RequestDefinition `json:"request"`
+		}
+
+		// the subscription could still be setting up so keep sending
+		// requests until one is streamed by the other server
+		require.Eventually(t, func() bool {
+			resp, err := http.Post(ingestServer.URL+"/"+endpoint.Reference,
+				"application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			timeout := time.After(500 * time.Millisecond)
+
+			for {
+				select {
+				case msg := <-received:
+					require.NoError(t, json.Unmarshal(msg.Data, &event))
+
+					// late events of the previous body are skipped
+					if event.Request.Body == body {
+						return true
+					}
+
+				case <-timeout:
+					return false
+				}
+			}
+		}, 15*time.Second, 100*time.Millisecond)
+
+		require.NotEmpty(t, event.ID)
+	}
+}
@@ -0,0 +1,161 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sethvargo/go-limiter"
+	"github.com/uptrace/bun"
+)
+
+// rateLimitSweepInterval is how often buckets whose interval has ended are
+// removed. They are recreated with a full bucket on the next request
+const rateLimitSweepInterval = time.Hour
+
+type rateLimitStore struct {
+	inner    *bun.DB
+	tokens   uint64
+	interval time.Duration
+
+	stopped  atomic.Bool
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRateLimitStore shares rate limit buckets between every HTTP server
+// connected to the same database. Each key gets tokens requests per
+// interval. The database clock is used so servers agree on when buckets
+// are refilled
+func NewRateLimitStore(db *bun.DB, tokens uint64, interval time.Duration) limiter.Store {
+	s := &rateLimitStore{
+		inner:    db,
+		tokens:   tokens,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	go s.sweep()
+
+	return s
+}
+
+func (s *rateLimitStore) sweep() {
+	ticker := time.NewTicker(rateLimitSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			_, _ = s.inner.NewDelete().
+				Table("rate_limits").
+				Where("reset_at < now()").
+				Exec(context.Background())
+		}
+	}
+}
+
+func (s *rateLimitStore) Take(ctx context.Context, key string) (uint64, uint64, uint64, bool, error) {
+	if s.stopped.Load() {
+		return 0, 0, 0, false, limiter.ErrStopped
+	}
+
+	var (
+		tokens    uint64
+		available int64
+		resetAt   time.Time
+	)
+
+	// the bucket is created, refilled or taken from in a single statement
+	// so concurrent requests on different servers cannot overspend it
+	err := s.inner.NewRaw(`INSERT INTO rate_limits (key, tokens, interval_ms, available, reset_at)
+		VALUES (?, ?, ?, ? - 1, now() + ? * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE SET
+			available = CASE WHEN rate_limits.reset_at <= now() THEN rate_limits.tokens - 1
+				ELSE GREATEST(rate_limits.available - 1, -1) END,
+			reset_at = CASE WHEN rate_limits.reset_at <= now()
+				THEN now() + rate_limits.interval_ms * interval '1 millisecond'
+				ELSE rate_limits.reset_at END
+		RETURNING tokens, available, reset_at`,
+		key, s.tokens, s.interval.Milliseconds(), s.tokens, s.interval.Milliseconds()).
+		Scan(ctx, &tokens, &available, &resetAt)
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+
+	if available < 0 {
+		return tokens, 0, uint64(resetAt.UnixNano()), false, nil
+	}
+
+	return tokens, uint64(available), uint64(resetAt.UnixNano()), true, nil
+}
+
+func (s *rateLimitStore) Get(ctx context.Context, key string) (uint64, uint64, error) {
+	if s.stopped.Load() {
+		return 0, 0, limiter.ErrStopped
+	}
+
+	var (
+		tokens    uint64
+		available int64
+	)
+
+	err := s.inner.NewRaw(`SELECT tokens,
+		CASE WHEN reset_at <= now() THEN tokens ELSE GREATEST(available, 0) END
+		FROM rate_limits WHERE key = ?`, key).
+		Scan(ctx, &tokens, &available)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.tokens, s.tokens, nil
+	}
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return tokens, uint64(available), nil
+}
+
+func (s *rateLimitStore) Set(ctx context.Context, key string, tokens uint64, interval time.Duration) error {
+	if s.stopped.Load() {
+		return limiter.ErrStopped
+	}
+
+	_, err := s.inner.NewRaw(`INSERT INTO rate_limits (key, tokens, interval_ms, available, reset_at)
+		VALUES (?, ?, ?, ?, now() + ? * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = EXCLUDED.tokens,
+			interval_ms = EXCLUDED.interval_ms,
+			available = EXCLUDED.available,
+			reset_at = EXCLUDED.reset_at`,
+		key, tokens, interval.Milliseconds(), tokens, interval.Milliseconds()).
+		Exec(ctx)
+	return err
+}
+
+func (s *rateLimitStore) Burst(ctx context.Context, key string, tokens uint64) error {
+	if s.stopped.Load() {
+		return limiter.ErrStopped
+	}
+
+	_, err := s.inner.NewRaw(`UPDATE rate_limits
+		SET available = GREATEST(available, 0) + ?
+		WHERE key = ? AND reset_at > now()`, tokens, key).
+		Exec(ctx)
+	return err
+}
+
+// Close stops removing expired buckets. The database connection is left
+// open as it is shared with the rest of the server
+func (s *rateLimitStore) Close(_ context.Context) error {
+	s.stopOnce.Do(func() {
+		s.stopped.Store(true)
+		close(s.stop)
+	})
+
+	return nil
+}
@@ -0,0 +1,75 @@
+//go:build integration
+// +build integration
+
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestRateLimitStore(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	store := NewRateLimitStore(client, 2, 3*time.Second)
+	defer store.Close(context.Background())
+
+	// another server sharing the same database
+	otherStore := NewRateLimitStore(bun.NewDB(client.DB, pgdialect.New()), 2, 3*time.Second)
+	defer otherStore.Close(context.Background())
+
+	tokens, remaining, _, ok, err := store.Take(context.Background(), "cmltfm6g330l5l1vq110")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), tokens)
+	require.Equal(t, uint64(1), remaining)
+
+	_, remaining, _, ok, err = otherStore.Take(context.Background(), "cmltfm6g330l5l1vq110")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(0), remaining)
+
+	_, _, reset, ok, err := store.Take(context.Background(), "cmltfm6g330l5l1vq110")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Greater(t, reset, uint64(time.Now().Add(-time.Minute).UnixNano()))
+
+	// other keys have their own bucket
+	_, _, _, ok, err = otherStore.Take(context.Background(), "cmltg1eg330l5l1vq11g")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, store.Burst(context.Background(), "cmltfm6g330l5l1vq110", 1))
+
+	_, remaining, err = otherStore.Get(context.Background(), "cmltfm6g330l5l1vq110")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), remaining)
+
+	_, _, _, ok, err = otherStore.Take(context.Background(), "cmltfm6g330l5l1vq110")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, _, _, ok, err = store.Take(context.Background(), "cmltfm6g330l5l1vq110")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// the bucket is refilled once the interval ends
+	require.Eventually(t, func() bool {
+		_, _, _, ok, err := store.Take(context.Background(), "cmltfm6g330l5l1vq110")
+		require.NoError(t, err)
+		return ok
+	}, 10*time.Second, 200*time.Millisecond)
+
+	require.NoError(t, store.Set(context.Background(), "cmltfm6g330l5l1vq110", 5, time.Minute))
+
+	tokens, remaining, err = store.Get(context.Background(), "cmltfm6g330l5l1vq110")
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), tokens)
+	require.Equal(t, uint64(5), remaining)
+}
@@ -5,7 +5,7 @@ package sql
 
 import (
 	"database/sql"
-	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/adelowo/sdump/config"
@@ -13,9 +13,6 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/driver/sqliteshim"
-
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 )
 
 func prepareSqliteTestDatabase(t *testing.T, dsn string) {
@@ -28,16 +25,16 @@ func prepareSqliteTestDatabase(t *testing.T, dsn string) {
 
 	require.NoError(t, db.Ping())
 
-	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
-	require.NoError(t, err)
-
-	migrator, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", "migrations"), "sqllite3", driver)
+	// the database lives as long as a connection to it is open so db has
+	// to be opened first
+	migrator, err := NewMigrator(config.DatabaseConfig{
+		DSN:    dsn,
+		Driver: config.DatabaseTypeSqlite,
+	})
 	require.NoError(t, err)
 
-	if err := migrator.Up(); err != nil && err != migrate.ErrNoChange {
-		require.NoError(t, err)
-	}
+	require.NoError(t, migrator.Up())
+	require.NoError(t, migrator.Close())
 
 	fixtures, err := testfixtures.New(
 		testfixtures.Database(db),
@@ -52,7 +49,8 @@ func prepareSqliteTestDatabase(t *testing.T, dsn string) {
 func setupSqliteDatabase(t *testing.T) (*bun.DB, func()) {
 	t.Helper()
 
-	dsn := "file::memory:?cache=shared"
+	// testfixtures only loads into databases with test in their name
+	dsn := "file:" + filepath.Join(t.TempDir(), "sdump_test.db")
 
 	prepareSqliteTestDatabase(t, dsn)
 
@@ -63,5 +61,6 @@ func setupSqliteDatabase(t *testing.T) (*bun.DB, func()) {
 	require.NoError(t, err)
 
 	return client, func() {
+		require.NoError(t, client.Close())
 	}
 }
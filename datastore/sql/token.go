@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type apiTokenRepositoryTable struct {
+	inner *bun.DB
+}
+
+func NewAPITokenRepositoryTable(db *bun.DB) sdump.APITokenRepository {
+	return &apiTokenRepositoryTable{
+		inner: db,
+	}
+}
+
+func (a *apiTokenRepositoryTable) Create(ctx context.Context,
+	model *sdump.APIToken,
+) error {
+	newID(&model.ID)
+
+	_, err := bun.NewInsertQuery(a.inner).Model(model).
+		Exec(ctx)
+	return err
+}
+
+func (a *apiTokenRepositoryTable) Find(ctx context.Context,
+	opts *sdump.FindAPITokenOptions,
+) (*sdump.APIToken, error) {
+	// an empty filter would match any token
+	if opts.ID == uuid.Nil && opts.Hash == "" {
+		return nil, sdump.ErrAPITokenNotFound
+	}
+
+	res := new(sdump.APIToken)
+
+	query := bun.NewSelectQuery(a.inner).Model(res)
+
+	if opts.ID != uuid.Nil {
+		query = query.Where("id = ?", opts.ID)
+	}
+
+	if opts.UserID != uuid.Nil {
+		query = query.Where("user_id = ?", opts.UserID)
+	}
+
+	if opts.Hash != "" {
+		query = query.Where("hash = ?", opts.Hash)
+	}
+
+	err := query.Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, sdump.ErrAPITokenNotFound
+	}
+
+	return res, err
+}
+
+func (a *apiTokenRepositoryTable) List(ctx context.Context,
+	userID uuid.UUID,
+) ([]sdump.APIToken, error) {
+	var tokens []sdump.APIToken
+
+	err := bun.NewSelectQuery(a.inner).Model(&tokens).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Scan(ctx)
+
+	return tokens, err
+}
+
+func (a *apiTokenRepositoryTable) Revoke(ctx context.Context,
+	model *sdump.APIToken,
+) error {
+	_, err := bun.NewDeleteQuery(a.inner).Model(model).
+		WherePK().
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,89 @@
+//go:build integration
+// +build integration
+
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenRepositoryTable_Create(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	tokenStore := NewAPITokenRepositoryTable(client)
+
+	token, _, err := sdump.NewAPIToken(userID, "ci")
+	require.NoError(t, err)
+
+	require.NoError(t, tokenStore.Create(context.Background(), token))
+}
+
+func TestAPITokenRepositoryTable_Find(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	tokenStore := NewAPITokenRepositoryTable(client)
+
+	token, plainText, err := sdump.NewAPIToken(userID, "ci")
+	require.NoError(t, err)
+
+	require.NoError(t, tokenStore.Create(context.Background(), token))
+
+	found, err := tokenStore.Find(context.Background(), &sdump.FindAPITokenOptions{
+		Hash: sdump.HashAPIToken(plainText),
+	})
+	require.NoError(t, err)
+	require.Equal(t, token.ID, found.ID)
+
+	_, err = tokenStore.Find(context.Background(), &sdump.FindAPITokenOptions{
+		ID:     token.ID,
+		UserID: uuid.New(),
+	})
+	require.ErrorIs(t, err, sdump.ErrAPITokenNotFound)
+
+	_, err = tokenStore.Find(context.Background(), &sdump.FindAPITokenOptions{})
+	require.ErrorIs(t, err, sdump.ErrAPITokenNotFound)
+}
+
+func TestAPITokenRepositoryTable_List(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	tokenStore := NewAPITokenRepositoryTable(client)
+
+	for _, name := range []string{"ci", "staging"} {
+		token, _, err := sdump.NewAPIToken(userID, name)
+		require.NoError(t, err)
+
+		require.NoError(t, tokenStore.Create(context.Background(), token))
+	}
+
+	tokens, err := tokenStore.List(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+}
+
+func TestAPITokenRepositoryTable_Revoke(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	tokenStore := NewAPITokenRepositoryTable(client)
+
+	token, plainText, err := sdump.NewAPIToken(userID, "ci")
+	require.NoError(t, err)
+
+	require.NoError(t, tokenStore.Create(context.Background(), token))
+
+	require.NoError(t, tokenStore.Revoke(context.Background(), token))
+
+	_, err = tokenStore.Find(context.Background(), &sdump.FindAPITokenOptions{
+		Hash: sdump.HashAPIToken(plainText),
+	})
+	require.ErrorIs(t, err, sdump.ErrAPITokenNotFound)
+}
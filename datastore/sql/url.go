@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/adelowo/sdump"
 	"github.com/google/uuid"
@@ -23,11 +24,133 @@ func NewURLRepositoryTable(db *bun.DB) sdump.URLRepository {
 func (u *urlRepositoryTable) Create(ctx context.Context,
 	model *sdump.URLEndpoint,
 ) error {
-	_, err := bun.NewInsertQuery(u.inner).Model(model).
+	exists, err := bun.NewSelectQuery(u.inner).
+		Model((*sdump.URLEndpoint)(nil)).
+		Where("reference = ?", model.Reference).
+		Exists(ctx)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return sdump.ErrReferenceTaken
+	}
+
+	if err := u.checkNameAvailable(ctx, model.UserID, uuid.Nil, model.Name); err != nil {
+		return err
+	}
+
+	newID(&model.ID)
+
+	_, err = bun.NewInsertQuery(u.inner).Model(model).
+		Exec(ctx)
+	return err
+}
+
+// checkNameAvailable returns sdump.ErrEndpointNameTaken if another of the
+// user's endpoints uses the name
+func (u *urlRepositoryTable) checkNameAvailable(ctx context.Context,
+	userID, endpointID uuid.UUID, name string,
+) error {
+	if name == "" {
+		return nil
+	}
+
+	query := bun.NewSelectQuery(u.inner).
+		Model((*sdump.URLEndpoint)(nil)).
+		Where("user_id = ?", userID).
+		Where("name = ?", name)
+
+	if endpointID != uuid.Nil {
+		query = query.Where("id != ?", endpointID)
+	}
+
+	exists, err := query.Exists(ctx)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return sdump.ErrEndpointNameTaken
+	}
+
+	return nil
+}
+
+func (u *urlRepositoryTable) Rename(ctx context.Context,
+	model *sdump.URLEndpoint, name string,
+) error {
+	if err := u.checkNameAvailable(ctx, model.UserID, model.ID, name); err != nil {
+		return err
+	}
+
+	model.Name = name
+	model.UpdatedAt = time.Now()
+
+	_, err := bun.NewUpdateQuery(u.inner).Model(model).
+		Column("name", "updated_at").
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (u *urlRepositoryTable) Deactivate(ctx context.Context,
+	model *sdump.URLEndpoint,
+) error {
+	model.IsActive = false
+	model.UpdatedAt = time.Now()
+
+	_, err := bun.NewUpdateQuery(u.inner).Model(model).
+		Column("is_active", "updated_at").
+		WherePK().
 		Exec(ctx)
 	return err
 }
 
+func (u *urlRepositoryTable) Update(ctx context.Context,
+	model *sdump.URLEndpoint,
+) error {
+	model.UpdatedAt = time.Now()
+
+	_, err := bun.NewUpdateQuery(u.inner).Model(model).
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (u *urlRepositoryTable) Delete(ctx context.Context,
+	model *sdump.URLEndpoint,
+) error {
+	_, err := bun.NewDeleteQuery(u.inner).Model(model).
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (u *urlRepositoryTable) List(ctx context.Context,
+	opts *sdump.ListURLOptions,
+) ([]sdump.URLEndpoint, error) {
+	var endpoints []sdump.URLEndpoint
+
+	query := bun.NewSelectQuery(u.inner).Model(&endpoints).
+		Order("created_at DESC")
+
+	if opts.UserID != uuid.Nil {
+		query = query.Where("user_id = ?", opts.UserID)
+	}
+
+	if opts.HasRetention {
+		query = query.Where("(metadata->'limits'->>'retention_hours') IS NOT NULL")
+	}
+
+	if opts.IsActive {
+		query = query.Where("is_active = ?", true)
+	}
+
+	err := query.Scan(ctx)
+	return endpoints, err
+}
+
 func (u *urlRepositoryTable) Get(ctx context.Context,
 	opts *sdump.FindURLOptions,
 ) (*sdump.URLEndpoint, error) {
@@ -59,6 +182,7 @@ func (u *urlRepositoryTable) Latest(ctx context.Context, userID uuid.UUID) (
 	err := bun.NewSelectQuery(u.inner).Model(ret).
 		Order("created_at DESC").
 		Where("user_id = ?", userID).
+		Where("is_active = ?", true).
 		Limit(1).
 		Scan(ctx)
 
@@ -54,3 +54,150 @@ func TestURLRepositoryTable_Latest(t *testing.T) {
 
 	require.Equal(t, endpoint.Reference, "cmltg1eg330l5l1vq11g")
 }
+
+func TestURLRepositoryTable_Update(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	endpoint.Metadata.Response = &sdump.ResponseDefinition{
+		StatusCode: 200,
+		Body:       "challenge",
+	}
+
+	require.NoError(t, urlStore.Update(context.Background(), endpoint))
+
+	endpoint, err = urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "challenge", endpoint.Metadata.Response.Body)
+}
+
+func TestURLRepositoryTable_List(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoints, err := urlStore.List(context.Background(), &sdump.ListURLOptions{
+		UserID: userID,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, endpoints, 2)
+	require.Equal(t, "cmltg1eg330l5l1vq11g", endpoints[0].Reference)
+
+	endpoints[1].Metadata.Limits = &sdump.LimitsDefinition{RetentionHours: 12}
+	require.NoError(t, urlStore.Update(context.Background(), &endpoints[1]))
+
+	endpoints, err = urlStore.List(context.Background(), &sdump.ListURLOptions{
+		HasRetention: true,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, endpoints, 1)
+	require.Equal(t, "cmltfm6g330l5l1vq110", endpoints[0].Reference)
+
+	require.NoError(t, urlStore.Deactivate(context.Background(), &endpoints[0]))
+
+	endpoints, err = urlStore.List(context.Background(), &sdump.ListURLOptions{
+		UserID:   userID,
+		IsActive: true,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, endpoints, 1)
+	require.Equal(t, "cmltg1eg330l5l1vq11g", endpoints[0].Reference)
+}
+
+func TestURLRepositoryTable_Delete(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110", // see fixtures/urls.yml
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, urlStore.Delete(context.Background(), endpoint))
+
+	_, err = urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: "cmltfm6g330l5l1vq110",
+	})
+	require.ErrorIs(t, err, sdump.ErrURLEndpointNotFound)
+}
+
+func TestURLRepositoryTable_Rename(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	urlStore := NewURLRepositoryTable(client)
+
+	endpoint, err := sdump.NewNamedURLEndpoint(userID, sdump.NewURLEndpointOptions{
+		Name:      "stripe-staging",
+		Reference: "stripe-staging",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, urlStore.Create(context.Background(), endpoint))
+
+	// references are unique across users and names per user
+	require.ErrorIs(t, urlStore.Create(context.Background(), &sdump.URLEndpoint{
+		Reference: "stripe-staging",
+		UserID:    userID,
+		IsActive:  true,
+	}), sdump.ErrReferenceTaken)
+
+	other := sdump.NewURLEndpoint(userID)
+	other.Name = "stripe-staging"
+	require.ErrorIs(t, urlStore.Create(context.Background(), other), sdump.ErrEndpointNameTaken)
+
+	other.Name = "github-ci"
+	require.NoError(t, urlStore.Create(context.Background(), other))
+
+	require.ErrorIs(t, urlStore.Rename(context.Background(), other, "stripe-staging"),
+		sdump.ErrEndpointNameTaken)
+
+	// renaming to its own name is not a conflict
+	require.NoError(t, urlStore.Rename(context.Background(), endpoint, "stripe-staging"))
+	require.NoError(t, urlStore.Rename(context.Background(), other, "github-actions"))
+
+	renamed, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: other.Reference,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "github-actions", renamed.Name)
+}
+
+func TestURLRepositoryTable_Deactivate(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	urlStore := NewURLRepositoryTable(client)
+
+	latest, err := urlStore.Latest(context.Background(), userID)
+	require.NoError(t, err)
+
+	require.NoError(t, urlStore.Deactivate(context.Background(), latest))
+
+	endpoint, err := urlStore.Get(context.Background(), &sdump.FindURLOptions{
+		Reference: latest.Reference,
+	})
+	require.NoError(t, err)
+	require.False(t, endpoint.IsActive)
+
+	// deactivated endpoints are not reused
+	next, err := urlStore.Latest(context.Background(), userID)
+	require.NoError(t, err)
+	require.NotEqual(t, latest.ID, next.ID)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
@@ -22,6 +24,8 @@ func NewUserRepositoryTable(db *bun.DB) sdump.UserRepository {
 func (u *userRepositoryTable) Create(ctx context.Context,
 	model *sdump.User,
 ) error {
+	newID(&model.ID)
+
 	_, err := bun.NewInsertQuery(u.inner).Model(model).
 		Exec(ctx)
 	return err
@@ -30,11 +34,24 @@ func (u *userRepositoryTable) Create(ctx context.Context,
 func (u *userRepositoryTable) Find(ctx context.Context,
 	opts *sdump.FindUserOptions,
 ) (*sdump.User, error) {
+	// an empty filter would match any user
+	if opts.ID == uuid.Nil && opts.SSHKeyFingerprint == "" {
+		return nil, sdump.ErrUserNotFound
+	}
+
 	res := new(sdump.User)
 
-	err := bun.NewSelectQuery(u.inner).Model(res).
-		Where("ssh_finger_print = ?", opts.SSHKeyFingerprint).
-		Scan(ctx)
+	query := bun.NewSelectQuery(u.inner).Model(res)
+
+	if opts.ID != uuid.Nil {
+		query = query.Where("id = ?", opts.ID)
+	}
+
+	if opts.SSHKeyFingerprint != "" {
+		query = query.Where("ssh_finger_print = ?", opts.SSHKeyFingerprint)
+	}
+
+	err := query.Scan(ctx)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, sdump.ErrUserNotFound
@@ -42,3 +59,30 @@ func (u *userRepositoryTable) Find(ctx context.Context,
 
 	return res, err
 }
+
+func (u *userRepositoryTable) Update(ctx context.Context,
+	model *sdump.User,
+) error {
+	model.UpdatedAt = time.Now()
+
+	_, err := bun.NewUpdateQuery(u.inner).Model(model).
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (u *userRepositoryTable) List(ctx context.Context,
+	opts *sdump.ListUserOptions,
+) ([]sdump.User, error) {
+	var users []sdump.User
+
+	query := bun.NewSelectQuery(u.inner).Model(&users).
+		Order("created_at DESC")
+
+	if opts.OnlyBanned {
+		query = query.Where("is_banned = ?", true)
+	}
+
+	err := query.Scan(ctx)
+	return users, err
+}
@@ -43,3 +43,27 @@ func TestUserRepository_Find(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestUserRepository_Update(t *testing.T) {
+	client, teardownFunc := setupPostgresDatabase(t)
+	defer teardownFunc()
+
+	userStore := NewUserRepositoryTable(client)
+
+	user := &sdump.User{
+		SSHFingerPrint: "oops",
+	}
+
+	require.NoError(t, userStore.Create(context.Background(), user))
+
+	user.Ban("spam")
+
+	require.NoError(t, userStore.Update(context.Background(), user))
+
+	banned, err := userStore.List(context.Background(), &sdump.ListUserOptions{
+		OnlyBanned: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, banned, 1)
+	require.Equal(t, "spam", banned[0].BanReason)
+}
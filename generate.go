@@ -3,3 +3,5 @@ package sdump
 //go:generate mockgen --source url.go -destination mocks/url.go -package mocks
 //go:generate mockgen --source ingest.go -destination mocks/ingest.go -package mocks
 //go:generate mockgen --source user.go -destination mocks/user.go -package mocks
+//go:generate mockgen --source token.go -destination mocks/token.go -package mocks
+//go:generate mockgen --source plan.go -destination mocks/plan.go -package mocks
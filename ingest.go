@@ -1,30 +1,115 @@
 package sdump
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"net"
 	"net/http"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
+const ErrIngestedRequestNotFound = appError("ingested request not found")
+
 type RequestDefinition struct {
-	Body      string      `mapstructure:"body" json:"body,omitempty"`
-	Query     string      `json:"query,omitempty"`
-	Headers   http.Header `json:"headers,omitempty"`
-	IPAddress net.IP      `json:"ip_address,omitempty" bson:"ip_address"`
-	Size      int64       `json:"size,omitempty"`
-	Method    string      `json:"method,omitempty"`
+	Body        string      `mapstructure:"body" json:"body,omitempty"`
+	Query       string      `json:"query,omitempty"`
+	Headers     http.Header `json:"headers,omitempty"`
+	IPAddress   net.IP      `json:"ip_address,omitempty" bson:"ip_address"`
+	Size        int64       `json:"size,omitempty"`
+	Method      string      `json:"method,omitempty"`
+	ContentType string      `json:"content_type,omitempty"`
+
+	// Path is the part of the url after the endpoint's reference.
+	// A request sent to /reference/github/push would have a path of /github/push
+	Path   string `json:"path,omitempty"`
+	RawURL string `json:"raw_url,omitempty"`
+	Proto  string `json:"proto,omitempty"`
+	Host   string `json:"host,omitempty"`
+
+	// IsBase64Encoded is set when the body is not valid text and had to be
+	// base64 encoded before it could be stored
+	IsBase64Encoded bool `json:"is_base64_encoded,omitempty"`
+}
+
+// encodeBody converts b to a string that can be stored as json. Bodies that
+// cannot be represented as text ( images, protobuf, gzipped content and the likes)
+// are base64 encoded
+func encodeBody(b []byte) (string, bool) {
+	// Postgres does not allow NUL characters in jsonb strings even though
+	// they are valid utf8
+	if utf8.Valid(b) && bytes.IndexByte(b, 0) == -1 {
+		return string(b), false
+	}
+
+	return base64.StdEncoding.EncodeToString(b), true
+}
+
+func decodeBody(s string, isBase64Encoded bool) ([]byte, error) {
+	if !isBase64Encoded {
+		return []byte(s), nil
+	}
+
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// SetBody stores b as the body of the request.
+func (r *RequestDefinition) SetBody(b []byte) {
+	r.Body, r.IsBase64Encoded = encodeBody(b)
+}
+
+// RawBody returns the body exactly as it was sent by the client
+func (r RequestDefinition) RawBody() ([]byte, error) {
+	return decodeBody(r.Body, r.IsBase64Encoded)
+}
+
+// ForwardResult describes how the endpoint's forward target responded to an
+// ingested request that was relayed to it
+type ForwardResult struct {
+	URL         string      `json:"url,omitempty"`
+	StatusCode  int         `json:"status_code,omitempty"`
+	LatencyInMS int64       `json:"latency_ms,omitempty"`
+	Headers     http.Header `json:"headers,omitempty"`
+	Body        string      `json:"body,omitempty"`
+
+	IsBase64Encoded bool `json:"is_base64_encoded,omitempty"`
+
+	// IsTruncated is set when the response body was too large to be stored
+	// completely
+	IsTruncated bool `json:"is_truncated,omitempty"`
+
+	// Error is set when the request could not be relayed at all
+	Error string `json:"error,omitempty"`
+}
+
+func (f *ForwardResult) SetBody(b []byte) {
+	f.Body, f.IsBase64Encoded = encodeBody(b)
+}
+
+func (f ForwardResult) RawBody() ([]byte, error) {
+	return decodeBody(f.Body, f.IsBase64Encoded)
 }
 
 type IngestHTTPRequest struct {
-	ID      uuid.UUID         `bun:"type:uuid,default:uuid_generate_v4()" json:"id,omitempty" mapstructure:"id"`
+	ID      uuid.UUID         `bun:"type:uuid,pk,default:uuid_generate_v4()" json:"id,omitempty" mapstructure:"id"`
 	UrlID   uuid.UUID         `json:"url_id,omitempty"`
 	Request RequestDefinition `json:"request,omitempty"`
 
-	// No need to store content type, it will always be application/json
+	// Forward is only available if the endpoint has a forward target
+	Forward *ForwardResult `json:"forward,omitempty"`
+
+	// Tunnel is only available if the endpoint's owner had a reverse SSH
+	// tunnel open when the request was ingested
+	Tunnel *ForwardResult `json:"tunnel,omitempty"`
+
+	// BodyJSON is the body of the request if it is valid json. It is only
+	// used to search through json bodies
+	BodyJSON json.RawMessage `bun:"body_json,type:jsonb,nullzero" json:"-"`
 
 	CreatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"created_at,omitempty" bson:"created_at" mapstructure:"created_at"`
 	UpdatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"updated_at,omitempty" bson:"updated_at" mapstructure:"updated_at"`
@@ -33,12 +118,118 @@ type IngestHTTPRequest struct {
 	bun.BaseModel `bun:"table:ingests"`
 }
 
+// DeleteIngestedRequestOptions selects the requests to delete. At least
+// one of Before, ID or URLID must be provided
 type DeleteIngestedRequestOptions struct {
 	Before         time.Time
+	ID             uuid.UUID
+	URLID          uuid.UUID
 	UseSoftDeletes bool
+
+	// Plan limits the deletion to requests sent to endpoints owned by
+	// users on the plan
+	Plan *Plan
+
+	// Limit caps how many requests are deleted so large tables can be
+	// pruned in batches. Everything matching is deleted if it is zero
+	Limit int
+}
+
+// PurgeIngestedRequestOptions selects the requests that are permanently
+// deleted because nothing can show them anymore: requests soft deleted
+// before Before and requests sent to endpoints deleted before Before
+type PurgeIngestedRequestOptions struct {
+	Before time.Time
+
+	// Limit caps how many requests are deleted. Everything matching is
+	// deleted if it is zero
+	Limit int
+}
+
+// CountIngestedRequestOptions counts the requests sent to all of a user's
+// endpoints, including soft deleted requests and deleted endpoints. Requests
+// that were removed for good are not counted
+type CountIngestedRequestOptions struct {
+	UserID uuid.UUID
+	Since  time.Time
+}
+
+type FindIngestedRequestOptions struct {
+	ID    uuid.UUID
+	URLID uuid.UUID
+}
+
+// ListIngestedRequestOptions pages through an endpoint's requests, newest
+// first
+type ListIngestedRequestOptions struct {
+	URLID uuid.UUID
+
+	// Cursor is the ID of the last request on the previous page.
+	// If not provided, the first page is returned
+	Cursor uuid.UUID
+
+	Limit int
+
+	// Method, Path, Since and Until are optional filters. Path
+	// matches any request whose path starts with it
+	Method string
+	Path   string
+	Since  time.Time
+	Until  time.Time
+}
+
+// TextSearchLimit is how many characters of a body text searches look at.
+// Longer bodies are cut so indexing them stays cheap. The postgres index on
+// ingests has to be recreated if this changes
+const TextSearchLimit = 65536
+
+// SearchIngestedRequestOptions pages through an endpoint's requests that
+// match every provided filter, newest first
+type SearchIngestedRequestOptions struct {
+	URLID uuid.UUID
+
+	// Cursor is the ID of the last request on the previous page.
+	// If not provided, the first page is returned
+	Cursor uuid.UUID
+
+	Limit int
+
+	Method string
+
+	// Path matches any request whose path starts with it
+	Path string
+
+	// HeaderName matches requests that sent the header. If HeaderValue is
+	// provided, one of the header's values must be exactly HeaderValue
+	HeaderName  string
+	HeaderValue string
+
+	// IPNetwork matches requests sent from an address within the range
+	IPNetwork *net.IPNet
+
+	// JSONPath matches requests with a json body that has JSONValue at the
+	// dot separated path such as data.object.id. Numbers, booleans and
+	// nested objects are compared with their json representation
+	JSONPath  string
+	JSONValue string
+
+	// Text matches requests whose body contains every word of it, ignoring
+	// case. Only the first TextSearchLimit characters of the body are
+	// searched
+	Text string
+
+	Since time.Time
+	Until time.Time
 }
 
 type IngestRepository interface {
 	Create(context.Context, *IngestHTTPRequest) error
-	Delete(context.Context, *DeleteIngestedRequestOptions) error
+	List(context.Context, *ListIngestedRequestOptions) ([]IngestHTTPRequest, error)
+	Search(context.Context, *SearchIngestedRequestOptions) ([]IngestHTTPRequest, error)
+	Get(context.Context, *FindIngestedRequestOptions) (*IngestHTTPRequest, error)
+	Update(context.Context, *IngestHTTPRequest) error
+	// Delete returns how many requests were deleted
+	Delete(context.Context, *DeleteIngestedRequestOptions) (int64, error)
+	Purge(context.Context, *PurgeIngestedRequestOptions) (int64, error)
+	Count(context.Context, *CountIngestedRequestOptions) (int64, error)
 }
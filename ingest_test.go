@@ -0,0 +1,50 @@
+package sdump
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestDefinition_SetBody(t *testing.T) {
+	tt := []struct {
+		name            string
+		body            []byte
+		isBase64Encoded bool
+	}{
+		{
+			name:            "json body is stored as is",
+			body:            []byte(`{"name" : "Lanre"}`),
+			isBase64Encoded: false,
+		},
+		{
+			name:            "form encoded body is stored as is",
+			body:            []byte(`name=Lanre&occupation=Software`),
+			isBase64Encoded: false,
+		},
+		{
+			name:            "invalid utf8 is base64 encoded",
+			body:            []byte{0xff, 0xfe, 0xfd},
+			isBase64Encoded: true,
+		},
+		{
+			name:            "nul characters are base64 encoded",
+			body:            []byte("name\x00Lanre"),
+			isBase64Encoded: true,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			var req RequestDefinition
+
+			req.SetBody(v.body)
+
+			require.Equal(t, v.isBase64Encoded, req.IsBase64Encoded)
+
+			b, err := req.RawBody()
+			require.NoError(t, err)
+			require.Equal(t, v.body, b)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package pubsub contains the in-memory pub/sub backend. It only delivers
+// events within a single process so it cannot be used when running more
+// than one HTTP server
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/adelowo/sdump"
+)
+
+// subscriberBufferSize is how many events a subscriber can fall behind by
+// before new ones are dropped for it
+const subscriberBufferSize = 64
+
+type memory struct {
+	mu          sync.RWMutex
+	subscribers map[int]chan *sdump.Event
+	next        int
+}
+
+func NewMemory() sdump.PubSub {
+	return &memory{
+		subscribers: make(map[int]chan *sdump.Event),
+	}
+}
+
+// Publish never waits for subscribers, a subscriber that is too slow to
+// keep up misses the events that do not fit in its buffer
+func (m *memory) Publish(_ context.Context, event *sdump.Event) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, events := range m.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Subscribe calls fn from the calling goroutine for every event until ctx
+// is cancelled
+func (m *memory) Subscribe(ctx context.Context, fn func(*sdump.Event)) error {
+	events := make(chan *sdump.Event, subscriberBufferSize)
+
+	m.mu.Lock()
+	id := m.next
+	m.next++
+	m.subscribers[id] = events
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.subscribers, id)
+		m.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			fn(event)
+		}
+	}
+}
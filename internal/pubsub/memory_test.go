@@ -0,0 +1,104 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	ps := NewMemory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	received := make(chan *sdump.Event, subscriberBufferSize)
+
+	done := make(chan error)
+	go func() {
+		done <- ps.Subscribe(ctx, func(e *sdump.Event) {
+			received <- e
+		})
+	}()
+
+	event := &sdump.Event{Channel: "messages.cmltfm6g330l5l1vq110", ID: "1", Data: []byte("{}")}
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, ps.Publish(context.Background(), event))
+
+		select {
+		case e := <-received:
+			return e == event
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// events published while waiting for the subscription are delivered
+	// after the first one
+	for len(received) > 0 {
+		<-received
+	}
+
+	// cancelled subscribers do not receive events anymore
+	require.NoError(t, ps.Publish(context.Background(), event))
+	require.Len(t, received, 0)
+}
+
+func TestMemory_SlowSubscriber(t *testing.T) {
+	ps := NewMemory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscribed := make(chan struct{}, 1)
+	block := make(chan struct{})
+
+	go func() {
+		_ = ps.Subscribe(ctx, func(_ *sdump.Event) {
+			select {
+			case subscribed <- struct{}{}:
+			default:
+			}
+
+			<-block
+		})
+	}()
+
+	event := &sdump.Event{Channel: "messages.cmltfm6g330l5l1vq110", ID: "1", Data: []byte("{}")}
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, ps.Publish(context.Background(), event))
+
+		select {
+		case <-subscribed:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	// events for a subscriber that does not keep up are dropped instead
+	// of blocking the publisher
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBufferSize*2; i++ {
+			_ = ps.Publish(context.Background(), event)
+		}
+
+		close(published)
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publishing blocked on a slow subscriber")
+	}
+
+	close(block)
+}
@@ -0,0 +1,225 @@
+// Package relay sends stored requests to other servers. It is used to forward
+// ingested requests to an endpoint's forward target and to replay them
+package relay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/adelowo/sdump"
+)
+
+// MaxResponseBodySize is how much of the target's response body is kept
+const MaxResponseBodySize = 64 * 1024
+
+var ErrPrivateNetwork = errors.New("relaying requests to private networks is not allowed")
+
+// hopByHopHeaders are meaningful only for a single connection and must not
+// be sent to the target
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Content-Length",
+}
+
+// NewClient creates an http client suitable for relaying requests.
+// If allowPrivateNetworks is false, the client refuses to connect to loopback,
+// private or link local addresses so users cannot use the server to reach
+// internal services
+func NewClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+
+	if !allowPrivateNetworks {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || isPrivateIP(ip) {
+				return ErrPrivateNetwork
+			}
+
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// blockedNetworks are not covered by the net.IP helpers. 100.64.0.0/10 is
+// the carrier grade NAT range cloud providers use internally and
+// 0.0.0.0/8 reaches the local host on most systems. IPv4 networks also
+// match their IPv4-mapped IPv6 addresses
+var blockedNetworks = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("0.0.0.0/8"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() {
+		return true
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BuildURL joins the target with the path and query the request was sent
+// with. A request sent to /reference/github/push?event=push relayed to
+// http://localhost:3000/hooks will be sent to
+// http://localhost:3000/hooks/github/push?event=push
+func BuildURL(target string, req sdump.RequestDefinition) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+
+	if req.Path != "" && req.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + req.Path
+	}
+
+	if req.Query != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&" + req.Query
+		} else {
+			u.RawQuery = req.Query
+		}
+	}
+
+	return u.String(), nil
+}
+
+// NewRequest builds an http request that mirrors req
+func NewRequest(ctx context.Context, target string,
+	req sdump.RequestDefinition,
+) (*http.Request, error) {
+	endpoint, err := BuildURL(target, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := req.RawBody()
+	if err != nil {
+		return nil, err
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header = req.Headers.Clone()
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+
+	for _, header := range hopByHopHeaders {
+		r.Header.Del(header)
+	}
+
+	if req.IPAddress != nil && !req.IPAddress.IsUnspecified() {
+		r.Header.Set("X-Forwarded-For", req.IPAddress.String())
+	}
+
+	return r, nil
+}
+
+// Do sends req to target. Failures are reported in the result rather than
+// as an error so they can be stored and displayed
+func Do(ctx context.Context, client *http.Client, target string,
+	req sdump.RequestDefinition,
+) *sdump.ForwardResult {
+	result := &sdump.ForwardResult{
+		URL: target,
+	}
+
+	r, err := NewRequest(ctx, target, req)
+	if err != nil {
+		result.Error = fmt.Sprintf("could not build request... %v", err)
+		return result
+	}
+
+	result.URL = r.URL.String()
+
+	start := time.Now()
+
+	resp, err := client.Do(r)
+	if err != nil {
+		result.LatencyInMS = time.Since(start).Milliseconds()
+		result.Error = err.Error()
+		return result
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBodySize+1))
+
+	result.LatencyInMS = time.Since(start).Milliseconds()
+	result.StatusCode = resp.StatusCode
+	result.Headers = resp.Header
+
+	if err != nil {
+		result.Error = fmt.Sprintf("could not read response body... %v", err)
+	}
+
+	if len(body) > MaxResponseBodySize {
+		body = body[:MaxResponseBodySize]
+		result.IsTruncated = true
+	}
+
+	result.SetBody(body)
+
+	return result
+}
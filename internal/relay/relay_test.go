@@ -0,0 +1,140 @@
+package relay
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildURL(t *testing.T) {
+	tt := []struct {
+		target   string
+		req      sdump.RequestDefinition
+		expected string
+	}{
+		{"http://localhost:3000", sdump.RequestDefinition{}, "http://localhost:3000"},
+		{"http://localhost:3000", sdump.RequestDefinition{Path: "/"}, "http://localhost:3000"},
+		{
+			"http://localhost:3000/hooks/",
+			sdump.RequestDefinition{Path: "/github/push", Query: "event=push"},
+			"http://localhost:3000/hooks/github/push?event=push",
+		},
+		{
+			"http://localhost:3000/hooks?token=secret",
+			sdump.RequestDefinition{Query: "event=push"},
+			"http://localhost:3000/hooks?token=secret&event=push",
+		},
+	}
+
+	for _, v := range tt {
+		u, err := BuildURL(v.target, v.req)
+		require.NoError(t, err)
+		require.Equal(t, v.expected, u)
+	}
+}
+
+func TestDo(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = r
+		receivedBody = string(b)
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "created")
+	}))
+	defer server.Close()
+
+	result := Do(context.Background(), NewClient(time.Second, true), server.URL, sdump.RequestDefinition{
+		Method: http.MethodPut,
+		Path:   "/github/push",
+		Query:  "event=push",
+		Body:   `{"name" : "Lanre"}`,
+		Headers: http.Header{
+			"Content-Type": []string{"application/json"},
+			"X-Signature":  []string{"signature"},
+			"Connection":   []string{"keep-alive"},
+		},
+		IPAddress: net.ParseIP("10.0.0.1"),
+	})
+
+	require.Empty(t, result.Error)
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	require.Equal(t, "created", result.Body)
+	require.Equal(t, server.URL+"/github/push?event=push", result.URL)
+
+	require.Equal(t, http.MethodPut, received.Method)
+	require.Equal(t, "/github/push", received.URL.Path)
+	require.Equal(t, "push", received.URL.Query().Get("event"))
+	require.Equal(t, "signature", received.Header.Get("X-Signature"))
+	require.Equal(t, "10.0.0.1", received.Header.Get("X-Forwarded-For"))
+	require.Equal(t, `{"name" : "Lanre"}`, receivedBody)
+}
+
+func TestDo_LargeResponseIsTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, strings.Repeat("a", MaxResponseBodySize+10))
+	}))
+	defer server.Close()
+
+	result := Do(context.Background(), NewClient(time.Second, true), server.URL, sdump.RequestDefinition{})
+
+	require.True(t, result.IsTruncated)
+	require.Len(t, result.Body, MaxResponseBodySize)
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	tt := []struct {
+		ip       string
+		expected bool
+	}{
+		{"127.0.0.1", true},
+		{"10.0.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"100.127.255.255", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:100.64.0.1", true},
+		{"::ffff:0.1.2.3", true},
+		{"100.128.0.1", false},
+		{"1.1.1.1", false},
+		{"::ffff:1.1.1.1", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, v := range tt {
+		t.Run(v.ip, func(t *testing.T) {
+			ip := net.ParseIP(v.ip)
+			require.NotNil(t, ip)
+			require.Equal(t, v.expected, isPrivateIP(ip))
+		})
+	}
+}
+
+func TestDo_PrivateNetworksAreBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	result := Do(context.Background(), NewClient(time.Second, false), server.URL, sdump.RequestDefinition{})
+
+	require.Contains(t, result.Error, ErrPrivateNetwork.Error())
+	require.Zero(t, result.StatusCode)
+}
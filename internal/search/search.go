@@ -0,0 +1,435 @@
+// Package search parses the query language used to filter ingested requests.
+//
+// A query is a list of space separated terms. A request matches when it
+// matches every term. Terms are written as key:value and can be negated with
+// a leading dash. Values with spaces can be double quoted.
+//
+//	method:POST,PUT          the request method is one of the values
+//	path:/github             the path starts with the value
+//	header:X-GitHub-Event    the header was sent
+//	header:X-GitHub-Event=push
+//	                         the header was sent with the value
+//	ip:10.0.0.0/8            the client ip is the value or within the range
+//	body:"customer.created"  the body contains the value
+//	text:"payment failed"    the start of the body contains every word of
+//	                         the value
+//	json:data.object.id=evt_1
+//	                         the json body has the value at the path
+//	size:>1kb                the body size compared with >, >=, <, <= or
+//	size:100..2kb            within the range
+//	since:15m until:2024-03-20T10:00:00Z
+//	                         received in the time range. Durations are
+//	                         relative to now
+//
+// A term without a key matches requests whose body or path contains it.
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/internal/tmpl"
+	"github.com/dustin/go-humanize"
+)
+
+// Query is a parsed search query
+type Query struct {
+	raw   string
+	terms []term
+
+	// Method, Path, Header, IP, JSON, Text, Since and Until are the parts
+	// of the query the ingested requests api can filter on. They are only
+	// set when the query has a single such term that is not negated
+	Method string
+	Path   string
+	Header string
+	IP     string
+	JSON   string
+	Text   string
+	Since  time.Time
+	Until  time.Time
+}
+
+type term struct {
+	negate bool
+	match  func(sdump.RequestDefinition, time.Time) bool
+}
+
+// Parse parses q. now is used to resolve relative times such as since:15m
+func Parse(q string, now time.Time) (*Query, error) {
+	tokens, err := tokenize(q)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Query{raw: strings.TrimSpace(q)}
+
+	counts := make(map[string]int)
+
+	for _, token := range tokens {
+		negate := strings.HasPrefix(token, "-") && len(token) > 1
+		if negate {
+			token = token[1:]
+		}
+
+		key, value, hasKey := strings.Cut(token, ":")
+		if !hasKey {
+			key, value = "", token
+		}
+
+		key = strings.ToLower(key)
+
+		if hasKey && value == "" {
+			return nil, fmt.Errorf("%s: please provide a value", key)
+		}
+
+		match, err := parseTerm(query, key, value, negate, now)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[key]++
+		query.terms = append(query.terms, term{negate: negate, match: match})
+	}
+
+	// the api accepts a single value per filter
+	if counts["method"] > 1 || strings.Contains(query.Method, ",") {
+		query.Method = ""
+	}
+
+	for key, value := range map[string]*string{
+		"path":   &query.Path,
+		"header": &query.Header,
+		"ip":     &query.IP,
+		"json":   &query.JSON,
+		"text":   &query.Text,
+	} {
+		if counts[key] > 1 {
+			*value = ""
+		}
+	}
+
+	return query, nil
+}
+
+func parseTerm(query *Query, key, value string, negate bool,
+	now time.Time,
+) (func(sdump.RequestDefinition, time.Time) bool, error) {
+	switch key {
+	case "":
+		value = strings.ToLower(value)
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			return strings.Contains(strings.ToLower(req.Path), value) ||
+				strings.Contains(strings.ToLower(body(req)), value)
+		}, nil
+
+	case "method":
+		methods := strings.Split(strings.ToUpper(value), ",")
+		if !negate {
+			query.Method = strings.ToUpper(value)
+		}
+
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			for _, method := range methods {
+				if strings.EqualFold(req.Method, method) {
+					return true
+				}
+			}
+
+			return false
+		}, nil
+
+	case "path":
+		if !negate {
+			query.Path = value
+		}
+
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			return strings.HasPrefix(req.Path, value)
+		}, nil
+
+	case "header":
+		if !negate {
+			query.Header = value
+		}
+
+		name, expected, hasValue := strings.Cut(value, "=")
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			values := req.Headers.Values(name)
+			if !hasValue {
+				return len(values) > 0
+			}
+
+			for _, v := range values {
+				if v == expected {
+					return true
+				}
+			}
+
+			return false
+		}, nil
+
+	case "ip":
+		if !negate {
+			query.IP = value
+		}
+
+		return parseIP(value)
+
+	case "body":
+		value = strings.ToLower(value)
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			return strings.Contains(strings.ToLower(body(req)), value)
+		}, nil
+
+	case "text":
+		if !negate {
+			query.Text = value
+		}
+
+		// matches the way the api searches so loaded requests and
+		// history agree
+		words := strings.Fields(strings.ToLower(value))
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			b := strings.ToLower(truncate(body(req), sdump.TextSearchLimit))
+			for _, word := range words {
+				if !strings.Contains(b, word) {
+					return false
+				}
+			}
+
+			return true
+		}, nil
+
+	case "json":
+		path, expected, hasValue := strings.Cut(value, "=")
+		// the api cannot check that a path is present
+		if !negate && hasValue {
+			query.JSON = value
+		}
+
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(body(req)), &parsed); err != nil {
+				return false
+			}
+
+			v, err := tmpl.Lookup(parsed, path)
+			if err != nil {
+				return false
+			}
+
+			if !hasValue {
+				return v != ""
+			}
+
+			return v == expected
+		}, nil
+
+	case "size":
+		return parseSize(value)
+
+	case "since", "until":
+		t, err := parseTime(value, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		if key == "since" {
+			if !negate {
+				query.Since = t
+			}
+
+			return func(_ sdump.RequestDefinition, createdAt time.Time) bool {
+				return !createdAt.Before(t)
+			}, nil
+		}
+
+		if !negate {
+			query.Until = t
+		}
+
+		return func(_ sdump.RequestDefinition, createdAt time.Time) bool {
+			return !createdAt.After(t)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("%s: unknown filter. Use method, path, header, ip, body, text, json, size, since or until", key)
+	}
+}
+
+func body(req sdump.RequestDefinition) string {
+	b, err := req.RawBody()
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+// truncate keeps the first n characters of s
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+
+		n--
+	}
+
+	return s
+}
+
+func parseIP(value string) (func(sdump.RequestDefinition, time.Time) bool, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.New("ip: please provide a valid ip address or range")
+		}
+
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			return network.Contains(req.IPAddress)
+		}, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, errors.New("ip: please provide a valid ip address or range")
+	}
+
+	return func(req sdump.RequestDefinition, _ time.Time) bool {
+		return ip.Equal(req.IPAddress)
+	}, nil
+}
+
+func parseSize(value string) (func(sdump.RequestDefinition, time.Time) bool, error) {
+	if lower, upper, isRange := strings.Cut(value, ".."); isRange {
+		from, err := humanize.ParseBytes(lower)
+		if err != nil {
+			return nil, errors.New("size: please provide a valid range such as 100..2kb")
+		}
+
+		to, err := humanize.ParseBytes(upper)
+		if err != nil {
+			return nil, errors.New("size: please provide a valid range such as 100..2kb")
+		}
+
+		return func(req sdump.RequestDefinition, _ time.Time) bool {
+			return req.Size >= int64(from) && req.Size <= int64(to)
+		}, nil
+	}
+
+	operator := "="
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, op) {
+			operator = op
+			value = strings.TrimPrefix(value, op)
+			break
+		}
+	}
+
+	n, err := humanize.ParseBytes(value)
+	if err != nil {
+		return nil, errors.New("size: please provide a size such as >1kb, <=500 or 100..2kb")
+	}
+
+	size := int64(n)
+
+	return func(req sdump.RequestDefinition, _ time.Time) bool {
+		switch operator {
+		case ">":
+			return req.Size > size
+		case ">=":
+			return req.Size >= size
+		case "<":
+			return req.Size < size
+		case "<=":
+			return req.Size <= size
+		default:
+			return req.Size == size
+		}
+	}, nil
+}
+
+// parseTime accepts RFC3339 timestamps or durations such as 15m or 2h that
+// are relative to now
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("please provide a duration such as 15m or an RFC3339 timestamp")
+	}
+
+	return t, nil
+}
+
+// tokenize splits q on spaces that are not within double quotes
+func tokenize(q string) ([]string, error) {
+	var (
+		tokens  []string
+		current strings.Builder
+		quoted  bool
+	)
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+
+		case r == ' ' && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if quoted {
+		return nil, errors.New("please close the double quote")
+	}
+
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+// String returns the query as it was written
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+
+	return q.raw
+}
+
+// IsEmpty is true when the query matches every request
+func (q *Query) IsEmpty() bool { return q == nil || len(q.terms) == 0 }
+
+// Match checks if a request received at createdAt matches every term of the
+// query
+func (q *Query) Match(req sdump.RequestDefinition, createdAt time.Time) bool {
+	if q.IsEmpty() {
+		return true
+	}
+
+	for _, t := range q.terms {
+		if t.match(req, createdAt) == t.negate {
+			return false
+		}
+	}
+
+	return true
+}
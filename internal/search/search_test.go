@@ -0,0 +1,195 @@
+package search
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name     string
+		query    string
+		hasError bool
+		expected Query
+	}{
+		{
+			name:  "empty query",
+			query: "  ",
+		},
+		{
+			name:  "api filters are extracted",
+			query: `method:post path:/github since:15m until:2024-03-20T09:55:00Z body:"push event"`,
+			expected: Query{
+				Method: "POST",
+				Path:   "/github",
+				Since:  now.Add(-15 * time.Minute),
+				Until:  time.Date(2024, 3, 20, 9, 55, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "search filters are extracted",
+			query: `header:X-GitHub-Event=push ip:10.0.0.0/8 json:data.id=evt_1 text:"payment failed"`,
+			expected: Query{
+				Header: "X-GitHub-Event=push",
+				IP:     "10.0.0.0/8",
+				JSON:   "data.id=evt_1",
+				Text:   "payment failed",
+			},
+		},
+		{
+			name:  "json presence cannot be sent to the api",
+			query: "json:data.id",
+		},
+		{
+			name:  "repeated filters cannot be sent to the api",
+			query: "header:X-GitHub-Event header:Stripe-Signature",
+		},
+		{
+			name:  "multiple methods cannot be sent to the api",
+			query: "method:GET,POST",
+		},
+		{
+			name:  "negated terms are not sent to the api",
+			query: "-method:GET -path:/health",
+		},
+		{
+			name:     "unknown filter",
+			query:    "status:200",
+			hasError: true,
+		},
+		{
+			name:     "missing value",
+			query:    "method:",
+			hasError: true,
+		},
+		{
+			name:     "unclosed quote",
+			query:    `body:"push`,
+			hasError: true,
+		},
+		{
+			name:     "invalid ip",
+			query:    "ip:10.0.0",
+			hasError: true,
+		},
+		{
+			name:     "invalid size",
+			query:    "size:>lots",
+			hasError: true,
+		},
+		{
+			name:     "invalid time",
+			query:    "since:yesterday",
+			hasError: true,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			q, err := Parse(v.query, now)
+			if v.hasError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, v.expected.Method, q.Method)
+			require.Equal(t, v.expected.Path, q.Path)
+			require.Equal(t, v.expected.Header, q.Header)
+			require.Equal(t, v.expected.IP, q.IP)
+			require.Equal(t, v.expected.JSON, q.JSON)
+			require.Equal(t, v.expected.Text, q.Text)
+			require.Equal(t, v.expected.Since, q.Since)
+			require.Equal(t, v.expected.Until, q.Until)
+		})
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	now := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	req := sdump.RequestDefinition{
+		Method: http.MethodPost,
+		Path:   "/github/push",
+		Headers: http.Header{
+			"X-Github-Event": []string{"push"},
+		},
+		IPAddress: net.ParseIP("10.0.1.20"),
+		Body:      `{"action" : "opened", "repository" : {"id" : 42, "name" : "sdump"}}`,
+		Size:      2048,
+	}
+
+	receivedAt := now.Add(-10 * time.Minute)
+
+	tt := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{name: "empty query matches everything", query: "", expected: true},
+		{name: "method", query: "method:post", expected: true},
+		{name: "one of many methods", query: "method:GET,POST", expected: true},
+		{name: "other method", query: "method:GET", expected: false},
+		{name: "negated method", query: "-method:GET", expected: true},
+		{name: "path prefix", query: "path:/github", expected: true},
+		{name: "header is present", query: "header:X-GitHub-Event", expected: true},
+		{name: "header value", query: "header:x-github-event=push", expected: true},
+		{name: "header values are case sensitive", query: "header:X-GitHub-Event=PUSH", expected: false},
+		{name: "other header value", query: "header:X-GitHub-Event=issues", expected: false},
+		{name: "missing header", query: "header:Stripe-Signature", expected: false},
+		{name: "ip", query: "ip:10.0.1.20", expected: true},
+		{name: "ip range", query: "ip:10.0.0.0/16", expected: true},
+		{name: "ip outside range", query: "ip:192.168.0.0/16", expected: false},
+		{name: "body substring", query: `body:"OPENED"`, expected: true},
+		{name: "text", query: `text:"SDUMP opened"`, expected: true},
+		{name: "text with a missing word", query: `text:"sdump closed"`, expected: false},
+		{name: "text matches part of a word", query: `text:"sdu open"`, expected: true},
+		{name: "json path value", query: "json:repository.name=sdump", expected: true},
+		{name: "json path number", query: "json:repository.id=42", expected: true},
+		{name: "json path is present", query: "json:action", expected: true},
+		{name: "other json path value", query: "json:repository.name=other", expected: false},
+		{name: "size larger than", query: "size:>1kb", expected: true},
+		{name: "size smaller than", query: "size:<=1kb", expected: false},
+		{name: "size range", query: "size:1kb..3kb", expected: true},
+		{name: "exact size", query: "size:2048", expected: true},
+		{name: "received since", query: "since:15m", expected: true},
+		{name: "received before", query: "since:5m", expected: false},
+		{name: "received until", query: "until:2024-03-20T09:55:00Z", expected: true},
+		{name: "free text matches body or path", query: "sdump github", expected: true},
+		{name: "every term must match", query: "method:POST header:Stripe-Signature", expected: false},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			q, err := Parse(v.query, now)
+			require.NoError(t, err)
+
+			require.Equal(t, v.expected, q.Match(req, receivedAt))
+		})
+	}
+}
+
+func TestQuery_Match_TextLimit(t *testing.T) {
+	now := time.Now()
+
+	req := sdump.RequestDefinition{
+		Body: strings.Repeat("a", sdump.TextSearchLimit-len("sdump")) + "sdump opened",
+	}
+
+	q, err := Parse("text:sdump", now)
+	require.NoError(t, err)
+	require.True(t, q.Match(req, now))
+
+	// only the start of the body is searched, like the api does
+	q, err = Parse("text:opened", now)
+	require.NoError(t, err)
+	require.False(t, q.Match(req, now))
+}
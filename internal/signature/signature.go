@@ -0,0 +1,158 @@
+// Package signature signs requests the SSH server makes to the HTTP server
+// with the shared admin secret so the secret itself never goes over the wire.
+// It also issues short lived tokens signed with the same secret
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// TimestampHeader is the unix time the request was signed at
+	TimestampHeader = "X-Sdump-Timestamp"
+
+	// SignatureHeader is the hex encoded HMAC-SHA256 of the request
+	SignatureHeader = "X-Sdump-Signature"
+
+	// MaxClockSkew is how old or how far in the future a signed request can
+	// be before it is rejected. It limits how long a captured request can be
+	// replayed
+	MaxClockSkew = 5 * time.Minute
+
+	// MaxBodySize is the largest body that can be signed. It stops
+	// unauthenticated callers from making the server buffer huge bodies
+	MaxBodySize = 1 << 20
+)
+
+// signedHeaders are covered by the signature so a captured request cannot be
+// replayed on behalf of another user
+var signedHeaders = []string{"X-SSH-Fingerprint"}
+
+var (
+	ErrMissingSignature = errors.New("request is not signed")
+	ErrInvalidSignature = errors.New("request signature is invalid")
+	ErrExpiredSignature = errors.New("request signature has expired")
+	ErrEmptySecret      = errors.New("secret must be provided")
+	ErrBodyTooLarge     = errors.New("request body is too large to be signed")
+)
+
+// Sign adds the timestamp and signature headers to the request. The body is
+// read and restored so the request can still be sent
+func Sign(r *http.Request, secret string, now time.Time) error {
+	if secret == "" {
+		return ErrEmptySecret
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(SignatureHeader, compute(secret, r, timestamp, body))
+	return nil
+}
+
+// Verify checks the request was signed with the secret within MaxClockSkew
+// of now
+func Verify(r *http.Request, secret string, now time.Time) error {
+	if secret == "" {
+		return ErrEmptySecret
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	sig := r.Header.Get(SignatureHeader)
+
+	if timestamp == "" || sig == "" {
+		return ErrMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return ErrExpiredSignature
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	expected := compute(secret, r, timestamp, body)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Transport signs every request before sending it with Base
+type Transport struct {
+	Secret string
+	Base   http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// a RoundTripper must not modify the request it was given
+	r = r.Clone(r.Context())
+
+	if err := Sign(r, t.Secret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return base.RoundTrip(r)
+}
+
+func compute(secret string, r *http.Request, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = io.WriteString(mac, r.Method+"\n"+r.URL.RequestURI()+"\n"+timestamp+"\n")
+
+	for _, header := range signedHeaders {
+		_, _ = io.WriteString(mac, r.Header.Get(header)+"\n")
+	}
+
+	_, _ = io.WriteString(mac, hex.EncodeToString(bodyHash[:]))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	_ = r.Body.Close()
+
+	if len(body) > MaxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
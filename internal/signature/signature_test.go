@@ -0,0 +1,175 @@
+package signature
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "sdump-admin-secret"
+
+func signedRequest(t *testing.T, body string, now time.Time) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/urls?limit=10", strings.NewReader(body))
+	req.Header.Set("X-SSH-Fingerprint", "sufojfpffhhofjfpjfo")
+	require.NoError(t, Sign(req, secret, now))
+	return req
+}
+
+func TestSign(t *testing.T) {
+	require.ErrorIs(t, Sign(httptest.NewRequest(http.MethodGet, "/", nil), "", time.Now()),
+		ErrEmptySecret)
+
+	req := signedRequest(t, `{"ssh_fingerprint":"oops"}`, time.Now())
+
+	// the body can still be read after signing
+	b, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"ssh_fingerprint":"oops"}`, string(b))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+
+	tt := []struct {
+		name        string
+		request     func(t *testing.T) *http.Request
+		secret      string
+		expectedErr error
+	}{
+		{
+			name: "request is not signed",
+			request: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/", nil)
+			},
+			secret:      secret,
+			expectedErr: ErrMissingSignature,
+		},
+		{
+			name: "no secret configured",
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, "{}", now)
+			},
+			expectedErr: ErrEmptySecret,
+		},
+		{
+			name: "signed with another secret",
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, "{}", now)
+			},
+			secret:      "another-secret",
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name: "body was tampered with",
+			request: func(t *testing.T) *http.Request {
+				req := signedRequest(t, "{}", now)
+				req.Body = io.NopCloser(strings.NewReader(`{"force_new_endpoint":true}`))
+				return req
+			},
+			secret:      secret,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name: "path was tampered with",
+			request: func(t *testing.T) *http.Request {
+				req := signedRequest(t, "{}", now)
+				req.URL.RawQuery = "limit=100"
+				return req
+			},
+			secret:      secret,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name: "fingerprint was tampered with",
+			request: func(t *testing.T) *http.Request {
+				req := signedRequest(t, "{}", now)
+				req.Header.Set("X-SSH-Fingerprint", "someone-else")
+				return req
+			},
+			secret:      secret,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name: "invalid timestamp",
+			request: func(t *testing.T) *http.Request {
+				req := signedRequest(t, "{}", now)
+				req.Header.Set(TimestampHeader, "yesterday")
+				return req
+			},
+			secret:      secret,
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name: "signature has expired",
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, "{}", now.Add(-MaxClockSkew-time.Minute))
+			},
+			secret:      secret,
+			expectedErr: ErrExpiredSignature,
+		},
+		{
+			name: "body is too large",
+			request: func(t *testing.T) *http.Request {
+				req := signedRequest(t, "{}", now)
+				req.Body = io.NopCloser(strings.NewReader(strings.Repeat("a", MaxBodySize+1)))
+				return req
+			},
+			secret:      secret,
+			expectedErr: ErrBodyTooLarge,
+		},
+		{
+			name: "valid signature",
+			request: func(t *testing.T) *http.Request {
+				return signedRequest(t, `{"ssh_fingerprint":"oops"}`, now.Add(-time.Minute))
+			},
+			secret: secret,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			err := Verify(v.request(t), v.secret, now)
+			if v.expectedErr != nil {
+				require.ErrorIs(t, err, v.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := Verify(r, secret, time.Now()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &Transport{Secret: secret},
+	}
+
+	resp, err := client.Post(server.URL+"/?reference=abc", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,83 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// Claims are the values a token vouches for
+type Claims struct {
+	// Subject is who the token was issued to
+	Subject string `json:"sub"`
+
+	// Audience is what the token can be used for
+	Audience string `json:"aud"`
+
+	ExpiresAt int64 `json:"exp"`
+}
+
+// NewToken issues a token for the claims that expires after ttl
+func NewToken(secret string, claims Claims, ttl time.Duration, now time.Time) (string, error) {
+	if secret == "" {
+		return "", ErrEmptySecret
+	}
+
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + signToken(secret, payload), nil
+}
+
+// VerifyToken checks the token was issued with the secret and has not
+// expired
+func VerifyToken(secret, token string, now time.Time) (*Claims, error) {
+	if secret == "" {
+		return nil, ErrEmptySecret
+	}
+
+	payload, sig, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signToken(secret, payload))) {
+		return nil, ErrInvalidToken
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+func signToken(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
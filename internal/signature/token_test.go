@@ -0,0 +1,49 @@
+package signature
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyToken(t *testing.T) {
+	now := time.Now()
+
+	claims := Claims{
+		Subject:  "8511ac86-5079-42ae-a030-cb46e6dbfbda",
+		Audience: "messages.cmltfm6g330l5l1vq110",
+	}
+
+	_, err := NewToken("", claims, time.Minute, now)
+	require.ErrorIs(t, err, ErrEmptySecret)
+
+	token, err := NewToken(secret, claims, time.Minute, now)
+	require.NoError(t, err)
+
+	verified, err := VerifyToken(secret, token, now.Add(30*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, claims.Subject, verified.Subject)
+	require.Equal(t, claims.Audience, verified.Audience)
+
+	_, err = VerifyToken(secret, token, now.Add(time.Minute))
+	require.ErrorIs(t, err, ErrExpiredToken)
+
+	_, err = VerifyToken("another-secret", token, now)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = VerifyToken(secret, "not-a-token", now)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// swapping the claims invalidates the signature
+	other, err := NewToken(secret, Claims{Subject: claims.Subject, Audience: "messages.other"},
+		time.Minute, now)
+	require.NoError(t, err)
+
+	payload, _, _ := strings.Cut(other, ".")
+	_, sig, _ := strings.Cut(token, ".")
+
+	_, err = VerifyToken(secret, payload+"."+sig, now)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,366 @@
+// Package snippet turns stored requests into code that sends the same
+// request again i.e curl commands or a Go program
+package snippet
+
+import (
+	"fmt"
+	"go/format"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/internal/relay"
+)
+
+type Format string
+
+const (
+	Curl   Format = "curl"
+	HTTPie Format = "httpie"
+	Go     Format = "go"
+	Python Format = "python"
+	Raw    Format = "raw"
+)
+
+// Formats lists all supported formats in the order they should be offered
+var Formats = []Format{Curl, HTTPie, Go, Python, Raw}
+
+var ErrUnsupportedFormat = fmt.Errorf("unsupported snippet format. Use one of %v", Formats)
+
+// Title is the human readable name of the format
+func (f Format) Title() string {
+	switch f {
+	case Curl:
+		return "curl"
+	case HTTPie:
+		return "HTTPie"
+	case Go:
+		return "Go net/http"
+	case Python:
+		return "Python requests"
+	case Raw:
+		return "Raw HTTP/1.1"
+	default:
+		return string(f)
+	}
+}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+
+	return "", ErrUnsupportedFormat
+}
+
+// skippedHeaders are either set by the client automatically or only make
+// sense for the connection the request was originally sent on
+var skippedHeaders = map[string]bool{
+	"Accept-Encoding":     true,
+	"Connection":          true,
+	"Content-Length":      true,
+	"Host":                true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+type header struct {
+	key, value string
+}
+
+// request is the parts of a stored request every format needs
+type request struct {
+	method  string
+	url     *url.URL
+	headers []header
+	body    string
+	binary  bool
+}
+
+func newRequest(target string, req sdump.RequestDefinition) (request, error) {
+	endpoint, err := relay.BuildURL(target, req)
+	if err != nil {
+		return request{}, err
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return request{}, err
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	keys := make([]string, 0, len(req.Headers))
+	for key := range req.Headers {
+		if skippedHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var headers []header
+	for _, key := range keys {
+		for _, value := range req.Headers[key] {
+			headers = append(headers, header{key: key, value: value})
+		}
+	}
+
+	return request{
+		method:  method,
+		url:     u,
+		headers: headers,
+		body:    req.Body,
+		binary:  req.IsBase64Encoded,
+	}, nil
+}
+
+// Generate builds a snippet that sends req to target. The request's path
+// and query are appended to target
+func Generate(f Format, target string, req sdump.RequestDefinition) (string, error) {
+	r, err := newRequest(target, req)
+	if err != nil {
+		return "", err
+	}
+
+	switch f {
+	case Curl:
+		return curl(r), nil
+	case HTTPie:
+		return httpie(r), nil
+	case Go:
+		return golang(r)
+	case Python:
+		return python(r), nil
+	case Raw:
+		return raw(r)
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// shellQuote wraps s in single quotes so the shell does not interpret it
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// stdin is the shell command that writes the request body to stdout
+func (r request) stdin() string {
+	if r.binary {
+		return fmt.Sprintf("echo %s | base64 -d", shellQuote(r.body))
+	}
+
+	return fmt.Sprintf("printf '%%s' %s", shellQuote(r.body))
+}
+
+func curl(r request) string {
+	s := new(strings.Builder)
+
+	if r.binary {
+		s.WriteString(r.stdin())
+		s.WriteString(" | ")
+	}
+
+	fmt.Fprintf(s, "curl -X %s %s", r.method, shellQuote(r.url.String()))
+
+	for _, h := range r.headers {
+		fmt.Fprintf(s, " \\\n  -H %s", shellQuote(h.key+": "+h.value))
+	}
+
+	switch {
+	case r.binary:
+		s.WriteString(" \\\n  --data-binary @-")
+	case r.body != "":
+		fmt.Fprintf(s, " \\\n  --data-raw %s", shellQuote(r.body))
+	}
+
+	return s.String()
+}
+
+func httpie(r request) string {
+	s := new(strings.Builder)
+
+	if r.body != "" {
+		s.WriteString(r.stdin())
+		s.WriteString(" | ")
+	}
+
+	fmt.Fprintf(s, "http %s %s", r.method, shellQuote(r.url.String()))
+
+	for _, h := range r.headers {
+		fmt.Fprintf(s, " \\\n  %s", shellQuote(h.key+":"+h.value))
+	}
+
+	return s.String()
+}
+
+func golang(r request) (string, error) {
+	imports := []string{"fmt", "io", "net/http"}
+
+	body := "nil"
+
+	switch {
+	case r.binary:
+		imports = append(imports, "bytes", "encoding/base64")
+		body = "bytes.NewReader(payload)"
+	case r.body != "":
+		imports = append(imports, "strings")
+		body = "strings.NewReader(payload)"
+	}
+
+	sort.Strings(imports)
+
+	s := new(strings.Builder)
+
+	s.WriteString("package main\n\nimport (\n")
+	for _, i := range imports {
+		fmt.Fprintf(s, "%q\n", i)
+	}
+	s.WriteString(")\n\nfunc main() {\n")
+
+	switch {
+	case r.binary:
+		fmt.Fprintf(s, "payload, err := base64.StdEncoding.DecodeString(%q)\n", r.body)
+		s.WriteString("if err != nil {\npanic(err)\n}\n\n")
+	case r.body != "":
+		fmt.Fprintf(s, "payload := %s\n\n", goString(r.body))
+	}
+
+	fmt.Fprintf(s, "req, err := http.NewRequest(%q, %q, %s)\n", r.method, r.url.String(), body)
+	s.WriteString("if err != nil {\npanic(err)\n}\n\n")
+
+	for _, h := range r.headers {
+		fmt.Fprintf(s, "req.Header.Add(%q, %q)\n", h.key, h.value)
+	}
+
+	s.WriteString(`
+resp, err := http.DefaultClient.Do(req)
+if err != nil {
+panic(err)
+}
+
+defer resp.Body.Close()
+
+b, err := io.ReadAll(resp.Body)
+if err != nil {
+panic(err)
+}
+
+fmt.Println(resp.Status)
+fmt.Println(string(b))
+}
+`)
+
+	src, err := format.Source([]byte(s.String()))
+	if err != nil {
+		return "", err
+	}
+
+	return string(src), nil
+}
+
+// goString uses a raw string literal where possible so bodies such as
+// JSON remain readable
+func goString(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return strconv.Quote(s)
+	}
+
+	return "`" + s + "`"
+}
+
+func python(r request) string {
+	s := new(strings.Builder)
+
+	if r.binary {
+		s.WriteString("import base64\n")
+	}
+
+	s.WriteString("import requests\n\n")
+	fmt.Fprintf(s, "url = %s\n", strconv.Quote(r.url.String()))
+
+	if len(r.headers) > 0 {
+		// python dicts cannot hold the same key twice
+		var keys []string
+		values := map[string][]string{}
+
+		for _, h := range r.headers {
+			if _, ok := values[h.key]; !ok {
+				keys = append(keys, h.key)
+			}
+
+			values[h.key] = append(values[h.key], h.value)
+		}
+
+		s.WriteString("headers = {\n")
+		for _, key := range keys {
+			fmt.Fprintf(s, "    %s: %s,\n", strconv.Quote(key),
+				strconv.Quote(strings.Join(values[key], ", ")))
+		}
+		s.WriteString("}\n")
+	}
+
+	switch {
+	case r.binary:
+		fmt.Fprintf(s, "data = base64.b64decode(%s)\n", strconv.Quote(r.body))
+	case r.body != "":
+		fmt.Fprintf(s, "data = %s\n", strconv.Quote(r.body))
+	}
+
+	fmt.Fprintf(s, "\nresponse = requests.request(%s, url", strconv.Quote(r.method))
+
+	if len(r.headers) > 0 {
+		s.WriteString(", headers=headers")
+	}
+
+	if r.body != "" {
+		s.WriteString(", data=data")
+	}
+
+	s.WriteString(")\n\nprint(response.status_code)\nprint(response.text)\n")
+
+	return s.String()
+}
+
+func raw(r request) (string, error) {
+	body, err := (sdump.RequestDefinition{
+		Body:            r.body,
+		IsBase64Encoded: r.binary,
+	}).RawBody()
+	if err != nil {
+		return "", err
+	}
+
+	s := new(strings.Builder)
+
+	fmt.Fprintf(s, "%s %s HTTP/1.1\r\n", r.method, r.url.RequestURI())
+	fmt.Fprintf(s, "Host: %s\r\n", r.url.Host)
+
+	for _, h := range r.headers {
+		fmt.Fprintf(s, "%s: %s\r\n", h.key, h.value)
+	}
+
+	if len(body) > 0 {
+		fmt.Fprintf(s, "Content-Length: %d\r\n", len(body))
+	}
+
+	s.WriteString("\r\n")
+	s.Write(body)
+
+	return s.String(), nil
+}
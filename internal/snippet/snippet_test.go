@@ -0,0 +1,78 @@
+package snippet
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/adelowo/sdump"
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	tt := []struct {
+		name string
+		req  sdump.RequestDefinition
+	}{
+		{
+			name: "json body",
+			req: sdump.RequestDefinition{
+				Method: http.MethodPost,
+				Path:   "/github/push",
+				Query:  "event=push",
+				Headers: http.Header{
+					"Content-Type":   []string{"application/json"},
+					"Content-Length": []string{"38"},
+					"X-Hub-Event":    []string{"push"},
+					"Accept":         []string{"text/plain", "application/json"},
+				},
+				Body:      `{"name" : "Lanre", "quote": "it's ok"}`,
+				IPAddress: net.ParseIP("127.0.0.1"),
+			},
+		},
+		{
+			name: "no body",
+			req: sdump.RequestDefinition{
+				Method: http.MethodGet,
+				Query:  "hub.challenge=abc",
+			},
+		},
+		{
+			name: "binary body",
+			req: sdump.RequestDefinition{
+				Method: http.MethodPut,
+				Headers: http.Header{
+					"Content-Type": []string{"application/octet-stream"},
+				},
+				Body:            "AAECAw==",
+				IsBase64Encoded: true,
+			},
+		},
+	}
+
+	g := goldie.New(t, goldie.WithFixtureDir("./testdata"))
+
+	for _, v := range tt {
+		for _, f := range Formats {
+			t.Run(v.name+"/"+string(f), func(t *testing.T) {
+				s, err := Generate(f, "https://sdump.app/cmltfm6g330l5l1vq110", v.req)
+				require.NoError(t, err)
+
+				g.Assert(t, t.Name(), []byte(s))
+			})
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("CURL")
+	require.NoError(t, err)
+	require.Equal(t, Curl, f)
+
+	_, err = ParseFormat("powershell")
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = Generate(Format("powershell"), "https://sdump.app", sdump.RequestDefinition{})
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,208 @@
+// Package tmpl renders the body of an endpoint's configured response using
+// values from the ingested request.
+package tmpl
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
+)
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Data is what is made available to templates. It can be used as
+// {{ .Method }}, {{ .Path }} and so on
+type Data struct {
+	Method    string
+	Path      string
+	Query     string
+	Body      string
+	IPAddress string
+}
+
+func newData(req sdump.RequestDefinition) (Data, error) {
+	body, err := req.RawBody()
+	if err != nil {
+		return Data{}, err
+	}
+
+	return Data{
+		Method:    req.Method,
+		Path:      req.Path,
+		Query:     req.Query,
+		Body:      string(body),
+		IPAddress: req.IPAddress.String(),
+	}, nil
+}
+
+func funcs(req sdump.RequestDefinition, data Data) template.FuncMap {
+	var (
+		parsedBody     interface{}
+		isBodyParsed   bool
+		parsedBodyErr  error
+		formValues     url.Values
+		isFormParsed   bool
+		parsedFormErr  error
+		queryValues, _ = url.ParseQuery(req.Query)
+	)
+
+	return template.FuncMap{
+		// json renders nothing if the body is not json just like it does
+		// for a missing path so senders cannot break the response
+		"json": func(path string) (string, error) {
+			if !isBodyParsed {
+				isBodyParsed = true
+				parsedBodyErr = json.Unmarshal([]byte(data.Body), &parsedBody)
+			}
+
+			if parsedBodyErr != nil {
+				return "", nil
+			}
+
+			return Lookup(parsedBody, path)
+		},
+		"header": func(key string) string {
+			return req.Headers.Get(key)
+		},
+		"query": func(key string) string {
+			return queryValues.Get(key)
+		},
+		"form": func(key string) (string, error) {
+			if !isFormParsed {
+				isFormParsed = true
+				formValues, parsedFormErr = url.ParseQuery(data.Body)
+			}
+
+			if parsedFormErr != nil {
+				return "", fmt.Errorf("request body is not form encoded... %v", parsedFormErr)
+			}
+
+			return formValues.Get(key), nil
+		},
+		"uuid": func() string {
+			return uuid.NewString()
+		},
+		"randomInt": func(min, max int64) (int64, error) {
+			if max <= min {
+				return 0, fmt.Errorf("randomInt: max ( %d ) must be greater than min ( %d )", max, min)
+			}
+
+			n, err := rand.Int(rand.Reader, big.NewInt(max-min))
+			if err != nil {
+				return 0, err
+			}
+
+			return n.Int64() + min, nil
+		},
+		"randomString": func(length int) (string, error) {
+			if length <= 0 {
+				return "", fmt.Errorf("randomString: length must be greater than zero")
+			}
+
+			b := make([]byte, length)
+
+			for i := range b {
+				n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphanumeric))))
+				if err != nil {
+					return "", err
+				}
+
+				b[i] = alphanumeric[n.Int64()]
+			}
+
+			return string(b), nil
+		},
+		"now": func() time.Time {
+			return time.Now().UTC()
+		},
+		"timestamp": func() int64 {
+			return time.Now().Unix()
+		},
+	}
+}
+
+// Validate checks the template can be parsed
+func Validate(body string) error {
+	_, err := parse(body, funcs(sdump.RequestDefinition{}, Data{}))
+	return err
+}
+
+func parse(body string, funcMap template.FuncMap) (*template.Template, error) {
+	t, err := template.New("response").
+		Option("missingkey=error").
+		Funcs(funcMap).
+		Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response template... %w", err)
+	}
+
+	return t, nil
+}
+
+// Render executes body as a template using values from req
+func Render(body string, req sdump.RequestDefinition) (string, error) {
+	data, err := newData(req)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := parse(body, funcs(req, data))
+	if err != nil {
+		return "", err
+	}
+
+	s := new(strings.Builder)
+
+	if err := t.Execute(s, data); err != nil {
+		return "", fmt.Errorf("could not render response template... %w", err)
+	}
+
+	return s.String(), nil
+}
+
+// Lookup finds the value at path in v. Path is a dot separated list of object
+// keys or array indexes such as data.items.0.id
+func Lookup(v interface{}, path string) (string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch value := v.(type) {
+			case map[string]interface{}:
+				v = value[key]
+
+			case []interface{}:
+				idx, err := strconv.Atoi(key)
+				if err != nil || idx < 0 || idx >= len(value) {
+					return "", nil
+				}
+
+				v = value[idx]
+
+			default:
+				return "", nil
+			}
+		}
+	}
+
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+
+	case string:
+		return value, nil
+
+	default:
+		b, err := json.Marshal(value)
+		return string(b), err
+	}
+}
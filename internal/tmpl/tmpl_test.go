@@ -0,0 +1,112 @@
+package tmpl
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adelowo/sdump"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	tt := []struct {
+		name     string
+		template string
+		request  sdump.RequestDefinition
+		expected string
+		hasError bool
+	}{
+		{
+			name:     "plain body is returned as is",
+			template: `{"message" : "ok"}`,
+			expected: `{"message" : "ok"}`,
+		},
+		{
+			name:     "echo slack challenge",
+			template: `{{ json "challenge" }}`,
+			request: sdump.RequestDefinition{
+				Body: `{"token":"Jhj5dZrVaK7ZwHHjRyZWjbDl","challenge":"3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P","type":"url_verification"}`,
+			},
+			expected: "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+		},
+		{
+			name:     "nested json paths and arrays",
+			template: `{{ json "$.data.items.1.id" }} {{ json "data.items.0" }} {{ json "data.count" }}`,
+			request: sdump.RequestDefinition{
+				Body: `{"data" : {"count" : 2, "items" : [{"id" : "one"}, {"id" : "two"}]}}`,
+			},
+			expected: `two {"id":"one"} 2`,
+		},
+		{
+			name:     "missing json path renders nothing",
+			template: `[{{ json "data.unknown" }}]`,
+			request: sdump.RequestDefinition{
+				Body: `{"data" : {}}`,
+			},
+			expected: `[]`,
+		},
+		{
+			name:     "json lookup on a non json body",
+			template: `{{ json "challenge" }}`,
+			request: sdump.RequestDefinition{
+				Body: `challenge=yes`,
+			},
+			expected: ``,
+		},
+		{
+			name:     "headers, query, form and request fields",
+			template: `{{ header "X-Hook-Secret" }} {{ query "hub.challenge" }} {{ form "From" }} {{ .Method }} {{ .Path }}`,
+			request: sdump.RequestDefinition{
+				Headers: http.Header{
+					"X-Hook-Secret": []string{"secret"},
+				},
+				Query:  "hub.challenge=1158201444",
+				Body:   "From=%2B14155551234&Body=Hello",
+				Method: http.MethodPost,
+				Path:   "/twilio",
+			},
+			expected: `secret 1158201444 +14155551234 POST /twilio`,
+		},
+		{
+			name:     "invalid template",
+			template: `{{ json "challenge" `,
+			hasError: true,
+		},
+		{
+			name:     "unknown function",
+			template: `{{ unknown }}`,
+			hasError: true,
+		},
+		{
+			name:     "invalid random int range",
+			template: `{{ randomInt 10 1 }}`,
+			hasError: true,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			s, err := Render(v.template, v.request)
+			if v.hasError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, v.expected, s)
+		})
+	}
+}
+
+func TestRender_RandomValues(t *testing.T) {
+	s, err := Render(`{{ uuid }}|{{ randomString 12 }}|{{ randomInt 1 5 }}|{{ timestamp }}|{{ now.Year }}`,
+		sdump.RequestDefinition{})
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9a-f-]{36}\|[a-zA-Z0-9]{12}\|[1-4]\|\d+\|\d{4}$`, s)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(`{"challenge" : "{{ json "challenge" }}"}`))
+	require.Error(t, Validate(`{{ json "challenge" `))
+	require.Error(t, Validate(`{{ unknown }}`))
+}
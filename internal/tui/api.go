@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/signature"
+)
+
+// apiError is the shape of errors returned by the HTTP server
+type apiError struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// BannedError is returned when the connected user has been banned
+type BannedError struct {
+	Message string
+}
+
+func (b *BannedError) Error() string { return b.Message }
+
+// newAPIClient signs every request to the HTTP server with the admin secret
+// as it only trusts the fingerprint on signed requests
+func newAPIClient(cfg *config.Config) *http.Client {
+	return &http.Client{
+		Timeout:   time.Minute,
+		Transport: &signature.Transport{Secret: cfg.HTTP.AdminSecret},
+	}
+}
+
+// CheckBan returns a *BannedError if the user with the fingerprint has been
+// banned. Other errors are ignored so the TUI can report them. opts are the
+// ones the TUI is created with
+func CheckBan(cfg *config.Config, sshFingerprint string, opts ...Option) error {
+	m := model{
+		cfg:            cfg,
+		apiURL:         cfg.HTTP.Domain,
+		sshFingerPrint: sshFingerprint,
+		httpClient:     newAPIClient(cfg),
+	}
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	var banned *BannedError
+	if err := m.doRequest(http.MethodGet, "/api/v1/users/me", nil, nil); errors.As(err, &banned) {
+		return banned
+	}
+
+	return nil
+}
+
+// doRequest sends a request to the HTTP server on behalf of the connected
+// user. If response is not nil, the response body is decoded into it
+func (m model) doRequest(method, path string, body, response interface{}) error {
+	var r io.Reader
+
+	if body != nil {
+		b := new(bytes.Buffer)
+		if err := json.NewEncoder(b).Encode(body); err != nil {
+			return err
+		}
+
+		r = b
+	}
+
+	req, err := http.NewRequest(method, m.apiURL+path, r)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-SSH-Fingerprint", m.sshFingerPrint)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var e apiError
+
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Message == "" {
+			return fmt.Errorf("unexpected response from server ( %d )", resp.StatusCode)
+		}
+
+		if e.Code == sdump.ErrorCodeUserBanned {
+			return &BannedError{Message: e.Message}
+		}
+
+		return errors.New(e.Message)
+	}
+
+	if response == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
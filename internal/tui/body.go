@@ -0,0 +1,192 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adelowo/sdump"
+	"github.com/dustin/go-humanize"
+)
+
+// maxBinaryPreviewSize limits how much of a binary body is shown as a hexdump
+const maxBinaryPreviewSize = 1024
+
+// renderedBody is the representation of a request body as it should be shown
+// in the detailed view.
+type renderedBody struct {
+	content string
+	// lexer is the chroma lexer to highlight the content with.
+	// If empty, no highlighting is done
+	lexer string
+}
+
+func renderBody(req sdump.RequestDefinition) renderedBody {
+	body, err := req.RawBody()
+	if err != nil {
+		return renderedBody{content: req.Body}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(req.ContentType)
+	if err != nil {
+		mediaType = ""
+	}
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return renderedBody{content: renderFormBody(body)}
+
+	case mediaType == "multipart/form-data":
+		return renderedBody{content: renderMultipartBody(body, params["boundary"])}
+
+	case isXMLMediaType(mediaType):
+		return renderedBody{content: string(body), lexer: "xml"}
+
+	case mediaType == "text/html":
+		return renderedBody{content: string(body), lexer: "html"}
+
+	case req.IsBase64Encoded:
+		return renderedBody{content: renderBinaryBody(body)}
+
+	case strings.HasPrefix(mediaType, "text/"):
+		return renderedBody{content: string(body)}
+	}
+
+	// Since the url is meant to take any json content ( valid or not)
+	// we do not want to enforce if a JSON is valid or not. Even on the ingestion side
+	// If we have a valid JSON, pretty print it. Else use the json body as is
+	jsonBody, err := prettyPrintJSON(string(body))
+	if err != nil {
+		jsonBody = string(body)
+	}
+
+	return renderedBody{content: jsonBody, lexer: "json"}
+}
+
+func isXMLMediaType(mediaType string) bool {
+	return mediaType == "application/xml" ||
+		mediaType == "text/xml" ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+func renderFormBody(body []byte) string {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return string(body)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	s := new(strings.Builder)
+
+	for _, key := range keys {
+		for _, value := range values[key] {
+			fmt.Fprintf(s, "%s = %s\n", key, value)
+		}
+	}
+
+	return s.String()
+}
+
+func renderMultipartBody(body []byte, boundary string) string {
+	if boundary == "" {
+		return string(body)
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	s := new(strings.Builder)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			fmt.Fprintf(s, "could not read multipart body... %v\n", err)
+			break
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			fmt.Fprintf(s, "could not read part %s... %v\n", part.FormName(), err)
+			break
+		}
+
+		if part.FileName() == "" && utf8.Valid(content) {
+			fmt.Fprintf(s, "%s = %s\n", part.FormName(), content)
+			continue
+		}
+
+		fmt.Fprintf(s, "%s = [file: %s, %s, %s]\n",
+			part.FormName(), part.FileName(),
+			part.Header.Get("Content-Type"),
+			humanize.Bytes(uint64(len(content))))
+	}
+
+	return s.String()
+}
+
+func renderBinaryBody(body []byte) string {
+	if len(body) <= maxBinaryPreviewSize {
+		return hex.Dump(body)
+	}
+
+	return hex.Dump(body[:maxBinaryPreviewSize]) +
+		fmt.Sprintf("\n... %s more", humanize.Bytes(uint64(len(body)-maxBinaryPreviewSize)))
+}
+
+// renderForwardResult shows how the endpoint's forward target or the user's
+// tunnel responded to the selected request
+func (m model) renderForwardResult(title string, f *sdump.ForwardResult, withHeaders bool) string {
+	s := new(strings.Builder)
+
+	s.WriteString(boldenString(title, true))
+	s.WriteString("\n")
+
+	if f.StatusCode == 0 {
+		s.WriteString(makeString(fmt.Sprintf("Could not forward request... %s", f.Error), false))
+		return s.String()
+	}
+
+	s.WriteString(boldenString(fmt.Sprintf("%d %s in %dms", f.StatusCode,
+		http.StatusText(f.StatusCode), f.LatencyInMS), false))
+	s.WriteString("\n\n")
+
+	if withHeaders && len(f.Headers) > 0 {
+		s.WriteString(formatHeaders(f.Headers))
+		s.WriteString("\n\n")
+	}
+
+	body := renderBody(sdump.RequestDefinition{
+		Body:            f.Body,
+		IsBase64Encoded: f.IsBase64Encoded,
+		ContentType:     f.Headers.Get("Content-Type"),
+	})
+
+	if body.lexer == "" {
+		s.WriteString(body.content)
+	} else if err := highlightCode(s, body.content, body.lexer, m.cfg.TUI.ColorScheme); err != nil {
+		s.WriteString(body.content)
+	}
+
+	if f.IsTruncated {
+		s.WriteString(makeString("\n... response body was truncated", true))
+	}
+
+	return s.String()
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adelowo/sdump/internal/snippet"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// copyMenu lets the user pick the format the selected request should be
+// copied as
+type copyMenu struct {
+	target string
+	item   item
+	cursor int
+	err    error
+}
+
+func newCopyMenu(target string, selectedItem item) copyMenu {
+	return copyMenu{
+		target: target,
+		item:   selectedItem,
+	}
+}
+
+func (c copyMenu) format() snippet.Format { return snippet.Formats[c.cursor] }
+
+func (c copyMenu) value() (string, error) {
+	return snippet.Generate(c.format(), c.target, c.item.Request)
+}
+
+func (c copyMenu) Update(msg tea.Msg) (copyMenu, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return c, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k", "shift+tab":
+		c.cursor = (c.cursor + len(snippet.Formats) - 1) % len(snippet.Formats)
+	case "down", "j", "tab":
+		c.cursor = (c.cursor + 1) % len(snippet.Formats)
+	}
+
+	return c, nil
+}
+
+func (c copyMenu) View() string {
+	views := []string{
+		boldenString(fmt.Sprintf("Copy %s %s as", c.item.Request.Method, c.item.Request.Path), true),
+		makeString("Use j,k or arrow up and down to pick a format. Enter to copy, Esc to cancel", true),
+		"",
+	}
+
+	for i, f := range snippet.Formats {
+		if i == c.cursor {
+			views = append(views, boldenString("> "+f.Title(), false))
+			continue
+		}
+
+		views = append(views, makeString("  "+f.Title(), false))
+	}
+
+	preview, err := c.value()
+	if err != nil {
+		views = append(views, "", errorStyle.Render(err.Error()))
+	} else {
+		// raw HTTP messages use CRLF which breaks the layout
+		views = append(views, "", strings.ReplaceAll(preview, "\r\n", "\n"))
+	}
+
+	if c.err != nil {
+		views = append(views, "", errorStyle.Render(c.err.Error()))
+	}
+
+	return lipgloss.NewStyle().Margin(1, 4).
+		Render(lipgloss.JoinVertical(lipgloss.Left, views...))
+}
@@ -47,6 +47,17 @@ func showError(err error) string {
 		)))
 }
 
+func showBanned(err *BannedError) string {
+	return errorStyle.Render(lipgloss.Place(200, 5, lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(lipgloss.Center,
+			boldenString("You can no longer use sdump", false),
+			"",
+			err.Error(),
+			"",
+			"Press Ctrl-c to shut down",
+		)))
+}
+
 func makeString(s string, withFeint bool) string {
 	style := defaultTextStyle.Copy()
 
@@ -67,8 +78,8 @@ func boldenString(s string, withFeint bool) string {
 	return style.Render(s)
 }
 
-func highlightCode(w io.Writer, s, colorscheme string) error {
-	err := quick.Highlight(w, s, "json", "terminal256", colorscheme)
+func highlightCode(w io.Writer, s, lexer, colorscheme string) error {
+	err := quick.Highlight(w, s, lexer, "terminal256", colorscheme)
 	return err
 }
 
@@ -0,0 +1,322 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adelowo/sdump"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type endpointPromptKind int
+
+const (
+	endpointPromptNone endpointPromptKind = iota
+	endpointPromptName
+	endpointPromptReference
+	endpointPromptRename
+)
+
+// endpointManager lists the user's endpoints and lets them switch between,
+// create, rename or deactivate them
+type endpointManager struct {
+	endpoints []sdump.URLEndpoint
+	cursor    int
+
+	prompting endpointPromptKind
+	prompt    prompt
+
+	// pendingName is the name of the endpoint being created while the
+	// user picks its reference
+	pendingName string
+
+	loading bool
+	err     error
+}
+
+func newEndpointManager() endpointManager {
+	return endpointManager{loading: true}
+}
+
+func (e endpointManager) selected() (sdump.URLEndpoint, bool) {
+	if e.cursor < 0 || e.cursor >= len(e.endpoints) {
+		return sdump.URLEndpoint{}, false
+	}
+
+	return e.endpoints[e.cursor], true
+}
+
+func (e endpointManager) Update(msg tea.Msg) (endpointManager, tea.Cmd) {
+	if e.prompting != endpointPromptNone {
+		var cmd tea.Cmd
+		e.prompt, cmd = e.prompt.Update(msg)
+		return e, cmd
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(e.endpoints) == 0 {
+		return e, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		e.cursor = (e.cursor + len(e.endpoints) - 1) % len(e.endpoints)
+	case "down", "j":
+		e.cursor = (e.cursor + 1) % len(e.endpoints)
+	}
+
+	return e, nil
+}
+
+func (e endpointManager) View(currentReference string) string {
+	if e.prompting != endpointPromptNone {
+		return e.prompt.View()
+	}
+
+	views := []string{
+		boldenString("Endpoints", true),
+		makeString("Use j,k or arrow up and down to select an endpoint. Enter to switch to it, n to create an endpoint, r to rename and d to deactivate the selected endpoint, Esc to go back", true),
+		"",
+	}
+
+	switch {
+	case e.loading:
+		views = append(views, makeString("Loading your endpoints...", true))
+
+	case len(e.endpoints) == 0:
+		views = append(views, makeString("You do not have any endpoints", true))
+	}
+
+	for i, endpoint := range e.endpoints {
+		name := endpoint.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+
+		line := fmt.Sprintf("%-30s %-30s created %s", name, endpoint.Reference,
+			endpoint.CreatedAt.Format("02/01/2006 15:04:05"))
+
+		if !endpoint.IsActive {
+			line += "   inactive"
+		} else if endpoint.IsPaused {
+			line += "   paused"
+		}
+
+		if endpoint.Reference == currentReference {
+			line += "   current"
+		}
+
+		if i == e.cursor {
+			views = append(views, boldenString("> "+line, false))
+			continue
+		}
+
+		views = append(views, makeString("  "+line, false))
+	}
+
+	if e.err != nil {
+		views = append(views, "", errorStyle.Render(e.err.Error()))
+	}
+
+	return lipgloss.NewStyle().Margin(1, 4).
+		Render(lipgloss.JoinVertical(lipgloss.Left, views...))
+}
+
+func (m model) fetchEndpoints() tea.Msg {
+	var response struct {
+		URLs []sdump.URLEndpoint `json:"urls"`
+	}
+
+	err := m.doRequest(http.MethodGet, "/api/v1/urls", nil, &response)
+	return EndpointsMsg{endpoints: response.URLs, err: err}
+}
+
+func (m model) createNamedEndpoint(name, reference string) func() tea.Msg {
+	return func() tea.Msg {
+		msg, err := m.requestEndpoint(map[string]interface{}{
+			"ssh_fingerprint": m.sshFingerPrint,
+			"name":            name,
+			"reference":       reference,
+		})
+		if err != nil {
+			return EndpointSavedMsg{err: err}
+		}
+
+		return msg
+	}
+}
+
+// switchEndpoint subscribes to another of the user's endpoints
+func (m model) switchEndpoint(endpoint sdump.URLEndpoint) func() tea.Msg {
+	return func() tea.Msg {
+		channel, token, err := m.streamToken(endpoint.Reference)
+		if err != nil {
+			return EndpointSavedMsg{err: err}
+		}
+
+		return DumpURLMsg{
+			URL:        fmt.Sprintf("%s/%s", m.cfg.HTTP.Domain, endpoint.Reference),
+			Reference:  endpoint.Reference,
+			Name:       endpoint.Name,
+			IsPaused:   endpoint.IsPaused,
+			SSEChannel: channel,
+			SSEToken:   token,
+		}
+	}
+}
+
+// streamToken issues a new token to subscribe to the endpoint's requests
+// with. Tokens are short lived so one is needed every time the stream is
+// connected to
+func (m model) streamToken(reference string) (string, string, error) {
+	var response struct {
+		SSE struct {
+			Channel string `json:"channel,omitempty"`
+			Token   string `json:"token,omitempty"`
+		} `json:"sse"`
+	}
+
+	err := m.doRequest(http.MethodPost,
+		fmt.Sprintf("/api/v1/urls/%s/stream", reference), nil, &response)
+	return response.SSE.Channel, response.SSE.Token, err
+}
+
+func (m model) renameEndpoint(endpoint sdump.URLEndpoint, name string) func() tea.Msg {
+	return func() tea.Msg {
+		return EndpointSavedMsg{err: m.doRequest(http.MethodPatch,
+			fmt.Sprintf("/api/v1/urls/%s", endpoint.Reference), map[string]string{
+				"name": name,
+			}, nil)}
+	}
+}
+
+func (m model) deactivateEndpoint(endpoint sdump.URLEndpoint) func() tea.Msg {
+	return func() tea.Msg {
+		return EndpointSavedMsg{err: m.doRequest(http.MethodPost,
+			fmt.Sprintf("/api/v1/urls/%s/deactivate", endpoint.Reference), nil, nil)}
+	}
+}
+
+func (m model) updateEndpointManager(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.Type == tea.KeyCtrlC {
+		return m, tea.Quit
+	}
+
+	if m.endpointManager.prompting != endpointPromptNone {
+		return m.updateEndpointPrompt(msg)
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.mode = viewRequests
+		return m, nil
+
+	case "enter":
+		endpoint, ok := m.endpointManager.selected()
+		if !ok {
+			return m, nil
+		}
+
+		m.endpointManager.err = nil
+		return m, m.switchEndpoint(endpoint)
+
+	case "n":
+		m.endpointManager.err = nil
+		m.endpointManager.prompting = endpointPromptName
+		m.endpointManager.prompt = newPrompt("Name your new endpoint",
+			"Enter to continue. Esc to cancel", "stripe-staging", "", m.width)
+		return m, textinput.Blink
+
+	case "r":
+		endpoint, ok := m.endpointManager.selected()
+		if !ok {
+			return m, nil
+		}
+
+		m.endpointManager.err = nil
+		m.endpointManager.prompting = endpointPromptRename
+		m.endpointManager.prompt = newPrompt("Rename endpoint "+endpoint.Reference,
+			"Enter to save. Esc to cancel", "stripe-staging", endpoint.Name, m.width)
+		return m, textinput.Blink
+
+	case "d":
+		endpoint, ok := m.endpointManager.selected()
+		if !ok || !endpoint.IsActive {
+			return m, nil
+		}
+
+		m.endpointManager.err = nil
+		return m, m.deactivateEndpoint(endpoint)
+	}
+
+	var cmd tea.Cmd
+	m.endpointManager, cmd = m.endpointManager.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateEndpointPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.endpointManager.prompting = endpointPromptNone
+		return m, nil
+
+	case tea.KeyEnter:
+		value := strings.TrimSpace(m.endpointManager.prompt.value())
+
+		switch m.endpointManager.prompting {
+		case endpointPromptName:
+			if err := sdump.ValidateEndpointName(value); err != nil {
+				m.endpointManager.prompt.err = err
+				return m, nil
+			}
+
+			m.endpointManager.pendingName = value
+			m.endpointManager.prompting = endpointPromptReference
+			m.endpointManager.prompt = newPrompt("Pick a custom reference for your new endpoint",
+				"Enter to create the endpoint. Leave empty for a random reference. Esc to cancel",
+				"stripe-staging", "", m.width)
+			return m, textinput.Blink
+
+		case endpointPromptReference:
+			if value != "" {
+				if err := sdump.ValidateReference(value); err != nil {
+					m.endpointManager.prompt.err = err
+					return m, nil
+				}
+			}
+
+			return m, m.createNamedEndpoint(m.endpointManager.pendingName, value)
+
+		case endpointPromptRename:
+			endpoint, ok := m.endpointManager.selected()
+			if !ok {
+				m.endpointManager.prompting = endpointPromptNone
+				return m, nil
+			}
+
+			return m, m.renameEndpoint(endpoint, value)
+		}
+	}
+
+	var cmd tea.Cmd
+	m.endpointManager, cmd = m.endpointManager.Update(msg)
+	return m, cmd
+}
+
+func (m model) handleEndpointSaved(msg EndpointSavedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		if m.endpointManager.prompting != endpointPromptNone {
+			m.endpointManager.prompt.err = msg.err
+			return m, nil
+		}
+
+		m.endpointManager.err = msg.err
+		return m, nil
+	}
+
+	m.endpointManager.prompting = endpointPromptNone
+	return m, m.fetchEndpoints
+}
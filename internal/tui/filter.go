@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adelowo/sdump/internal/search"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxSearchPages is how many pages of history are searched for matches
+// before waiting for the user to scroll down
+const maxSearchPages = 10
+
+const filterHelp = "Enter to search older requests too. Esc to cancel. Leave empty to show every request. " +
+	"Filter with method:POST path:/github header:X-GitHub-Event=push ip:10.0.0.0/8 body:text text:\"some words\" " +
+	"json:data.id=evt_1 size:>1kb since:15m until:2024-03-20T10:00:00Z. Prefix a filter with - to negate it"
+
+func (m model) newFilterPrompt() prompt {
+	return newPrompt("Filter requests", filterHelp,
+		"method:POST header:X-GitHub-Event=push", m.filter.String(), m.width)
+}
+
+// showItems displays the loaded requests that match q
+func (m model) showItems(q *search.Query) model {
+	items := make([]list.Item, 0, len(m.items))
+
+	for _, i := range m.items {
+		if q.Match(i.Request, i.CreatedAt) {
+			items = append(items, i)
+		}
+	}
+
+	m.requestList.SetItems(items)
+
+	m.requestList.Title = "Incoming requests"
+	if !q.IsEmpty() {
+		m.requestList.Title = fmt.Sprintf("Incoming requests matching %s", q)
+	}
+
+	return m
+}
+
+// applyFilter filters the loaded requests and searches the endpoint's
+// history for older ones
+func (m model) applyFilter(q *search.Query) (model, tea.Cmd) {
+	m.filter = q
+	m = m.showItems(q)
+
+	m.historyCursor = ""
+	m.hasMoreHistory = false
+	m.loadingHistory = true
+	m.historyErr = nil
+	m.searchPages = 0
+
+	return m, m.fetchHistory(m.reference, "", q)
+}
+
+// searchMoreHistory keeps fetching older requests while the filter matches
+// too few of them to fill a page
+func (m model) searchMoreHistory() (model, tea.Cmd) {
+	if m.filter.IsEmpty() || !m.hasMoreHistory || m.loadingHistory ||
+		m.searchPages >= maxSearchPages ||
+		len(m.requestList.Items()) >= historyPageSize {
+		return m, nil
+	}
+
+	m.searchPages++
+	m.loadingHistory = true
+	return m, m.fetchHistory(m.reference, m.historyCursor, m.filter)
+}
+
+func (m model) updateFilterPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m = m.showItems(m.filter)
+		m.mode = viewRequests
+		return m, nil
+
+	case tea.KeyEnter:
+		q, err := search.Parse(m.filterPrompt.value(), time.Now())
+		if err != nil {
+			m.filterPrompt.err = err
+			return m, nil
+		}
+
+		m.mode = viewRequests
+		return m.applyFilter(q)
+	}
+
+	var cmd tea.Cmd
+	m.filterPrompt, cmd = m.filterPrompt.Update(msg)
+
+	// the loaded requests are filtered as the user types
+	q, err := search.Parse(m.filterPrompt.value(), time.Now())
+	m.filterPrompt.err = err
+	if err == nil {
+		m = m.showItems(q)
+	}
+
+	return m, cmd
+}
+
+func (m model) filterStatus() string {
+	if m.filter.IsEmpty() {
+		return ""
+	}
+
+	status := fmt.Sprintf("Showing %d of %d loaded requests matching %s. Press / to change the filter",
+		len(m.requestList.Items()), len(m.items), m.filter)
+
+	if m.loadingHistory {
+		status += ". Searching older requests..."
+	}
+
+	return boldenString(status, false)
+}
@@ -2,28 +2,37 @@ package tui
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/adelowo/sdump"
 	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/relay"
+	"github.com/adelowo/sdump/internal/search"
+	"github.com/adelowo/sdump/internal/tunnel"
 	"github.com/adelowo/sdump/internal/util"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize/english"
 	"github.com/r3labs/sse/v2"
 	"golang.design/x/clipboard"
 	"golang.org/x/term"
+	"gopkg.in/cenkalti/backoff.v1"
 )
 
 type model struct {
@@ -33,14 +42,46 @@ type model struct {
 	cfg        *config.Config
 	dumpURL    *url.URL
 	pubChannel string
-	err        error
+	reference  string
+	// endpointName is the name the user gave the endpoint in view, if any
+	endpointName string
+	err          error
+
+	// isPaused is true when the endpoint in view replies to requests
+	// without storing or streaming them
+	isPaused bool
+	pauseErr error
+
+	// dropped is how many requests the endpoint in view turned away
+	// because they were rate limited
+	dropped int
+
+	// stopStream ends the subscription to the previous endpoint's
+	// requests once the user switches endpoints. streamCtx is the
+	// context of the current subscription
+	stopStream context.CancelFunc
+	streamCtx  context.Context
+
+	// streamErr is why the subscription to the endpoint's requests was
+	// lost. It is cleared once it is back up
+	streamErr error
+
+	// ctx is cancelled once the SSH session ends
+	ctx context.Context
+
+	// apiURL is where the HTTP server's API is reached. It is the public
+	// domain unless the TUI runs alongside the HTTP server
+	apiURL string
+
+	// subscriber receives the endpoint's requests in process instead of
+	// over SSE when the TUI runs alongside the HTTP server
+	subscriber sdump.Subscriber
 
 	requestList list.Model
 	httpClient  *http.Client
 	colorscheme string
 
-	sseClient                 *sse.Client
-	receiveChan               chan item
+	receiveChan               chan tea.Msg
 	detailedRequestView       viewport.Model
 	detailedRequestViewBuffer *bytes.Buffer
 
@@ -48,8 +89,63 @@ type model struct {
 	width, height int
 
 	sshFingerPrint string
+
+	mode viewMode
+
+	responseEditor responseEditor
+	forwardPrompt  prompt
+
+	tunnel    *tunnel.Tunnel
+	tunnelURL string
+	tunnelErr error
+
+	copyMenu copyMenu
+
+	tokenManager tokenManager
+
+	// items are the requests loaded so far. requestList only shows the
+	// ones matching filter
+	items        []item
+	filter       *search.Query
+	filterPrompt prompt
+
+	// searchPages is how many pages of history were searched for the
+	// filter without the user scrolling
+	searchPages int
+
+	endpointManager endpointManager
+
+	// quota is what is left of the user's plan. It is fetched when an
+	// endpoint is opened and counted down as requests come in
+	quota *sdump.Quota
+
+	// historyCursor is where the next page of older requests starts from
+	historyCursor  string
+	hasMoreHistory bool
+	loadingHistory bool
+	historyErr     error
+
+	replayPrompt       prompt
+	replayItem         item
+	replayView         viewport.Model
+	replayClient       *http.Client
+	tunnelReplayClient *http.Client
 }
 
+type viewMode int
+
+const (
+	viewRequests viewMode = iota
+	viewResponseEditor
+	viewForwardPrompt
+	viewReplayPrompt
+	viewReplayResult
+	viewCopyMenu
+	viewTokenManager
+	viewEndpointManager
+	viewFilterPrompt
+)
+
 func New(cfg *config.Config,
 	opts ...Option,
 ) (tea.Model, error) {
@@ -87,6 +183,11 @@ func newModel(cfg *config.Config, width, height int) model {
 		},
 	}
 
+	replayTimeout := cfg.HTTP.Forwarding.Timeout
+	if replayTimeout <= 0 {
+		replayTimeout = 10 * time.Second
+	}
+
 	m := model{
 		colorscheme: cfg.TUI.ColorScheme,
 		width:       width,
@@ -97,16 +198,20 @@ func newModel(cfg *config.Config, width, height int) model {
 			spinner.WithStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("205"))),
 		),
 
-		cfg: cfg,
-		httpClient: &http.Client{
-			Timeout: time.Minute,
-		},
+		cfg:        cfg,
+		ctx:        context.Background(),
+		apiURL:     cfg.HTTP.Domain,
+		httpClient: newAPIClient(cfg),
+		replayClient: relay.NewClient(replayTimeout,
+			cfg.HTTP.Forwarding.AllowPrivateNetworks),
+		// tunnel listeners live on the SSH server's private address
+		tunnelReplayClient: relay.NewClient(replayTimeout, true),
+		replayView:         viewport.New(width, height/2),
 
 		requestList:               list.New([]list.Item{}, list.NewDefaultDelegate(), 50, height),
 		detailedRequestView:       viewport.New(width, height),
 		detailedRequestViewBuffer: bytes.NewBuffer(nil),
-		sseClient:                 sse.NewClient(fmt.Sprintf("%s/events", cfg.HTTP.Domain)),
-		receiveChan:               make(chan item),
+		receiveChan:               make(chan tea.Msg),
 
 		headersTable: table.New(table.WithColumns(columns),
 			table.WithFocused(true),
@@ -118,6 +223,8 @@ func newModel(cfg *config.Config, width, height int) model {
 
 	m.requestList.Title = "Incoming requests"
 	m.requestList.SetShowTitle(true)
+	// requests are filtered with the search query language instead of the
+	// list's fuzzy matching. See filter.go
 	m.requestList.SetFilteringEnabled(false)
 	m.requestList.DisableQuitKeybindings()
 
@@ -131,82 +238,408 @@ func (m model) isInitialized() bool { return m.dumpURL != nil }
 func (m model) Init() tea.Cmd {
 	tea.SetWindowTitle(m.title)
 
-	return tea.Batch(m.spinner.Tick,
-		m.createEndpoint(false))
+	// a single waiter is kept for the whole session, it is started again
+	// after every request it receives
+	cmds := []tea.Cmd{m.spinner.Tick, m.createEndpoint(false), m.waitForNextItem}
+
+	if m.tunnel != nil {
+		cmds = append(cmds, m.waitForTunnel)
+	}
+
+	return tea.Batch(cmds...)
 }
 
-func (m model) listenForNextItem() tea.Msg {
-	var knownError error
+// streamRetryInterval is how long to wait before subscribing to the
+// endpoint's requests again once the stream gave up reconnecting
+const streamRetryInterval = 10 * time.Second
+
+// newSSEClient subscribes to the endpoint's requests with the short lived
+// token returned when the endpoint was created. A new token is issued
+// before every reconnect since the first one expires after a few minutes
+func (m model) newSSEClient(ctx context.Context, reference, token string) *sse.Client {
+	client := sse.NewClient(fmt.Sprintf("%s/events", m.apiURL))
+	client.Headers["X-Stream-Token"] = token
+
+	reconnect := backoff.NewExponentialBackOff()
+	client.ReconnectStrategy = reconnect
+
+	// isRejected is set once the token was turned down. Only a token
+	// issued after that gets another attempt
+	var isRejected bool
+
+	client.ResponseValidator = func(_ *sse.Client, resp *http.Response) error {
+		if resp.StatusCode == http.StatusOK {
+			isRejected = false
+
+			// streams that stayed up for long get the full retry
+			// budget when they drop
+			reconnect.Reset()
+			m.send(ctx, StreamConnectedMsg{})
+			return nil
+		}
 
-	err := m.sseClient.Subscribe(m.pubChannel, func(msg *sse.Event) {
-		var i item
+		resp.Body.Close()
+
+		err := fmt.Errorf("could not connect to stream: %s", http.StatusText(resp.StatusCode))
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			if isRejected {
+				return backoff.Permanent(err)
+			}
+
+			isRejected = true
+			return err
+
+		// the endpoint is gone. Retrying will not help
+		case resp.StatusCode < http.StatusInternalServerError:
+			return backoff.Permanent(err)
+
+		default:
+			return err
+		}
+	}
+
+	client.ReconnectNotify = func(_ error, _ time.Duration) {
+		// the next attempt uses the old token and is retried if it
+		// has expired
+		_, token, err := m.streamToken(reference)
+		if err != nil {
+			return
+		}
 
-		if err := json.NewDecoder(bytes.NewBuffer(msg.Data)).Decode(&i); err != nil {
+		client.Headers["X-Stream-Token"] = token
+	}
+
+	return client
+}
+
+func (m model) listenForNextItem(ctx context.Context, reference, channel, token string) tea.Msg {
+	if m.subscriber != nil {
+		return m.receiveInProcess(ctx, reference, channel)
+	}
+
+	var knownError error
+
+	err := m.newSSEClient(ctx, reference, token).SubscribeWithContext(ctx, channel, func(msg *sse.Event) {
+		if err := m.receive(ctx, string(msg.Event), msg.Data); err != nil {
 			knownError = err
+		}
+	})
+
+	// the user switched to another endpoint
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if knownError != nil {
+		return StreamErrorMsg{reference: reference, err: knownError}
+	}
+
+	if err != nil {
+		return StreamErrorMsg{reference: reference, err: err}
+	}
+
+	return nil
+}
+
+// resubscribe subscribes to the endpoint's requests again with a new token
+func (m model) resubscribe(ctx context.Context, reference string) tea.Cmd {
+	return func() tea.Msg {
+		channel, token, err := m.streamToken(reference)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return StreamErrorMsg{reference: reference, err: err}
+		}
+
+		return m.listenForNextItem(ctx, reference, channel, token)
+	}
+}
+
+// receiveInProcess subscribes to the pub/sub the HTTP server publishes
+// to, skipping the round trip through the SSE endpoint
+func (m model) receiveInProcess(ctx context.Context, reference, channel string) tea.Msg {
+	var knownError error
+
+	err := m.subscriber.Subscribe(ctx, func(event *sdump.Event) {
+		// events that are too large to publish are only sent to
+		// other HTTP servers, which load them back by ID
+		if event.Channel != channel || len(event.Data) == 0 {
 			return
 		}
 
-		m.receiveChan <- i
+		if err := m.receive(ctx, event.Type, event.Data); err != nil {
+			knownError = err
+		}
 	})
 
+	if ctx.Err() != nil {
+		return nil
+	}
+
 	if knownError != nil {
-		return ErrorMsg{err: err}
+		return StreamErrorMsg{reference: reference, err: knownError}
 	}
 
 	if err != nil {
-		return ErrorMsg{err: err}
+		return StreamErrorMsg{reference: reference, err: err}
+	}
+
+	return nil
+}
+
+// receive hands an event published for the endpoint to the TUI. It gives up
+// once the context is cancelled so the publisher is never blocked by a
+// session that is gone
+func (m model) receive(ctx context.Context, eventType string, data []byte) error {
+	var msg tea.Msg = DroppedMsg{}
+
+	if eventType != sdump.EventTypeDropped {
+		var i item
+
+		if err := json.NewDecoder(bytes.NewBuffer(data)).Decode(&i); err != nil {
+			return err
+		}
+
+		msg = ItemMsg{item: i}
 	}
 
+	m.send(ctx, msg)
 	return nil
 }
 
+// send hands msg to the waiter started in Init unless ctx is cancelled first
+func (m model) send(ctx context.Context, msg tea.Msg) {
+	select {
+	case m.receiveChan <- msg:
+	case <-ctx.Done():
+	}
+}
+
 func (m model) waitForNextItem() tea.Msg {
-	return ItemMsg{item: <-m.receiveChan}
+	return <-m.receiveChan
 }
 
-func (m model) createEndpoint(forceURLChange bool) func() tea.Msg {
-	return func() tea.Msg {
-		// err can be safely ignored
-		req, _ := http.NewRequest(http.MethodPost,
-			m.cfg.HTTP.Domain,
-			strings.NewReader(fmt.Sprintf(`{"ssh_fingerprint" : "%s","force_new_endpoint" : %v}`,
-				m.sshFingerPrint, forceURLChange)))
+// historyPageSize is how many past requests are loaded at a time
+const historyPageSize = 25
 
-		req.Header.Add("Content-Type", "application/json")
+// fetchHistory fetches a page of the endpoint's past requests. The parts of q
+// the api supports are applied by the server, the rest once the page is
+// received
+func (m model) fetchHistory(reference, cursor string, q *search.Query) func() tea.Msg {
+	return func() tea.Msg {
+		var response struct {
+			Requests   []item `json:"requests"`
+			NextCursor string `json:"next_cursor"`
+		}
 
-		resp, err := m.httpClient.Do(req)
-		if err != nil {
-			return ErrorMsg{err: err}
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(historyPageSize))
+		if cursor != "" {
+			query.Set("cursor", cursor)
 		}
 
-		defer resp.Body.Close()
+		if q != nil {
+			for key, value := range map[string]string{
+				"method": q.Method,
+				"path":   q.Path,
+				"header": q.Header,
+				"ip":     q.IP,
+				"json":   q.JSON,
+				"q":      q.Text,
+			} {
+				if value != "" {
+					query.Set(key, value)
+				}
+			}
 
-		if resp.StatusCode > http.StatusCreated {
-			_, err := io.Copy(io.Discard, resp.Body)
-			if err != nil {
-				return ErrorMsg{err: err}
+			for key, value := range map[string]time.Time{
+				"since": q.Since,
+				"until": q.Until,
+			} {
+				if !value.IsZero() {
+					query.Set(key, value.Format(time.RFC3339))
+				}
 			}
+		}
+
+		err := m.doRequest(http.MethodGet,
+			fmt.Sprintf("/api/v1/urls/%s/requests?%s", reference, query.Encode()), nil, &response)
 
-			return ErrorMsg{err: errors.New("an error occurred while creating ingest url")}
+		return HistoryMsg{
+			reference:  reference,
+			query:      q.String(),
+			items:      response.Requests,
+			nextCursor: response.NextCursor,
+			err:        err,
 		}
+	}
+}
 
-		var response struct {
-			URL struct {
-				HumanReadableEndpoint string `json:"human_readable_endpoint,omitempty"`
-			} `json:"url,omitempty"`
-			SSE struct {
-				Channel string `json:"channel,omitempty"`
-			} `json:"sse,omitempty"`
+// loadMoreHistory fetches older requests once the user scrolls to the
+// bottom of the list
+func (m model) loadMoreHistory() (model, tea.Cmd) {
+	if !m.hasMoreHistory || m.loadingHistory ||
+		m.requestList.Index() < len(m.requestList.Items())-1 {
+		return m, nil
+	}
+
+	m.loadingHistory = true
+	m.searchPages = 0
+	return m, m.fetchHistory(m.reference, m.historyCursor, m.filter)
+}
+
+func (m model) waitForTunnel() tea.Msg {
+	url, ok := <-m.tunnel.Updates()
+	if !ok {
+		return nil
+	}
+
+	return TunnelMsg{url: url}
+}
+
+// saveTunnel points the endpoint at the user's reverse SSH tunnel or
+// removes it if the tunnel is no longer available. The endpoint is cleared
+// once the SSH connection goes away since the user may not be around to
+func (m model) saveTunnel(tunnelURL string) func() tea.Msg {
+	return func() tea.Msg {
+		path := fmt.Sprintf("/api/v1/urls/%s/tunnel", m.reference)
+
+		if tunnelURL == "" {
+			m.tunnel.OnClose(m.reference, nil)
+			return TunnelSavedMsg{err: m.doRequest(http.MethodDelete, path, nil, nil)}
+		}
+
+		err := m.doRequest(http.MethodPut, path, &sdump.ForwardDefinition{
+			URL: tunnelURL,
+		}, nil)
+		if err != nil {
+			return TunnelSavedMsg{err: err}
 		}
 
-		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			return ErrorMsg{err: err}
+		m.tunnel.OnClose(m.reference, func() {
+			_ = m.doRequest(http.MethodDelete, path, nil, nil)
+		})
+
+		return TunnelSavedMsg{}
+	}
+}
+
+// createEndpoint fetches the user's latest endpoint. If forceURLChange is
+// true, a new endpoint is created and the one in view is deactivated
+func (m model) createEndpoint(forceURLChange bool) func() tea.Msg {
+	body := map[string]interface{}{
+		"ssh_fingerprint":    m.sshFingerPrint,
+		"force_new_endpoint": forceURLChange,
+	}
+
+	if forceURLChange && m.reference != "" {
+		body["replace"] = m.reference
+	}
+
+	return func() tea.Msg {
+		msg, err := m.requestEndpoint(body)
+		if err != nil {
+			return ErrorMsg{err: fmt.Errorf("an error occurred while creating ingest url... %w", err)}
+		}
+
+		return msg
+	}
+}
+
+// requestEndpoint fetches or creates the user's endpoint
+func (m model) requestEndpoint(body map[string]interface{}) (DumpURLMsg, error) {
+	var response struct {
+		URL struct {
+			Identifier            string `json:"identifier,omitempty"`
+			HumanReadableEndpoint string `json:"human_readable_endpoint,omitempty"`
+			Name                  string `json:"name,omitempty"`
+			IsPaused              bool   `json:"is_paused,omitempty"`
+		} `json:"url,omitempty"`
+		SSE struct {
+			Channel string `json:"channel,omitempty"`
+			Token   string `json:"token,omitempty"`
+		} `json:"sse,omitempty"`
+	}
+
+	if err := m.doRequest(http.MethodPost, "", body, &response); err != nil {
+		return DumpURLMsg{}, err
+	}
+
+	return DumpURLMsg{
+		URL:        response.URL.HumanReadableEndpoint,
+		Reference:  response.URL.Identifier,
+		Name:       response.URL.Name,
+		IsPaused:   response.URL.IsPaused,
+		SSEChannel: response.SSE.Channel,
+		SSEToken:   response.SSE.Token,
+	}, nil
+}
+
+func (m model) fetchResponse() tea.Msg {
+	var response struct {
+		Response *sdump.ResponseDefinition `json:"response"`
+	}
+
+	err := m.doRequest(http.MethodGet,
+		fmt.Sprintf("/api/v1/urls/%s/response", m.reference), nil, &response)
+	if err != nil {
+		return ErrorMsg{err: err}
+	}
+
+	return ResponseMsg{response: response.Response}
+}
+
+func (m model) fetchForward() tea.Msg {
+	var response struct {
+		Forward *sdump.ForwardDefinition `json:"forward"`
+	}
+
+	err := m.doRequest(http.MethodGet,
+		fmt.Sprintf("/api/v1/urls/%s/forward", m.reference), nil, &response)
+	if err != nil {
+		return ErrorMsg{err: err}
+	}
+
+	return ForwardMsg{forward: response.Forward}
+}
+
+func (m model) saveForward(forward *sdump.ForwardDefinition) func() tea.Msg {
+	return func() tea.Msg {
+		path := fmt.Sprintf("/api/v1/urls/%s/forward", m.reference)
+
+		if forward == nil {
+			return ForwardSavedMsg{err: m.doRequest(http.MethodDelete, path, nil, nil)}
 		}
 
-		return DumpURLMsg{
-			URL:        response.URL.HumanReadableEndpoint,
-			SSEChannel: response.SSE.Channel,
+		return ForwardSavedMsg{err: m.doRequest(http.MethodPut, path, forward, nil)}
+	}
+}
+
+// togglePause pauses or resumes capturing the requests sent to the endpoint
+// in view
+func (m model) togglePause() tea.Msg {
+	path := fmt.Sprintf("/api/v1/urls/%s/pause", m.reference)
+
+	if m.isPaused {
+		return PauseSavedMsg{paused: false, err: m.doRequest(http.MethodDelete, path, nil, nil)}
+	}
+
+	return PauseSavedMsg{paused: true, err: m.doRequest(http.MethodPut, path, nil, nil)}
+}
+
+func (m model) saveResponse(response *sdump.ResponseDefinition) func() tea.Msg {
+	return func() tea.Msg {
+		path := fmt.Sprintf("/api/v1/urls/%s/response", m.reference)
+
+		if response == nil {
+			return ResponseSavedMsg{err: m.doRequest(http.MethodDelete, path, nil, nil)}
 		}
+
+		return ResponseSavedMsg{err: m.doRequest(http.MethodPut, path, response, nil)}
 	}
 }
 
@@ -232,21 +665,256 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+		if msg.Reference != m.reference {
+			m.items = nil
+			m.requestList.SetItems([]list.Item{})
+			m.dropped = 0
+		}
+
 		m.pubChannel = msg.SSEChannel
-		go m.listenForNextItem()
-		return m, m.waitForNextItem
+		m.reference = msg.Reference
+		m.endpointName = msg.Name
+		m.isPaused = msg.IsPaused
+		m.pauseErr = nil
+		m.mode = viewRequests
+		m.historyCursor = ""
+		m.hasMoreHistory = false
+		m.loadingHistory = true
+		m.historyErr = nil
+		m.searchPages = 0
+
+		if m.stopStream != nil {
+			m.stopStream()
+		}
+
+		var ctx context.Context
+		ctx, m.stopStream = context.WithCancel(m.ctx)
+		m.streamCtx = ctx
+		m.streamErr = nil
+
+		// only returns once the subscription fails or is stopped
+		listen := func() tea.Msg {
+			return m.listenForNextItem(ctx, msg.Reference, msg.SSEChannel, msg.SSEToken)
+		}
+
+		cmds := []tea.Cmd{listen, m.fetchHistory(m.reference, "", m.filter), m.fetchQuota}
+
+		if m.tunnel != nil {
+			// also clears a tunnel left behind by a previous session
+			cmds = append(cmds, m.saveTunnel(m.tunnelURL))
+		}
+
+		return m, tea.Batch(cmds...)
+
+	case HistoryMsg:
+
+		// the endpoint or filter changed while the page was being fetched
+		if msg.reference != m.reference || msg.query != m.filter.String() {
+			return m, cmd
+		}
+
+		m.loadingHistory = false
+		m.historyErr = msg.err
+
+		if msg.err != nil {
+			return m, cmd
+		}
+
+		// requests ingested while the page was being fetched may have
+		// been received over SSE already
+		seen := make(map[string]bool, len(m.items))
+		for _, i := range m.items {
+			seen[i.ID] = true
+		}
+
+		for _, i := range msg.items {
+			if seen[i.ID] {
+				continue
+			}
+
+			m.items = append(m.items, i)
+		}
+
+		m = m.showItems(m.filter)
+
+		m.historyCursor = msg.nextCursor
+		m.hasMoreHistory = msg.nextCursor != ""
+		return m.searchMoreHistory()
+
+	case TunnelMsg:
+
+		m.tunnelURL = msg.url
+		if !m.isInitialized() {
+			return m, m.waitForTunnel
+		}
+
+		return m, tea.Batch(m.waitForTunnel, m.saveTunnel(m.tunnelURL))
+
+	case TunnelSavedMsg:
+
+		m.tunnelErr = msg.err
+		return m, cmd
 
 	case ErrorMsg:
 
 		m.err = msg.err
 		return m, cmd
 
+	case StreamErrorMsg:
+
+		// the user switched to another endpoint in the meantime
+		if msg.reference != m.reference {
+			return m, cmd
+		}
+
+		var banned *BannedError
+		if errors.As(msg.err, &banned) {
+			m.err = banned
+			return m, cmd
+		}
+
+		m.streamErr = msg.err
+
+		retry := resubscribeMsg{reference: msg.reference, ctx: m.streamCtx}
+		return m, tea.Tick(streamRetryInterval, func(time.Time) tea.Msg {
+			return retry
+		})
+
+	case resubscribeMsg:
+
+		if msg.ctx != m.streamCtx {
+			return m, cmd
+		}
+
+		return m, m.resubscribe(msg.ctx, msg.reference)
+
+	case StreamConnectedMsg:
+
+		m.streamErr = nil
+		return m, m.waitForNextItem
+
 	case ItemMsg:
 
-		m.requestList.InsertItem(0, msg.item)
+		m.items = append([]item{msg.item}, m.items...)
+		if m.filter.Match(msg.item.Request, msg.item.CreatedAt) {
+			m.requestList.InsertItem(0, msg.item)
+		}
+
+		// every stored request uses up one of today's requests so the
+		// quota is kept up to date without asking the server each time
+		if m.quota != nil {
+			quota := *m.quota
+			_ = quota.RequestsToday.Take()
+			m.quota = &quota
+		}
+
+		return m, m.waitForNextItem
+
+	case DroppedMsg:
 
+		m.dropped++
 		return m, m.waitForNextItem
 
+	case QuotaMsg:
+
+		// the quota is informational so a failed refresh keeps the
+		// last known one
+		if msg.err == nil {
+			m.quota = msg.quota
+		}
+
+		return m, cmd
+
+	case ResponseMsg:
+
+		m.responseEditor = newResponseEditor(m.width, msg.response)
+		m.mode = viewResponseEditor
+		return m, textarea.Blink
+
+	case ResponseSavedMsg:
+
+		if msg.err != nil {
+			m.responseEditor.err = msg.err
+			return m, cmd
+		}
+
+		m.mode = viewRequests
+		return m, cmd
+
+	case ForwardMsg:
+
+		var target string
+		if msg.forward != nil {
+			target = msg.forward.URL
+		}
+
+		m.forwardPrompt = newPrompt("Forward ingested requests to another server",
+			"Enter to save. Leave empty and press Enter to stop forwarding. Esc to cancel",
+			"https://example.com/webhooks", target, m.width)
+		m.mode = viewForwardPrompt
+		return m, textinput.Blink
+
+	case ForwardSavedMsg:
+
+		if msg.err != nil {
+			m.forwardPrompt.err = msg.err
+			return m, cmd
+		}
+
+		m.mode = viewRequests
+		return m, cmd
+
+	case TokensMsg:
+
+		m.tokenManager.loading = false
+		m.tokenManager.err = msg.err
+		m.tokenManager.tokens = msg.tokens
+		if m.tokenManager.cursor >= len(msg.tokens) {
+			m.tokenManager.cursor = 0
+		}
+
+		return m, cmd
+
+	case TokenSavedMsg:
+
+		return m.handleTokenSaved(msg)
+
+	case EndpointsMsg:
+
+		m.endpointManager.loading = false
+		m.endpointManager.err = msg.err
+		m.endpointManager.endpoints = msg.endpoints
+		if m.endpointManager.cursor >= len(msg.endpoints) {
+			m.endpointManager.cursor = 0
+		}
+
+		return m, cmd
+
+	case EndpointSavedMsg:
+
+		return m.handleEndpointSaved(msg)
+
+	case PauseSavedMsg:
+
+		m.pauseErr = msg.err
+		if msg.err == nil {
+			m.isPaused = msg.paused
+		}
+
+		return m, cmd
+
+	case ReplayMsg:
+
+		// the user cancelled while the request was in flight
+		if m.mode != viewReplayPrompt {
+			return m, cmd
+		}
+
+		m.replayView.SetContent(m.renderReplayResult(msg.result))
+		m.replayView.GotoTop()
+		m.mode = viewReplayResult
+		return m, cmd
+
 	case tea.WindowSizeMsg:
 
 		m.requestList.SetSize(msg.Width, msg.Height-27)
@@ -254,13 +922,111 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case tea.KeyMsg:
+		switch m.mode {
+		case viewResponseEditor:
+			return m.updateResponseEditor(msg)
+
+		case viewForwardPrompt:
+			return m.updateForwardPrompt(msg)
+
+		case viewReplayPrompt:
+			return m.updateReplayPrompt(msg)
+
+		case viewReplayResult:
+			return m.updateReplayResult(msg)
+
+		case viewCopyMenu:
+			return m.updateCopyMenu(msg)
+
+		case viewTokenManager:
+			return m.updateTokenManager(msg)
+
+		case viewEndpointManager:
+			return m.updateEndpointManager(msg)
+
+		case viewFilterPrompt:
+			return m.updateFilterPrompt(msg)
+		}
+
+		if msg.String() == "/" && m.isInitialized() {
+			m.filterPrompt = m.newFilterPrompt()
+			m.mode = viewFilterPrompt
+			return m, textinput.Blink
+		}
+
 		switch msg.Type {
+		case tea.KeyCtrlF:
+
+			if !m.isInitialized() {
+				return m, cmd
+			}
+
+			return m, m.fetchForward
+
+		case tea.KeyCtrlX:
+
+			selectedItem, ok := m.requestList.SelectedItem().(item)
+			if !ok || !m.isInitialized() {
+				return m, cmd
+			}
+
+			m.copyMenu = newCopyMenu(m.dumpURL.String(), selectedItem)
+			m.mode = viewCopyMenu
+			return m, cmd
+
+		case tea.KeyCtrlP:
+
+			selectedItem, ok := m.requestList.SelectedItem().(item)
+			if !ok {
+				return m, cmd
+			}
+
+			m.replayItem = selectedItem
+			m.replayPrompt = m.newReplayPrompt(selectedItem)
+			m.mode = viewReplayPrompt
+			return m, textinput.Blink
+
+		case tea.KeyCtrlT:
+
+			m.tokenManager = newTokenManager()
+			m.mode = viewTokenManager
+			return m, m.fetchTokens
+
+		case tea.KeyCtrlO:
+
+			if !m.isInitialized() {
+				return m, cmd
+			}
+
+			m.endpointManager = newEndpointManager()
+			m.mode = viewEndpointManager
+			return m, m.fetchEndpoints
+
+		case tea.KeyCtrlE:
+
+			if !m.isInitialized() {
+				return m, cmd
+			}
+
+			return m, m.fetchResponse
+
 		case tea.KeyCtrlR:
 
+			cmd = m.createEndpoint(true)
+
 			m.dumpURL = nil
+			m.items = nil
 			m.requestList.SetItems([]list.Item{})
 
-			return m, m.createEndpoint(true)
+			return m, cmd
+
+		case tea.KeyCtrlA:
+
+			if !m.isInitialized() {
+				return m, cmd
+			}
+
+			return m, m.togglePause
 
 		case tea.KeyCtrlY:
 
@@ -280,9 +1046,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	var cmds []tea.Cmd
 
+	switch m.mode {
+	case viewResponseEditor:
+		m.responseEditor, cmd = m.responseEditor.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case viewForwardPrompt:
+		m.forwardPrompt, cmd = m.forwardPrompt.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case viewReplayPrompt:
+		m.replayPrompt, cmd = m.replayPrompt.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case viewReplayResult:
+		m.replayView, cmd = m.replayView.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case viewTokenManager:
+		m.tokenManager, cmd = m.tokenManager.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case viewEndpointManager:
+		m.endpointManager, cmd = m.endpointManager.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case viewFilterPrompt:
+		m.filterPrompt, cmd = m.filterPrompt.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
 	m.requestList, cmd = m.requestList.Update(msg)
 	cmds = append(cmds, cmd)
 
+	if m.mode == viewRequests {
+		m, cmd = m.loadMoreHistory()
+		cmds = append(cmds, cmd)
+	}
+
 	m.detailedRequestView, cmd = m.detailedRequestView.Update(msg)
 	cmds = append(cmds, cmd)
 
@@ -292,7 +1093,149 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+func (m model) updateResponseEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m.mode = viewRequests
+		return m, nil
+
+	case tea.KeyCtrlD:
+		return m, m.saveResponse(nil)
+
+	case tea.KeyCtrlS:
+		response, err := m.responseEditor.value()
+		if err != nil {
+			m.responseEditor.err = err
+			return m, nil
+		}
+
+		return m, m.saveResponse(response)
+	}
+
+	var cmd tea.Cmd
+	m.responseEditor, cmd = m.responseEditor.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateForwardPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m.mode = viewRequests
+		return m, nil
+
+	case tea.KeyEnter:
+		target := strings.TrimSpace(m.forwardPrompt.value())
+		if target == "" {
+			return m, m.saveForward(nil)
+		}
+
+		forward := &sdump.ForwardDefinition{URL: target}
+		if err := forward.Validate(); err != nil {
+			m.forwardPrompt.err = err
+			return m, nil
+		}
+
+		return m, m.saveForward(forward)
+	}
+
+	var cmd tea.Cmd
+	m.forwardPrompt, cmd = m.forwardPrompt.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateReplayPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m.mode = viewRequests
+		return m, nil
+
+	case tea.KeyEnter:
+		m.replayPrompt.err = nil
+		target := strings.TrimSpace(m.replayPrompt.value())
+
+		if target == tunnelReplayTarget {
+			if m.tunnelURL == "" {
+				m.replayPrompt.err = errors.New("you do not have an open SSH tunnel")
+				return m, nil
+			}
+
+			return m, m.replay(target, m.replayItem.Request)
+		}
+
+		if err := (&sdump.ForwardDefinition{URL: target}).Validate(); err != nil {
+			m.replayPrompt.err = errors.New("replay url must be a valid http or https url")
+			return m, nil
+		}
+
+		return m, m.replay(target, m.replayItem.Request)
+	}
+
+	var cmd tea.Cmd
+	m.replayPrompt, cmd = m.replayPrompt.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateCopyMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m.mode = viewRequests
+		return m, nil
+
+	case tea.KeyEnter:
+		s, err := m.copyMenu.value()
+		if err != nil {
+			m.copyMenu.err = err
+			return m, nil
+		}
+
+		_ = clipboard.Write(clipboard.FmtText, []byte(s))
+		m.mode = viewRequests
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.copyMenu, cmd = m.copyMenu.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateReplayResult(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m.mode = viewRequests
+		return m, nil
+
+	case tea.KeyCtrlP:
+		// send the same request again, possibly to another url
+		m.mode = viewReplayPrompt
+		return m, textinput.Blink
+	}
+
+	var cmd tea.Cmd
+	m.replayView, cmd = m.replayView.Update(msg)
+	return m, cmd
+}
+
 func (m model) View() string {
+	var banned *BannedError
+	if errors.As(m.err, &banned) {
+		return showBanned(banned)
+	}
+
 	if m.err != nil {
 		return showError(m.err)
 	}
@@ -314,13 +1257,134 @@ func (m model) View() string {
 		lipgloss.JoinVertical(lipgloss.Center,
 			boldenString("Inspecting incoming HTTP requests", true),
 			boldenString(fmt.Sprintf(`
-Waiting for requests on %s .. Press Ctrl-y to copy the url. Use ctrl-b to copy the json request body in view.
-				You can use j,k or arrow up and down to navigate your requests`, m.dumpURL), true),
+Waiting for requests on %s .. Press Ctrl-y to copy the url. Use ctrl-b to copy the request body in view.
+				Use ctrl-e to configure the response sent back to your requests and ctrl-f to forward them to another server.
+				Use ctrl-p to replay the request in view to any url and ctrl-x to copy it as curl, HTTPie, Go, Python or raw HTTP.
+				Use ctrl-t to manage the API tokens used to access the HTTP API and ctrl-o to switch between your endpoints.
+				Use ctrl-r to replace this url with a new one and ctrl-a to pause or resume capturing requests. Press / to filter requests.
+				You can use j,k or arrow up and down to navigate your requests`, m.endpointLabel()), true),
 		))
 
+	if tunnelStatus := m.tunnelStatus(); tunnelStatus != "" {
+		browserHeader = lipgloss.JoinVertical(lipgloss.Center, browserHeader,
+			lipgloss.PlaceHorizontal(200, lipgloss.Center, tunnelStatus))
+	}
+
+	if pauseStatus := m.pauseStatus(); pauseStatus != "" {
+		browserHeader = lipgloss.JoinVertical(lipgloss.Center, browserHeader,
+			lipgloss.PlaceHorizontal(200, lipgloss.Center, pauseStatus))
+	}
+
+	if droppedStatus := m.droppedStatus(); droppedStatus != "" {
+		browserHeader = lipgloss.JoinVertical(lipgloss.Center, browserHeader,
+			lipgloss.PlaceHorizontal(200, lipgloss.Center, droppedStatus))
+	}
+
+	if quotaStatus := m.quotaStatus(); quotaStatus != "" {
+		browserHeader = lipgloss.JoinVertical(lipgloss.Center, browserHeader,
+			lipgloss.PlaceHorizontal(200, lipgloss.Center, quotaStatus))
+	}
+
+	if filterStatus := m.filterStatus(); filterStatus != "" {
+		browserHeader = lipgloss.JoinVertical(lipgloss.Center, browserHeader,
+			lipgloss.PlaceHorizontal(200, lipgloss.Center, filterStatus))
+	}
+
+	if m.streamErr != nil {
+		browserHeader = lipgloss.JoinVertical(lipgloss.Center, browserHeader,
+			lipgloss.PlaceHorizontal(200, lipgloss.Center,
+				errorStyle.Render(fmt.Sprintf("Lost connection to your requests, reconnecting... %v", m.streamErr))))
+	}
+
+	if m.historyErr != nil {
+		browserHeader = lipgloss.JoinVertical(lipgloss.Center, browserHeader,
+			lipgloss.PlaceHorizontal(200, lipgloss.Center,
+				errorStyle.Render(fmt.Sprintf("Could not load older requests... %v", m.historyErr))))
+	}
+
+	switch m.mode {
+	case viewResponseEditor:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) + m.responseEditor.View()
+
+	case viewForwardPrompt:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) + m.forwardPrompt.View()
+
+	case viewReplayPrompt:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) + m.replayPrompt.View()
+
+	case viewCopyMenu:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) + m.copyMenu.View()
+
+	case viewTokenManager:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) + m.tokenManager.View()
+
+	case viewEndpointManager:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) + m.endpointManager.View(m.reference)
+
+	case viewFilterPrompt:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) +
+			m.filterPrompt.View() + "\n" + m.makeTable()
+
+	case viewReplayResult:
+		return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) +
+			lipgloss.NewStyle().Margin(1, 4).Render(lipgloss.JoinVertical(lipgloss.Left,
+				makeString("Use j,k or arrow up and down to scroll. Ctrl-p to replay again, Esc to go back", true),
+				"",
+				m.replayView.View()))
+	}
+
 	return m.spinner.View() + browserHeader + strings.Repeat("\n", 2) + m.makeTable()
 }
 
+// endpointLabel is the url of the endpoint in view and its name if it has one
+func (m model) endpointLabel() string {
+	if m.endpointName == "" {
+		return m.dumpURL.String()
+	}
+
+	return fmt.Sprintf("%s (%s)", m.dumpURL, m.endpointName)
+}
+
+func (m model) pauseStatus() string {
+	switch {
+	case m.pauseErr != nil:
+		return errorStyle.Render(fmt.Sprintf("Could not update capture... %v", m.pauseErr))
+
+	case m.isPaused:
+		return boldenString("Capture paused. Requests get your configured response but are not stored. Press ctrl-a to resume", false)
+
+	default:
+		return ""
+	}
+}
+
+// droppedStatus tells the user requests are being turned away by the rate
+// limiter since they never show up in the list
+func (m model) droppedStatus() string {
+	if m.dropped == 0 {
+		return ""
+	}
+
+	return errorStyle.Render(fmt.Sprintf("%s dropped because of the rate limit",
+		english.Plural(m.dropped, "request", "")))
+}
+
+func (m model) tunnelStatus() string {
+	switch {
+	case m.tunnel == nil:
+		return ""
+
+	case m.tunnelErr != nil:
+		return errorStyle.Render(fmt.Sprintf("Could not set up your tunnel... %v", m.tunnelErr))
+
+	case m.tunnelURL == "":
+		return makeString("Reconnect with ssh -R 0:localhost:<port> to deliver requests to your machine", true)
+
+	default:
+		return boldenString("Tunnel open. Requests are also delivered to your machine", false)
+	}
+}
+
 func (m model) buildView() string {
 	return lipgloss.JoinHorizontal(lipgloss.Top,
 		lipgloss.NewStyle().Margin(1, 4).
@@ -340,24 +1404,33 @@ func (m model) makeTable() string {
 
 	m.detailedRequestViewBuffer.Reset()
 
-	// Since the url is meant to take any json content ( valid or not)
-	// we do not want to enforce if a JSON is valid or not. Even on the ingestion side
-	// If we have a valid JSON, pretty print it. Else use the json body as is
-	jsonBody, err := prettyPrintJSON(selectedItem.Request.Body)
-	if err != nil {
-		jsonBody = selectedItem.Request.Body
-	}
+	body := renderBody(selectedItem.Request)
 
-	// if we have an error here, just reuse the json body as it is without adding
+	// if we have an error here, just reuse the body as it is without adding
 	// color
-	if err := highlightCode(m.detailedRequestViewBuffer, jsonBody, m.cfg.TUI.ColorScheme); err != nil {
-		m.detailedRequestViewBuffer.WriteString(jsonBody)
+	if body.lexer == "" {
+		m.detailedRequestViewBuffer.WriteString(body.content)
+	} else if err := highlightCode(m.detailedRequestViewBuffer, body.content, body.lexer, m.cfg.TUI.ColorScheme); err != nil {
+		m.detailedRequestViewBuffer.Reset()
+		m.detailedRequestViewBuffer.WriteString(body.content)
+	}
+
+	if selectedItem.Forward != nil {
+		m.detailedRequestViewBuffer.WriteString("\n\n")
+		m.detailedRequestViewBuffer.WriteString(m.renderForwardResult("Forwarded to "+selectedItem.Forward.URL,
+			selectedItem.Forward, false))
+	}
+
+	if selectedItem.Tunnel != nil {
+		m.detailedRequestViewBuffer.WriteString("\n\n")
+		m.detailedRequestViewBuffer.WriteString(m.renderForwardResult("Delivered through your SSH tunnel",
+			selectedItem.Tunnel, false))
 	}
 
 	m.detailedRequestView.SetContent(m.detailedRequestViewBuffer.String())
 
 	m.detailedRequestViewBuffer.Reset()
-	m.detailedRequestViewBuffer.WriteString(jsonBody)
+	m.detailedRequestViewBuffer.WriteString(body.content)
 
 	var keys []string
 	for key := range selectedItem.Request.Headers {
@@ -1,7 +1,11 @@
 package tui
 
 import (
+	"context"
+
+	"github.com/adelowo/sdump"
 	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/tunnel"
 )
 
 type Option func(*model)
@@ -35,3 +39,37 @@ func WithSSHFingerPrint(fingerPrint string) Option {
 		m.sshFingerPrint = fingerPrint
 	}
 }
+
+// WithTunnel delivers ingested requests through the reverse SSH tunnel the
+// user opened on this connection
+func WithTunnel(t *tunnel.Tunnel) Option {
+	return func(m *model) {
+		m.tunnel = t
+	}
+}
+
+// WithContext ties the TUI's subscriptions to the SSH session so they stop
+// once the user disconnects
+func WithContext(ctx context.Context) Option {
+	return func(m *model) {
+		m.ctx = ctx
+	}
+}
+
+// WithAPIURL sends the TUI's API requests to apiURL instead of the public
+// domain, e.g the HTTP server's local address when both run in the same
+// process
+func WithAPIURL(apiURL string) Option {
+	return func(m *model) {
+		m.apiURL = apiURL
+	}
+}
+
+// WithSubscriber receives ingested requests straight from the pub/sub the
+// HTTP server publishes to. It can only be used when both servers run in
+// the same process
+func WithSubscriber(subscriber sdump.Subscriber) Option {
+	return func(m *model) {
+		m.subscriber = subscriber
+	}
+}
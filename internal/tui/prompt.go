@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// prompt asks the user for a single line of input
+type prompt struct {
+	title string
+	help  string
+	input textinput.Model
+	err   error
+}
+
+func newPrompt(title, help, placeholder, value string, width int) prompt {
+	input := textinput.New()
+	input.Placeholder = placeholder
+	input.Width = width / 2
+	input.SetValue(value)
+	input.CursorEnd()
+	input.Focus()
+
+	return prompt{
+		title: title,
+		help:  help,
+		input: input,
+	}
+}
+
+func (p prompt) value() string { return p.input.Value() }
+
+func (p prompt) Update(msg tea.Msg) (prompt, tea.Cmd) {
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	return p, cmd
+}
+
+func (p prompt) View() string {
+	views := []string{
+		boldenString(p.title, true),
+		makeString(p.help, true),
+		"",
+		p.input.View(),
+	}
+
+	if p.err != nil {
+		views = append(views, "", errorStyle.Render(p.err.Error()))
+	}
+
+	return lipgloss.NewStyle().Margin(1, 4).
+		Render(lipgloss.JoinVertical(lipgloss.Left, views...))
+}
@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adelowo/sdump"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+func (m model) fetchQuota() tea.Msg {
+	var response struct {
+		Quota *sdump.Quota `json:"quota"`
+	}
+
+	err := m.doRequest(http.MethodGet, "/api/v1/users/me/quota", nil, &response)
+	return QuotaMsg{quota: response.Quota, err: err}
+}
+
+// quotaStatus describes what is left of the user's plan. Unlimited
+// resources are left out
+func (m model) quotaStatus() string {
+	if m.quota == nil {
+		return ""
+	}
+
+	var parts []string
+
+	if !m.quota.Endpoints.IsUnlimited() {
+		parts = append(parts, fmt.Sprintf("%d of %d endpoints left",
+			m.quota.Endpoints.Remaining, m.quota.Endpoints.Limit))
+	}
+
+	if !m.quota.RequestsToday.IsUnlimited() {
+		parts = append(parts, fmt.Sprintf("%d of %d requests left today",
+			m.quota.RequestsToday.Remaining, m.quota.RequestsToday.Limit))
+	}
+
+	if m.quota.RetentionDays > 0 {
+		parts = append(parts, fmt.Sprintf("requests kept for %d days", m.quota.RetentionDays))
+	}
+
+	if m.quota.MaxRequestBodySize > 0 {
+		parts = append(parts, fmt.Sprintf("bodies up to %s",
+			humanize.Bytes(uint64(m.quota.MaxRequestBodySize))))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	status := strings.Join(parts, " · ")
+	if m.quota.Plan != "" {
+		status = fmt.Sprintf("%s plan: %s", m.quota.Plan, status)
+	}
+
+	if !m.quota.RequestsToday.IsUnlimited() && m.quota.RequestsToday.Remaining <= 0 {
+		return errorStyle.Render(status + ". New requests are rejected until tomorrow")
+	}
+
+	return makeString(status, false)
+}
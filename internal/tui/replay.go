@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/internal/relay"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tunnelReplayTarget can be typed in the replay prompt to send the request
+// through the user's reverse SSH tunnel
+const tunnelReplayTarget = "tunnel"
+
+func (m model) newReplayPrompt(selectedItem item) prompt {
+	var target string
+	if selectedItem.Forward != nil {
+		target = selectedItem.Forward.URL
+	}
+
+	help := "Enter to send the request. Esc to cancel"
+	if m.tunnelURL != "" {
+		help = fmt.Sprintf("Enter to send the request. Type %q to send it through your SSH tunnel. Esc to cancel",
+			tunnelReplayTarget)
+	}
+
+	return newPrompt(fmt.Sprintf("Replay %s %s", selectedItem.Request.Method, selectedItem.Request.Path),
+		help, "https://example.com/webhooks", target, m.width)
+}
+
+// replay re-sends the stored request with its original method, headers,
+// query and body
+func (m model) replay(target string, req sdump.RequestDefinition) func() tea.Msg {
+	return func() tea.Msg {
+		client := m.replayClient
+
+		if target == tunnelReplayTarget {
+			target = m.tunnelURL
+			client = m.tunnelReplayClient
+		}
+
+		return ReplayMsg{result: relay.Do(context.Background(), client, target, req)}
+	}
+}
+
+// renderReplayResult shows the full response to a replayed request
+func (m model) renderReplayResult(f *sdump.ForwardResult) string {
+	return m.renderForwardResult("Replayed to "+f.URL, f, true)
+}
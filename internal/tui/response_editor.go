@@ -0,0 +1,226 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/internal/tmpl"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	focusStatusCode = iota
+	focusDelay
+	focusHeaders
+	focusBody
+	numberOfResponseEditorInputs
+)
+
+// responseEditor is a form used to configure the response an endpoint
+// sends back to ingested requests
+type responseEditor struct {
+	statusCode textinput.Model
+	delay      textinput.Model
+	headers    textarea.Model
+	body       textarea.Model
+
+	focused int
+	err     error
+}
+
+func newResponseEditor(width int, response *sdump.ResponseDefinition) responseEditor {
+	if response == nil {
+		response = &sdump.ResponseDefinition{
+			StatusCode: http.StatusAccepted,
+		}
+	}
+
+	statusCode := textinput.New()
+	statusCode.Placeholder = "202"
+	statusCode.CharLimit = 3
+	statusCode.SetValue(strconv.Itoa(response.StatusCode))
+
+	delay := textinput.New()
+	delay.Placeholder = "0"
+	delay.CharLimit = 5
+	delay.SetValue(strconv.FormatInt(response.DelayInMS, 10))
+
+	headers := textarea.New()
+	headers.Placeholder = "Content-Type: application/json"
+	headers.ShowLineNumbers = false
+	headers.SetWidth(width / 2)
+	headers.SetHeight(5)
+	headers.SetValue(formatHeaders(response.Headers))
+
+	body := textarea.New()
+	body.Placeholder = `{"message" : "ok"}`
+	body.ShowLineNumbers = false
+	body.SetWidth(width / 2)
+	body.SetHeight(10)
+	body.SetValue(response.Body)
+
+	r := responseEditor{
+		statusCode: statusCode,
+		delay:      delay,
+		headers:    headers,
+		body:       body,
+	}
+
+	r.focus()
+
+	return r
+}
+
+func formatHeaders(headers http.Header) string {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	s := new(strings.Builder)
+
+	for _, key := range keys {
+		for _, value := range headers[key] {
+			fmt.Fprintf(s, "%s: %s\n", key, value)
+		}
+	}
+
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+func parseHeaders(s string) (http.Header, error) {
+	headers := http.Header{}
+
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header ( %s ). Use the Key: Value format", line)
+		}
+
+		headers.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	return headers, nil
+}
+
+func (r *responseEditor) focus() {
+	r.statusCode.Blur()
+	r.delay.Blur()
+	r.headers.Blur()
+	r.body.Blur()
+
+	switch r.focused {
+	case focusStatusCode:
+		r.statusCode.Focus()
+	case focusDelay:
+		r.delay.Focus()
+	case focusHeaders:
+		r.headers.Focus()
+	case focusBody:
+		r.body.Focus()
+	}
+}
+
+// value builds the response definition from the form
+func (r responseEditor) value() (*sdump.ResponseDefinition, error) {
+	statusCode, err := strconv.Atoi(strings.TrimSpace(r.statusCode.Value()))
+	if err != nil {
+		return nil, fmt.Errorf("status code must be a number")
+	}
+
+	var delay int64
+	if v := strings.TrimSpace(r.delay.Value()); v != "" {
+		delay, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("delay must be a number of milliseconds")
+		}
+	}
+
+	headers, err := parseHeaders(r.headers.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &sdump.ResponseDefinition{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       r.body.Value(),
+		DelayInMS:  delay,
+	}
+
+	if err := response.Validate(); err != nil {
+		return nil, err
+	}
+
+	return response, tmpl.Validate(response.Body)
+}
+
+func (r responseEditor) Update(msg tea.Msg) (responseEditor, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyTab:
+			r.focused = (r.focused + 1) % numberOfResponseEditorInputs
+			r.focus()
+			return r, nil
+
+		case tea.KeyShiftTab:
+			r.focused = (r.focused + numberOfResponseEditorInputs - 1) % numberOfResponseEditorInputs
+			r.focus()
+			return r, nil
+		}
+	}
+
+	var cmd tea.Cmd
+
+	switch r.focused {
+	case focusStatusCode:
+		r.statusCode, cmd = r.statusCode.Update(msg)
+	case focusDelay:
+		r.delay, cmd = r.delay.Update(msg)
+	case focusHeaders:
+		r.headers, cmd = r.headers.Update(msg)
+	case focusBody:
+		r.body, cmd = r.body.Update(msg)
+	}
+
+	return r, cmd
+}
+
+func (r responseEditor) View() string {
+	views := []string{
+		boldenString("Configure the response sent back for ingested requests", true),
+		makeString("Tab/Shift-Tab to move between fields. Ctrl-s to save, Ctrl-d to reset to the default response, Esc to cancel", true),
+		"",
+		boldenString("Status code", false),
+		r.statusCode.View(),
+		"",
+		boldenString("Delay ( milliseconds )", false),
+		r.delay.View(),
+		"",
+		boldenString("Headers ( one Key: Value per line )", false),
+		r.headers.View(),
+		"",
+		boldenString("Body", false),
+		r.body.View(),
+	}
+
+	if r.err != nil {
+		views = append(views, "", errorStyle.Render(r.err.Error()))
+	}
+
+	return lipgloss.NewStyle().Margin(1, 4).
+		Render(lipgloss.JoinVertical(lipgloss.Left, views...))
+}
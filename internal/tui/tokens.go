@@ -0,0 +1,210 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/adelowo/sdump"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.design/x/clipboard"
+)
+
+// tokenManager lists the user's API tokens and lets them mint or revoke
+// tokens
+type tokenManager struct {
+	tokens []sdump.APIToken
+	cursor int
+
+	// secret is the plain text of a newly created token. It is only ever
+	// shown once
+	secret string
+
+	naming     bool
+	namePrompt prompt
+
+	loading bool
+	err     error
+}
+
+func newTokenManager() tokenManager {
+	return tokenManager{loading: true}
+}
+
+func (t tokenManager) selected() (sdump.APIToken, bool) {
+	if t.cursor < 0 || t.cursor >= len(t.tokens) {
+		return sdump.APIToken{}, false
+	}
+
+	return t.tokens[t.cursor], true
+}
+
+func (t tokenManager) Update(msg tea.Msg) (tokenManager, tea.Cmd) {
+	if t.naming {
+		var cmd tea.Cmd
+		t.namePrompt, cmd = t.namePrompt.Update(msg)
+		return t, cmd
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(t.tokens) == 0 {
+		return t, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		t.cursor = (t.cursor + len(t.tokens) - 1) % len(t.tokens)
+	case "down", "j":
+		t.cursor = (t.cursor + 1) % len(t.tokens)
+	}
+
+	return t, nil
+}
+
+func (t tokenManager) View() string {
+	if t.naming {
+		return t.namePrompt.View()
+	}
+
+	views := []string{
+		boldenString("API tokens", true),
+		makeString("Use j,k or arrow up and down to select a token. n to create a token, d to revoke the selected token, Esc to go back", true),
+		"",
+	}
+
+	if t.secret != "" {
+		views = append(views,
+			boldenString("Your new token has been copied to your clipboard. It will not be shown again", false),
+			t.secret,
+			"")
+	}
+
+	switch {
+	case t.loading:
+		views = append(views, makeString("Loading your tokens...", true))
+
+	case len(t.tokens) == 0:
+		views = append(views, makeString("You do not have any API tokens", true))
+	}
+
+	for i, token := range t.tokens {
+		line := fmt.Sprintf("%s   %s...   created %s", token.Name, token.Prefix,
+			token.CreatedAt.Format("02/01/2006 15:04:05"))
+
+		if i == t.cursor {
+			views = append(views, boldenString("> "+line, false))
+			continue
+		}
+
+		views = append(views, makeString("  "+line, false))
+	}
+
+	if t.err != nil {
+		views = append(views, "", errorStyle.Render(t.err.Error()))
+	}
+
+	return lipgloss.NewStyle().Margin(1, 4).
+		Render(lipgloss.JoinVertical(lipgloss.Left, views...))
+}
+
+func (m model) fetchTokens() tea.Msg {
+	var response struct {
+		Tokens []sdump.APIToken `json:"tokens"`
+	}
+
+	err := m.doRequest(http.MethodGet, "/api/v1/tokens", nil, &response)
+	return TokensMsg{tokens: response.Tokens, err: err}
+}
+
+func (m model) createToken(name string) func() tea.Msg {
+	return func() tea.Msg {
+		var response struct {
+			Secret string `json:"secret"`
+		}
+
+		err := m.doRequest(http.MethodPost, "/api/v1/tokens", map[string]string{
+			"name": name,
+		}, &response)
+		if err != nil {
+			return TokenSavedMsg{err: err}
+		}
+
+		return TokenSavedMsg{secret: response.Secret}
+	}
+}
+
+func (m model) revokeToken(token sdump.APIToken) func() tea.Msg {
+	return func() tea.Msg {
+		return TokenSavedMsg{err: m.doRequest(http.MethodDelete,
+			fmt.Sprintf("/api/v1/tokens/%s", token.ID), nil, nil)}
+	}
+}
+
+func (m model) updateTokenManager(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.Type == tea.KeyCtrlC {
+		return m, tea.Quit
+	}
+
+	if m.tokenManager.naming {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.tokenManager.naming = false
+			return m, nil
+
+		case tea.KeyEnter:
+			return m, m.createToken(m.tokenManager.namePrompt.value())
+		}
+
+		var cmd tea.Cmd
+		m.tokenManager, cmd = m.tokenManager.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.mode = viewRequests
+		return m, nil
+
+	case "n":
+		m.tokenManager.err = nil
+		m.tokenManager.naming = true
+		m.tokenManager.namePrompt = newPrompt("Create an API token",
+			"Enter to create the token. Esc to cancel", "ci", "", m.width)
+		return m, textinput.Blink
+
+	case "d":
+		token, ok := m.tokenManager.selected()
+		if !ok {
+			return m, nil
+		}
+
+		m.tokenManager.err = nil
+		m.tokenManager.secret = ""
+		return m, m.revokeToken(token)
+	}
+
+	var cmd tea.Cmd
+	m.tokenManager, cmd = m.tokenManager.Update(msg)
+	return m, cmd
+}
+
+func (m model) handleTokenSaved(msg TokenSavedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		if m.tokenManager.naming {
+			m.tokenManager.namePrompt.err = msg.err
+			return m, nil
+		}
+
+		m.tokenManager.err = msg.err
+		return m, nil
+	}
+
+	m.tokenManager.naming = false
+	m.tokenManager.secret = msg.secret
+	if msg.secret != "" {
+		_ = clipboard.Write(clipboard.FmtText, []byte(msg.secret))
+	}
+
+	return m, m.fetchTokens
+}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -14,24 +15,124 @@ type ErrorMsg struct {
 
 type DumpURLMsg struct {
 	URL        string `json:"url,omitempty"`
+	Reference  string `json:"reference,omitempty"`
+	Name       string `json:"name,omitempty"`
+	IsPaused   bool   `json:"is_paused,omitempty"`
 	SSEChannel string `json:"sse_channel,omitempty"`
+	SSEToken   string `json:"sse_token,omitempty"`
+}
+
+type ResponseMsg struct {
+	response *sdump.ResponseDefinition
+}
+
+type ResponseSavedMsg struct {
+	err error
+}
+
+type ForwardMsg struct {
+	forward *sdump.ForwardDefinition
+}
+
+type ForwardSavedMsg struct {
+	err error
+}
+
+type TunnelMsg struct {
+	url string
+}
+
+type TunnelSavedMsg struct {
+	err error
+}
+
+type ReplayMsg struct {
+	result *sdump.ForwardResult
+}
+
+type TokensMsg struct {
+	tokens []sdump.APIToken
+	err    error
+}
+
+type TokenSavedMsg struct {
+	secret string
+	err    error
+}
+
+type PauseSavedMsg struct {
+	paused bool
+	err    error
+}
+
+type EndpointsMsg struct {
+	endpoints []sdump.URLEndpoint
+	err       error
+}
+
+type EndpointSavedMsg struct {
+	err error
+}
+
+type QuotaMsg struct {
+	quota *sdump.Quota
+	err   error
+}
+
+type HistoryMsg struct {
+	reference  string
+	query      string
+	items      []item
+	nextCursor string
+	err        error
 }
 
 type ItemMsg struct {
 	item item
 }
 
+// StreamErrorMsg is received when the subscription to the endpoint's
+// requests could not be kept up. It is retried after a while
+type StreamErrorMsg struct {
+	reference string
+	err       error
+}
+
+// StreamConnectedMsg is received every time the subscription to the
+// endpoint's requests is established
+type StreamConnectedMsg struct{}
+
+type resubscribeMsg struct {
+	reference string
+
+	// ctx is the subscription that was lost. Nothing is done if the user
+	// has opened an endpoint since
+	ctx context.Context
+}
+
+// DroppedMsg is received when the endpoint turns a request away because it
+// was rate limited
+type DroppedMsg struct{}
+
 type item struct {
 	Request   sdump.RequestDefinition `json:"request,omitempty"`
+	Forward   *sdump.ForwardResult    `json:"forward,omitempty"`
+	Tunnel    *sdump.ForwardResult    `json:"tunnel,omitempty"`
 	ID        string                  `json:"id,omitempty"`
 	CreatedAt time.Time               `json:"created_at,omitempty"`
 }
 
 func (i item) Title() string { return fmt.Sprintf("%s    %s", i.ID, i.Request.IPAddress) }
 func (i item) Description() string {
-	return fmt.Sprintf("%s   %s    %s",
+	path := i.Request.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return fmt.Sprintf("%s %s   %s    %s",
 		defaultTextStyle.Copy().Foreground(faintBuleColor).
-			Render(i.Request.Method), humanize.Bytes(uint64(i.Request.Size)), i.CreatedAt.Format("02/01/2006 15:04:05"))
+			Render(i.Request.Method), path,
+		humanize.Bytes(uint64(i.Request.Size)), i.CreatedAt.Format("02/01/2006 15:04:05"))
 }
 func (i item) FilterValue() string { return i.ID }
 
@@ -0,0 +1,424 @@
+// Package tunnel delivers ingested requests to a user's machine through a
+// reverse SSH tunnel i.e ssh -R 0:localhost:3000.
+//
+// Instead of exposing raw TCP listeners, every tunnel is served by a small
+// HTTP reverse proxy that only accepts requests carrying the tunnel's secret
+// token as the first path segment. This makes sure a stale tunnel address
+// can never deliver requests to another user that gets the same port later on
+package tunnel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adelowo/sdump/config"
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const forwardedTCPChannelType = "forwarded-tcpip"
+
+type contextKey struct{}
+
+var tunnelContextKey = &contextKey{}
+
+var mutex sync.Mutex
+
+var (
+	errTooManyForwards = errors.New("too many tunnels are open on this connection")
+	errNoFreePort      = errors.New("no port is available for the tunnel")
+)
+
+// see RFC 4254, section 7.1
+type remoteForwardRequest struct {
+	BindAddr string
+	BindPort uint32
+}
+
+type remoteForwardSuccess struct {
+	BindPort uint32
+}
+
+type remoteForwardChannelData struct {
+	DestAddr   string
+	DestPort   uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+// Tunnel holds all the reverse port forwards requested on a single SSH
+// connection
+type Tunnel struct {
+	mu       sync.Mutex
+	token    string
+	host     string
+	forwards map[string]*forward
+	latest   string
+	url      string
+	updates  chan string
+	closed   bool
+
+	// onClose is called once the SSH connection goes away so whatever was
+	// pointed at the tunnel can be cleared
+	onClose map[string]func()
+}
+
+type forward struct {
+	server *http.Server
+	url    string
+}
+
+// FromContext returns the tunnel tied to the SSH connection. It is created if
+// it does not exist yet
+func FromContext(ctx ssh.Context) *Tunnel {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if t, ok := ctx.Value(tunnelContextKey).(*Tunnel); ok {
+		return t
+	}
+
+	t := &Tunnel{
+		token:    newToken(),
+		forwards: make(map[string]*forward),
+		updates:  make(chan string, 1),
+		onClose:  make(map[string]func()),
+	}
+
+	ctx.SetValue(tunnelContextKey, t)
+
+	go func() {
+		<-ctx.Done()
+		t.close()
+	}()
+
+	return t
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// URL is where the HTTP server should send requests that should be delivered
+// through the tunnel. It is empty if there are no active forwards
+func (t *Tunnel) URL() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.url
+}
+
+// OnClose calls fn once the SSH connection goes away. Calling it again with
+// the same key replaces the previous fn, a nil fn removes it
+func (t *Tunnel) OnClose(key string, fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if fn == nil {
+		delete(t.onClose, key)
+		return
+	}
+
+	t.onClose[key] = fn
+}
+
+func (t *Tunnel) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.forwards)
+}
+
+// Updates notifies when the tunnel's url changes. An empty string means the
+// tunnel is no longer available. The channel is closed once the SSH connection
+// goes away
+func (t *Tunnel) Updates() <-chan string { return t.updates }
+
+// setURL must be called with the lock held
+func (t *Tunnel) setURL(url string) {
+	if t.closed {
+		return
+	}
+
+	t.url = url
+
+	// only the most recent url matters
+	select {
+	case <-t.updates:
+	default:
+	}
+
+	t.updates <- url
+}
+
+func (t *Tunnel) add(addr string, f *forward) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		_ = f.server.Close()
+		return
+	}
+
+	t.forwards[addr] = f
+	t.latest = addr
+	t.setURL(f.url)
+}
+
+func (t *Tunnel) remove(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, ok := t.forwards[addr]
+	if !ok {
+		return
+	}
+
+	_ = f.server.Close()
+	delete(t.forwards, addr)
+
+	if t.latest != addr {
+		return
+	}
+
+	t.latest = ""
+	url := ""
+
+	for key, f := range t.forwards {
+		t.latest = key
+		url = f.url
+		break
+	}
+
+	t.setURL(url)
+}
+
+func (t *Tunnel) close() {
+	t.mu.Lock()
+
+	for addr, f := range t.forwards {
+		_ = f.server.Close()
+		delete(t.forwards, addr)
+	}
+
+	t.latest = ""
+	t.url = ""
+	t.closed = true
+	close(t.updates)
+
+	onClose := t.onClose
+	t.onClose = make(map[string]func())
+
+	t.mu.Unlock()
+
+	for _, fn := range onClose {
+		fn()
+	}
+}
+
+// Handler handles tcpip-forward and cancel-tcpip-forward requests
+type Handler struct {
+	cfg config.TunnelConfig
+}
+
+func NewHandler(cfg config.TunnelConfig) *Handler {
+	return &Handler{
+		cfg: cfg,
+	}
+}
+
+// Option registers the handler on the SSH server
+func (h *Handler) Option() ssh.Option {
+	return func(srv *ssh.Server) error {
+		if srv.RequestHandlers == nil {
+			srv.RequestHandlers = map[string]ssh.RequestHandler{}
+		}
+
+		srv.RequestHandlers["tcpip-forward"] = h.HandleSSHRequest
+		srv.RequestHandlers["cancel-tcpip-forward"] = h.HandleSSHRequest
+		return nil
+	}
+}
+
+func (h *Handler) HandleSSHRequest(ctx ssh.Context, _ *ssh.Server, req *gossh.Request) (bool, []byte) {
+	if !h.cfg.IsEnabled {
+		return false, []byte("tunnels are disabled")
+	}
+
+	var payload remoteForwardRequest
+	if err := gossh.Unmarshal(req.Payload, &payload); err != nil {
+		return false, []byte{}
+	}
+
+	addr := net.JoinHostPort(payload.BindAddr, strconv.Itoa(int(payload.BindPort)))
+
+	t := FromContext(ctx)
+
+	switch req.Type {
+	case "tcpip-forward":
+		conn, ok := ctx.Value(ssh.ContextKeyConn).(*gossh.ServerConn)
+		if !ok {
+			return false, []byte{}
+		}
+
+		// requests on a connection are handled one after the other so
+		// this cannot race with another forward being opened
+		if t.count() >= h.cfg.ForwardsPerConnection() {
+			return false, []byte(errTooManyForwards.Error())
+		}
+
+		port, err := h.listen(t, conn, payload)
+		if err != nil {
+			return false, []byte(err.Error())
+		}
+
+		return true, gossh.Marshal(&remoteForwardSuccess{BindPort: port})
+
+	case "cancel-tcpip-forward":
+		t.remove(addr)
+		return true, nil
+
+	default:
+		return false, nil
+	}
+}
+
+func (h *Handler) listen(t *Tunnel, conn *gossh.ServerConn,
+	payload remoteForwardRequest,
+) (uint32, error) {
+	ln, err := h.listenInRange()
+	if err != nil {
+		return 0, err
+	}
+
+	_, listenPortStr, _ := net.SplitHostPort(ln.Addr().String())
+
+	// The client expects to see the port it asked for in the channels
+	// we open. If it asked for any port, give it the one we are listening on
+	port := payload.BindPort
+	if port == 0 {
+		listenPort, _ := strconv.Atoi(listenPortStr)
+		port = uint32(listenPort)
+	}
+
+	// clients cancel the forward with the port they were given
+	addr := net.JoinHostPort(payload.BindAddr, strconv.Itoa(int(port)))
+
+	// requests are proxied so there is no meaningful origin. Clients reject
+	// port 0 though
+	channelData := gossh.Marshal(&remoteForwardChannelData{
+		DestAddr:   payload.BindAddr,
+		DestPort:   port,
+		OriginAddr: h.cfg.BindHost,
+		OriginPort: port,
+	})
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.URL.Scheme = "http"
+			r.URL.Host = "localhost"
+			r.Host = "localhost"
+		},
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				ch, reqs, err := conn.OpenChannel(forwardedTCPChannelType, channelData)
+				if err != nil {
+					return nil, err
+				}
+
+				go gossh.DiscardRequests(reqs)
+
+				return &channelConn{Channel: ch}, nil
+			},
+			DisableKeepAlives: true,
+		},
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			http.Error(w, fmt.Sprintf("could not deliver request through the tunnel... %v", err),
+				http.StatusBadGateway)
+		},
+	}
+
+	server := &http.Server{
+		Handler:           authorize(t.token, proxy),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.remove(addr)
+		}
+	}()
+
+	t.add(addr, &forward{
+		server: server,
+		url:    fmt.Sprintf("http://%s/%s", net.JoinHostPort(h.cfg.AdvertiseHost, listenPortStr), t.token),
+	})
+
+	return port, nil
+}
+
+// listenInRange opens a listener on a free port within the configured range,
+// starting from a random one
+func (h *Handler) listenInRange() (net.Listener, error) {
+	minPort, maxPort := h.cfg.PortRange()
+	size := maxPort - minPort + 1
+
+	offset := mathrand.Intn(size)
+
+	for i := 0; i < size; i++ {
+		port := minPort + (offset+i)%size
+
+		ln, err := net.Listen("tcp", net.JoinHostPort(h.cfg.BindHost, strconv.Itoa(port)))
+		if err == nil {
+			return ln, nil
+		}
+	}
+
+	return nil, errNoFreePort
+}
+
+// authorize makes sure only requests sent to /token/... are delivered. The
+// token is stripped from the path before the request is sent to the user
+func authorize(token string, next http.Handler) http.Handler {
+	prefix := "/" + token
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != prefix && !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		r.URL.RawPath = ""
+		if r.URL.Path == "" {
+			r.URL.Path = "/"
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// channelConn allows an SSH channel to be used as a net.Conn
+type channelConn struct {
+	gossh.Channel
+}
+
+func (c *channelConn) LocalAddr() net.Addr  { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (c *channelConn) RemoteAddr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+
+func (c *channelConn) SetDeadline(_ time.Time) error      { return nil }
+func (c *channelConn) SetReadDeadline(_ time.Time) error  { return nil }
+func (c *channelConn) SetWriteDeadline(_ time.Time) error { return nil }
@@ -0,0 +1,145 @@
+package tunnel
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump/config"
+	"github.com/charmbracelet/ssh"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func startServer(t *testing.T, tunnels chan<- *Tunnel) string {
+	t.Helper()
+
+	handler := NewHandler(config.TunnelConfig{
+		IsEnabled:     true,
+		BindHost:      "127.0.0.1",
+		AdvertiseHost: "127.0.0.1",
+		MinPort:       41000,
+		MaxPort:       41099,
+		MaxForwards:   2,
+	})
+
+	srv := &ssh.Server{
+		Handler: func(s ssh.Session) {
+			tunnels <- FromContext(s.Context())
+			<-s.Context().Done()
+		},
+	}
+
+	require.NoError(t, handler.Option()(srv))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = srv.Serve(ln) }()
+
+	t.Cleanup(func() { _ = srv.Close() })
+
+	return ln.Addr().String()
+}
+
+func TestTunnel(t *testing.T) {
+	tunnels := make(chan *Tunnel, 1)
+
+	addr := startServer(t, tunnels)
+
+	client, err := gossh.Dial("tcp", addr, &gossh.ClientConfig{
+		User:            "sdump",
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+	})
+	require.NoError(t, err)
+
+	// ssh -R 0:localhost:3000
+	ln, err := client.Listen("tcp", "0.0.0.0:0")
+	require.NoError(t, err)
+
+	go func() {
+		_ = http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, r.Method+" "+r.URL.RequestURI())
+		}))
+	}()
+
+	session, err := client.NewSession()
+	require.NoError(t, err)
+
+	require.NoError(t, session.Shell())
+
+	var tunnel *Tunnel
+
+	select {
+	case tunnel = <-tunnels:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session was not started")
+	}
+
+	url := <-tunnel.Updates()
+	require.True(t, strings.HasPrefix(url, "http://127.0.0.1:410"))
+	require.Equal(t, url, tunnel.URL())
+
+	closed := make(chan struct{})
+	tunnel.OnClose("cmltfm6g330l5l1vq110", func() { close(closed) })
+
+	t.Run("request is delivered through the tunnel", func(t *testing.T) {
+		resp, err := http.Post(url+"/hooks?id=1", "application/json", strings.NewReader("{}"))
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		require.Equal(t, "POST /hooks?id=1", string(body), string(body))
+	})
+
+	t.Run("request without the tunnel token is rejected", func(t *testing.T) {
+		resp, err := http.Get(url[:strings.LastIndex(url, "/")] + "/hooks")
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("forwards are limited per connection", func(t *testing.T) {
+		second, err := client.Listen("tcp", "0.0.0.0:0")
+		require.NoError(t, err)
+
+		defer second.Close()
+
+		_, err = client.Listen("tcp", "0.0.0.0:0")
+		require.Error(t, err)
+	})
+
+	t.Run("tunnel is closed with the connection", func(t *testing.T) {
+		require.NoError(t, client.Close())
+
+		// the forwards opened above may still be reported first
+		for open := true; open; {
+			select {
+			case _, open = <-tunnel.Updates():
+			case <-time.After(5 * time.Second):
+				t.Fatal("tunnel was not closed")
+			}
+		}
+
+		require.Empty(t, tunnel.URL())
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("close callbacks were not called")
+		}
+
+		_, err := http.Get(url)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,11 @@
+package sdump
+
+import "context"
+
+// Locker hands out locks that are held by a single process at a time
+// across every server sharing the database
+type Locker interface {
+	// TryLock returns false if another process holds the lock. Otherwise
+	// the returned function has to be called to release it
+	TryLock(ctx context.Context, name string) (func(), bool, error)
+}
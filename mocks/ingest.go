@@ -40,6 +40,21 @@ func (m *MockIngestRepository) EXPECT() *MockIngestRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *MockIngestRepository) Count(arg0 context.Context, arg1 *sdump.CountIngestedRequestOptions) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockIngestRepositoryMockRecorder) Count(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockIngestRepository)(nil).Count), arg0, arg1)
+}
+
 // Create mocks base method.
 func (m *MockIngestRepository) Create(arg0 context.Context, arg1 *sdump.IngestHTTPRequest) error {
 	m.ctrl.T.Helper()
@@ -55,11 +70,12 @@ func (mr *MockIngestRepositoryMockRecorder) Create(arg0, arg1 any) *gomock.Call
 }
 
 // Delete mocks base method.
-func (m *MockIngestRepository) Delete(arg0 context.Context, arg1 *sdump.DeleteIngestedRequestOptions) error {
+func (m *MockIngestRepository) Delete(arg0 context.Context, arg1 *sdump.DeleteIngestedRequestOptions) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
@@ -67,3 +83,77 @@ func (mr *MockIngestRepositoryMockRecorder) Delete(arg0, arg1 any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockIngestRepository)(nil).Delete), arg0, arg1)
 }
+
+// Get mocks base method.
+func (m *MockIngestRepository) Get(arg0 context.Context, arg1 *sdump.FindIngestedRequestOptions) (*sdump.IngestHTTPRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*sdump.IngestHTTPRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockIngestRepositoryMockRecorder) Get(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockIngestRepository)(nil).Get), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockIngestRepository) List(arg0 context.Context, arg1 *sdump.ListIngestedRequestOptions) ([]sdump.IngestHTTPRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]sdump.IngestHTTPRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockIngestRepositoryMockRecorder) List(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockIngestRepository)(nil).List), arg0, arg1)
+}
+
+// Purge mocks base method.
+func (m *MockIngestRepository) Purge(arg0 context.Context, arg1 *sdump.PurgeIngestedRequestOptions) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockIngestRepositoryMockRecorder) Purge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockIngestRepository)(nil).Purge), arg0, arg1)
+}
+
+// Search mocks base method.
+func (m *MockIngestRepository) Search(arg0 context.Context, arg1 *sdump.SearchIngestedRequestOptions) ([]sdump.IngestHTTPRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1)
+	ret0, _ := ret[0].([]sdump.IngestHTTPRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockIngestRepositoryMockRecorder) Search(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockIngestRepository)(nil).Search), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockIngestRepository) Update(arg0 context.Context, arg1 *sdump.IngestHTTPRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockIngestRepositoryMockRecorder) Update(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockIngestRepository)(nil).Update), arg0, arg1)
+}
@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: plan.go
+//
+// Generated by this command:
+//
+//	mockgen --source plan.go -destination mocks/plan.go -package mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	sdump "github.com/adelowo/sdump"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPlanRepository is a mock of PlanRepository interface.
+type MockPlanRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPlanRepositoryMockRecorder
+}
+
+// MockPlanRepositoryMockRecorder is the mock recorder for MockPlanRepository.
+type MockPlanRepositoryMockRecorder struct {
+	mock *MockPlanRepository
+}
+
+// NewMockPlanRepository creates a new mock instance.
+func NewMockPlanRepository(ctrl *gomock.Controller) *MockPlanRepository {
+	mock := &MockPlanRepository{ctrl: ctrl}
+	mock.recorder = &MockPlanRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPlanRepository) EXPECT() *MockPlanRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockPlanRepository) Create(arg0 context.Context, arg1 *sdump.Plan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockPlanRepositoryMockRecorder) Create(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPlanRepository)(nil).Create), arg0, arg1)
+}
+
+// Find mocks base method.
+func (m *MockPlanRepository) Find(arg0 context.Context, arg1 *sdump.FindPlanOptions) (*sdump.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].(*sdump.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockPlanRepositoryMockRecorder) Find(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockPlanRepository)(nil).Find), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockPlanRepository) List(arg0 context.Context) ([]sdump.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]sdump.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockPlanRepositoryMockRecorder) List(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockPlanRepository)(nil).List), arg0)
+}
+
+// Update mocks base method.
+func (m *MockPlanRepository) Update(arg0 context.Context, arg1 *sdump.Plan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockPlanRepositoryMockRecorder) Update(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockPlanRepository)(nil).Update), arg0, arg1)
+}
@@ -55,6 +55,34 @@ func (mr *MockURLRepositoryMockRecorder) Create(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockURLRepository)(nil).Create), arg0, arg1)
 }
 
+// Deactivate mocks base method.
+func (m *MockURLRepository) Deactivate(arg0 context.Context, arg1 *sdump.URLEndpoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deactivate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Deactivate indicates an expected call of Deactivate.
+func (mr *MockURLRepositoryMockRecorder) Deactivate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deactivate", reflect.TypeOf((*MockURLRepository)(nil).Deactivate), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockURLRepository) Delete(arg0 context.Context, arg1 *sdump.URLEndpoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockURLRepositoryMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockURLRepository)(nil).Delete), arg0, arg1)
+}
+
 // Get mocks base method.
 func (m *MockURLRepository) Get(arg0 context.Context, arg1 *sdump.FindURLOptions) (*sdump.URLEndpoint, error) {
 	m.ctrl.T.Helper()
@@ -84,3 +112,46 @@ func (mr *MockURLRepositoryMockRecorder) Latest(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Latest", reflect.TypeOf((*MockURLRepository)(nil).Latest), arg0, arg1)
 }
+
+// List mocks base method.
+func (m *MockURLRepository) List(arg0 context.Context, arg1 *sdump.ListURLOptions) ([]sdump.URLEndpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]sdump.URLEndpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockURLRepositoryMockRecorder) List(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockURLRepository)(nil).List), arg0, arg1)
+}
+
+// Rename mocks base method.
+func (m *MockURLRepository) Rename(arg0 context.Context, arg1 *sdump.URLEndpoint, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rename", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rename indicates an expected call of Rename.
+func (mr *MockURLRepositoryMockRecorder) Rename(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rename", reflect.TypeOf((*MockURLRepository)(nil).Rename), arg0, arg1, arg2)
+}
+
+// Update mocks base method.
+func (m *MockURLRepository) Update(arg0 context.Context, arg1 *sdump.URLEndpoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockURLRepositoryMockRecorder) Update(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockURLRepository)(nil).Update), arg0, arg1)
+}
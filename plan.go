@@ -0,0 +1,180 @@
+package sdump
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Plan is the set of limits a user's account is subject to. A zero limit
+// means the plan does not restrict that resource
+type Plan struct {
+	ID   uuid.UUID `bun:"type:uuid,pk,default:uuid_generate_v4()" json:"id,omitempty"`
+	Name string    `json:"name,omitempty"`
+
+	MaxEndpoints      int64 `json:"max_endpoints"`
+	MaxRequestsPerDay int64 `json:"max_requests_per_day"`
+
+	// RetentionDays is how long ingested requests are kept before the
+	// HTTP server prunes them
+	RetentionDays int64 `json:"retention_days"`
+
+	// MaxRequestBodySize can only lower the instance wide
+	// http.max_request_body_size, never raise it
+	MaxRequestBodySize int64 `json:"max_request_body_size"`
+
+	// IngestRequestsPerMinute and APIRequestsPerMinute replace the instance
+	// wide http.rate_limit limits for users on this plan
+	IngestRequestsPerMinute int64 `json:"ingest_requests_per_minute"`
+	APIRequestsPerMinute    int64 `json:"api_requests_per_minute"`
+
+	// IsDefault marks the plan of users that have not been assigned one
+	IsDefault bool `json:"is_default,omitempty"`
+
+	CreatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"created_at,omitempty" bson:"created_at"`
+	UpdatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"updated_at,omitempty" bson:"updated_at"`
+	DeletedAt *time.Time `bun:",soft_delete,nullzero" json:"-,omitempty" bson:"deleted_at"`
+
+	bun.BaseModel `bun:"table:plans"`
+}
+
+func (p *Plan) Retention() time.Duration {
+	return time.Duration(p.RetentionDays) * 24 * time.Hour
+}
+
+// RequestBodySize is the largest request body an endpoint on this plan can
+// ingest given the instance wide limit
+func (p *Plan) RequestBodySize(instanceLimit int64) int64 {
+	if p.MaxRequestBodySize <= 0 || p.MaxRequestBodySize > instanceLimit {
+		return instanceLimit
+	}
+
+	return p.MaxRequestBodySize
+}
+
+// IngestRateLimit is how many requests per minute the endpoints of a user on
+// this plan can ingest. The user's own limit takes precedence over the plan's
+func (p *Plan) IngestRateLimit(user *User, instanceLimit uint64) uint64 {
+	return rateLimit(instanceLimit, p.IngestRequestsPerMinute, user.ingestRequestsPerMinute())
+}
+
+// APIRateLimit is how many requests per minute a user on this plan can make
+// to the HTTP API
+func (p *Plan) APIRateLimit(user *User, instanceLimit uint64) uint64 {
+	return rateLimit(instanceLimit, p.APIRequestsPerMinute, user.apiRequestsPerMinute())
+}
+
+// rateLimit picks the limit that applies to a user. Plans and users can only
+// change a limit that is enabled instance wide
+func rateLimit(instanceLimit uint64, planLimit, userLimit int64) uint64 {
+	switch {
+	case instanceLimit == 0:
+		return 0
+	case userLimit > 0:
+		return uint64(userLimit)
+	case planLimit > 0:
+		return uint64(planLimit)
+	default:
+		return instanceLimit
+	}
+}
+
+// Usage is how much of a plan a user has consumed
+type Usage struct {
+	Endpoints     int64
+	RequestsToday int64
+}
+
+// QuotaLimit is a single limit of a plan and how much of it is left
+type QuotaLimit struct {
+	// Limit is zero when the plan does not restrict the resource
+	Limit     int64   `json:"limit"`
+	Used      int64   `json:"used"`
+	Remaining Counter `json:"remaining"`
+}
+
+func newQuotaLimit(limit, used int64) QuotaLimit {
+	q := QuotaLimit{
+		Limit: limit,
+		Used:  used,
+	}
+
+	if limit > used {
+		q.Remaining = Counter(limit - used)
+	}
+
+	return q
+}
+
+func (q *QuotaLimit) IsUnlimited() bool { return q.Limit <= 0 }
+
+// MarshalJSON leaves out remaining for limits the plan does not restrict
+// so clients do not read it as nothing being left
+func (q QuotaLimit) MarshalJSON() ([]byte, error) {
+	if !q.IsUnlimited() {
+		type quotaLimit QuotaLimit
+		return json.Marshal(quotaLimit(q))
+	}
+
+	return json.Marshal(struct {
+		Limit     int64 `json:"limit"`
+		Used      int64 `json:"used"`
+		Unlimited bool  `json:"unlimited"`
+	}{
+		Limit:     q.Limit,
+		Used:      q.Used,
+		Unlimited: true,
+	})
+}
+
+// Take uses up one unit of the limit. ErrCounterExhausted is returned
+// once the limit has been reached
+func (q *QuotaLimit) Take() error {
+	if q.IsUnlimited() {
+		return nil
+	}
+
+	if err := q.Remaining.Take(); err != nil {
+		return err
+	}
+
+	q.Used++
+	return nil
+}
+
+// Quota is what is left of a user's plan
+type Quota struct {
+	Plan          string     `json:"plan"`
+	Endpoints     QuotaLimit `json:"endpoints"`
+	RequestsToday QuotaLimit `json:"requests_today"`
+
+	RetentionDays      int64 `json:"retention_days"`
+	MaxRequestBodySize int64 `json:"max_request_body_size"`
+}
+
+func (p *Plan) Quota(usage Usage) *Quota {
+	return &Quota{
+		Plan:               p.Name,
+		Endpoints:          newQuotaLimit(p.MaxEndpoints, usage.Endpoints),
+		RequestsToday:      newQuotaLimit(p.MaxRequestsPerDay, usage.RequestsToday),
+		RetentionDays:      p.RetentionDays,
+		MaxRequestBodySize: p.MaxRequestBodySize,
+	}
+}
+
+type FindPlanOptions struct {
+	// ID of the plan. If empty and Name is not provided, the default
+	// plan is returned
+	ID   uuid.UUID
+	Name string
+}
+
+type PlanRepository interface {
+	Create(context.Context, *Plan) error
+	Update(context.Context, *Plan) error
+	Find(context.Context, *FindPlanOptions) (*Plan, error)
+	List(context.Context) ([]Plan, error)
+}
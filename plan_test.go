@@ -0,0 +1,138 @@
+package sdump
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaLimit_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(newQuotaLimit(10, 4))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"limit":10,"used":4,"remaining":6}`, string(b))
+
+	b, err = json.Marshal(newQuotaLimit(0, 4))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"limit":0,"used":4,"unlimited":true}`, string(b))
+}
+
+func TestQuotaLimit_Take(t *testing.T) {
+	tt := []struct {
+		name              string
+		limit             int64
+		used              int64
+		expectedRemaining Counter
+		hasError          bool
+	}{
+		{
+			name:              "limit not reached",
+			limit:             10,
+			used:              4,
+			expectedRemaining: 5,
+		},
+		{
+			name:              "limit reached",
+			limit:             10,
+			used:              10,
+			expectedRemaining: 0,
+			hasError:          true,
+		},
+		{
+			name:              "usage above a lowered limit",
+			limit:             10,
+			used:              12,
+			expectedRemaining: 0,
+			hasError:          true,
+		},
+		{
+			name:              "unlimited",
+			limit:             0,
+			used:              1000,
+			expectedRemaining: 0,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			plan := &Plan{MaxRequestsPerDay: v.limit}
+
+			limit := plan.Quota(Usage{RequestsToday: v.used}).RequestsToday
+
+			err := limit.Take()
+			if v.hasError {
+				require.ErrorIs(t, err, ErrCounterExhausted)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, v.expectedRemaining, limit.Remaining)
+		})
+	}
+}
+
+func TestPlan_RequestBodySize(t *testing.T) {
+	require.Equal(t, int64(100), (&Plan{}).RequestBodySize(100))
+	require.Equal(t, int64(50), (&Plan{MaxRequestBodySize: 50}).RequestBodySize(100))
+
+	// the instance wide limit cannot be raised by a plan
+	require.Equal(t, int64(100), (&Plan{MaxRequestBodySize: 500}).RequestBodySize(100))
+}
+
+func TestPlan_IngestRateLimit(t *testing.T) {
+	tt := []struct {
+		name     string
+		plan     *Plan
+		user     *User
+		instance uint64
+		expected uint64
+	}{
+		{
+			name:     "instance limit",
+			plan:     &Plan{},
+			user:     &User{},
+			instance: 60,
+			expected: 60,
+		},
+		{
+			name:     "no owner",
+			plan:     &Plan{},
+			instance: 60,
+			expected: 60,
+		},
+		{
+			name:     "plan limit",
+			plan:     &Plan{IngestRequestsPerMinute: 600},
+			user:     &User{},
+			instance: 60,
+			expected: 600,
+		},
+		{
+			name:     "user limit takes precedence over the plan",
+			plan:     &Plan{IngestRequestsPerMinute: 600},
+			user:     &User{IngestRequestsPerMinute: 5},
+			instance: 60,
+			expected: 5,
+		},
+		{
+			name:     "instance limit disabled",
+			plan:     &Plan{IngestRequestsPerMinute: 600},
+			user:     &User{IngestRequestsPerMinute: 5},
+			instance: 0,
+			expected: 0,
+		},
+		{
+			name:     "api limit is not used",
+			plan:     &Plan{APIRequestsPerMinute: 600},
+			user:     &User{APIRequestsPerMinute: 5},
+			instance: 60,
+			expected: 60,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			require.Equal(t, v.expected, v.plan.IngestRateLimit(v.user, v.instance))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package sdump
+
+import "context"
+
+// EventTypeDropped is published when an endpoint turns a request away
+// because it was rate limited
+const EventTypeDropped = "dropped"
+
+// Event is published every time an endpoint ingests a request so it can be
+// streamed to the TUI sessions subscribed to the endpoint, regardless of
+// which HTTP server they are connected to
+type Event struct {
+	// Channel is the PubChannel of the endpoint that ingested the request
+	Channel string `json:"channel"`
+
+	// Type is empty for ingested requests
+	Type string `json:"type,omitempty"`
+
+	// ID is the ID of the ingested request
+	ID string `json:"id"`
+
+	// Data is sent as is to the channel's subscribers. It is empty if it
+	// was too large for the pub/sub backend, in which case subscribers
+	// have to load the ingested request by ID
+	Data []byte `json:"data,omitempty"`
+}
+
+type Publisher interface {
+	Publish(context.Context, *Event) error
+}
+
+type Subscriber interface {
+	// Subscribe calls fn with every published event until the context
+	// is cancelled
+	Subscribe(ctx context.Context, fn func(*Event)) error
+}
+
+type PubSub interface {
+	Publisher
+	Subscriber
+}
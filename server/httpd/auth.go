@@ -0,0 +1,310 @@
+package httpd
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/internal/signature"
+	"github.com/adelowo/sdump/internal/util"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// sshFingerprintHeader identifies the user the SSH server is making
+	// requests on behalf of. It is only trusted alongside the admin secret
+	sshFingerprintHeader = "X-SSH-Fingerprint"
+
+	// adminSecretHeader carries HTTPConfig.AdminSecret. The SSH server signs
+	// its requests instead so the secret is never sent over the wire
+	adminSecretHeader = "X-Admin-Secret"
+
+	// streamTokenHeader carries the token returned when an endpoint is
+	// created. It can also be sent as the token query parameter since
+	// browsers cannot set headers on EventSource requests
+	streamTokenHeader = "X-Stream-Token"
+
+	// streamTokenTTL is how long a stream token can be used to subscribe to
+	// an endpoint's requests. It is only checked when subscribing
+	streamTokenTTL = 5 * time.Minute
+)
+
+// isAdmin checks the request either carries the admin secret or was signed
+// with it by the SSH server. An empty secret never matches
+func (u *urlHandler) isAdmin(r *http.Request) bool {
+	if util.IsStringEmpty(u.cfg.HTTP.AdminSecret) {
+		return false
+	}
+
+	if r.Header.Get(signature.SignatureHeader) != "" {
+		return signature.Verify(r, u.cfg.HTTP.AdminSecret, time.Now()) == nil
+	}
+
+	secret := r.Header.Get(adminSecretHeader)
+	if util.IsStringEmpty(secret) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(u.cfg.HTTP.AdminSecret)) == 1
+}
+
+// requireAdmin protects routes that should only be called by an admin or
+// the SSH server
+func (u *urlHandler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span, _ := getTracer(r.Context(), r, "url.requireAdmin")
+		defer span.End()
+
+		if !u.isAdmin(r) {
+			span.SetStatus(codes.Error, "invalid admin secret")
+			_ = render.Render(w, r, newAPIError(http.StatusUnauthorized, "you are not allowed to access this resource"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireUser identifies the user making the request either from an API
+// token or the SSH server acting on their behalf
+func (u *urlHandler) requireUser(next http.Handler) http.Handler {
+	return u.authenticateUser(true)(next)
+}
+
+// requireSSHUser only allows requests the SSH server makes on behalf of a
+// connected user. It protects actions that should only be carried out from
+// the SSH session such as minting API tokens
+func (u *urlHandler) requireSSHUser(next http.Handler) http.Handler {
+	return u.authenticateUser(false)(next)
+}
+
+// requireSSHSession only allows requests the SSH server makes itself, even
+// after the user has been identified. Tunnel listeners only exist on the SSH
+// server so it is the only one that can say where they are
+func (u *urlHandler) requireSSHSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span, _ := getTracer(r.Context(), r, "url.requireSSHSession")
+		defer span.End()
+
+		if _, hasToken := bearerToken(r); hasToken || !u.isAdmin(r) {
+			span.SetStatus(codes.Error, "request was not made by the SSH server")
+			_ = render.Render(w, r, newAPIError(http.StatusForbidden,
+				"this can only be done from your SSH session"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (u *urlHandler) authenticateUser(allowAPITokens bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span, requestID := getTracer(r.Context(), r, "url.requireUser")
+			defer span.End()
+
+			logger := u.logger.WithField("request_id", requestID).
+				WithField("method", "urlHandler.requireUser")
+
+			token, hasToken := bearerToken(r)
+
+			var user *sdump.User
+			var err error
+
+			switch {
+			case hasToken && allowAPITokens:
+				user, err = u.findUserByAPIToken(ctx, token)
+				if errors.Is(err, sdump.ErrAPITokenNotFound) || errors.Is(err, sdump.ErrUserNotFound) {
+					span.SetStatus(codes.Error, "invalid api token")
+					_ = render.Render(w, r, newAPIError(http.StatusUnauthorized, "invalid api token"))
+					return
+				}
+
+			case u.isAdmin(r):
+				fingerprint := r.Header.Get(sshFingerprintHeader)
+				if util.IsStringEmpty(fingerprint) {
+					span.SetStatus(codes.Error, "please provide ssh fingerprint")
+					_ = render.Render(w, r, newAPIError(http.StatusUnauthorized, "please provide your ssh fingerprint"))
+					return
+				}
+
+				user, err = u.userRepo.Find(ctx, &sdump.FindUserOptions{
+					SSHKeyFingerprint: fingerprint,
+				})
+				if errors.Is(err, sdump.ErrUserNotFound) {
+					span.SetStatus(codes.Error, "user not found")
+					_ = render.Render(w, r, newAPIError(http.StatusNotFound, "Dump url does not exist"))
+					return
+				}
+
+			default:
+				span.SetStatus(codes.Error, "no credentials provided")
+				_ = render.Render(w, r, newAPIError(http.StatusUnauthorized, "please provide an api token"))
+				return
+			}
+
+			if err != nil {
+				logger.WithError(err).Error("could not find user from database")
+				span.SetStatus(codes.Error, "could not find user from database")
+				_ = render.Render(w, r, newAPIError(http.StatusInternalServerError, "could not find user from database"))
+				return
+			}
+
+			if user.IsBanned {
+				span.SetStatus(codes.Error, "user is banned")
+				_ = render.Render(w, r, newBannedUserError(user))
+				return
+			}
+
+			isRateLimited, err := u.takeAPIRateLimit(w, r, user)
+			if err != nil {
+				logger.WithError(err).Error("could not check rate limit")
+				span.SetStatus(codes.Error, "could not check rate limit")
+				_ = render.Render(w, r, newAPIError(http.StatusInternalServerError, "could not check rate limit"))
+				return
+			}
+
+			if isRateLimited {
+				span.SetStatus(codes.Error, "rate limited")
+				_ = render.Render(w, r, newRateLimitError())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userCtxKey, user)))
+		})
+	}
+}
+
+// takeAPIRateLimit counts the request against the user's API rate limit. The
+// plan is only looked up when the limit is enabled
+func (u *urlHandler) takeAPIRateLimit(w http.ResponseWriter, r *http.Request,
+	user *sdump.User,
+) (bool, error) {
+	if u.cfg.HTTP.RateLimit.API.RequestsPerMinute == 0 {
+		return false, nil
+	}
+
+	plan, err := u.userPlan(r.Context(), user)
+	if err != nil {
+		return false, err
+	}
+
+	return u.takeRateLimit(w, r, rateLimitScopeAPI,
+		plan.APIRateLimit(user, u.cfg.HTTP.RateLimit.API.RequestsPerMinute),
+		chi.URLParam(r, "reference"), user.ID)
+}
+
+// requireStreamSubscriber only lets the owner of an endpoint subscribe to its
+// stream of ingested requests. Subscriptions are rejected before they are
+// attached to the stream
+func (u *urlHandler) requireStreamSubscriber(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span, requestID := getTracer(r.Context(), r, "url.requireStreamSubscriber")
+		defer span.End()
+
+		channel := r.URL.Query().Get("stream")
+
+		logger := u.logger.WithField("request_id", requestID).
+			WithField("method", "urlHandler.requireStreamSubscriber").
+			WithField("stream", channel)
+
+		token := r.Header.Get(streamTokenHeader)
+		if util.IsStringEmpty(token) {
+			token = r.URL.Query().Get("token")
+		}
+
+		if util.IsStringEmpty(token) {
+			span.SetStatus(codes.Error, "no stream token provided")
+			_ = render.Render(w, r, newAPIError(http.StatusUnauthorized, "please provide a stream token"))
+			return
+		}
+
+		claims, err := signature.VerifyToken(u.cfg.HTTP.AdminSecret, token, time.Now())
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid stream token")
+			_ = render.Render(w, r, newAPIError(http.StatusUnauthorized, "invalid or expired stream token"))
+			return
+		}
+
+		reference, found := strings.CutPrefix(channel, "messages.")
+		if !found || claims.Audience != channel {
+			span.SetStatus(codes.Error, "stream token not valid for stream")
+			_ = render.Render(w, r, newAPIError(http.StatusForbidden, "stream token is not valid for this stream"))
+			return
+		}
+
+		endpoint, err := u.urlRepo.Get(ctx, &sdump.FindURLOptions{
+			Reference: reference,
+		})
+		if errors.Is(err, sdump.ErrURLEndpointNotFound) {
+			span.SetStatus(codes.Error, "url not found")
+			_ = render.Render(w, r, newAPIError(http.StatusNotFound, "Dump url does not exist"))
+			return
+		}
+
+		if err != nil {
+			logger.WithError(err).Error("could not find dump url by reference")
+			span.SetStatus(codes.Error, "could not find dump url by reference")
+			_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+				"an error occurred while fetching url"))
+			return
+		}
+
+		// tokens are only valid for the user they were issued to
+		if endpoint.UserID.String() != claims.Subject {
+			span.SetStatus(codes.Error, "url not owned by token subject")
+			_ = render.Render(w, r, newAPIError(http.StatusNotFound, "Dump url does not exist"))
+			return
+		}
+
+		// the stream token could have been issued by another HTTP server
+		if !u.sseServer.StreamExists(channel) {
+			_ = u.sseServer.CreateStream(channel)
+		}
+
+		span.SetStatus(codes.Ok, "stream subscription authorized")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newBannedUserError tells the user why they can no longer use the service
+func newBannedUserError(user *sdump.User) APIError {
+	msg := sdump.ErrUserBanned.Error()
+	if !util.IsStringEmpty(user.BanReason) {
+		msg = fmt.Sprintf("%s. Reason: %s", msg, user.BanReason)
+	}
+
+	apiErr := newAPIError(http.StatusForbidden, msg)
+	apiErr.Code = sdump.ErrorCodeUserBanned
+
+	return apiErr
+}
+
+func (u *urlHandler) findUserByAPIToken(ctx context.Context, token string) (*sdump.User, error) {
+	apiToken, err := u.apiTokenRepo.Find(ctx, &sdump.FindAPITokenOptions{
+		Hash: sdump.HashAPIToken(token),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.userRepo.Find(ctx, &sdump.FindUserOptions{
+		ID: apiToken.UserID,
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || util.IsStringEmpty(token) {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
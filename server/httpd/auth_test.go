@@ -0,0 +1,489 @@
+package httpd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/signature"
+	"github.com/adelowo/sdump/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/r3labs/sse/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testAdminSecret = "sdump-admin-secret"
+
+// authenticateAsSSHUser makes the request the way the SSH server does on
+// behalf of a connected user
+func authenticateAsSSHUser(req *http.Request, fingerprint string) {
+	req.Header.Set(adminSecretHeader, testAdminSecret)
+	req.Header.Set(sshFingerprintHeader, fingerprint)
+}
+
+func TestURLHandler_RequireUser(t *testing.T) {
+	userID := uuid.New()
+
+	tt := []struct {
+		name               string
+		mockFn             func(apiTokenRepo *mocks.MockAPITokenRepository, userRepo *mocks.MockUserRepository)
+		headers            map[string]string
+		allowAPITokens     bool
+		sign               bool
+		prepareFn          func(req *http.Request)
+		expectedStatusCode int
+	}{
+		{
+			name:               "no credentials provided",
+			mockFn:             func(_ *mocks.MockAPITokenRepository, _ *mocks.MockUserRepository) {},
+			allowAPITokens:     true,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "ssh fingerprint without admin secret",
+			mockFn: func(_ *mocks.MockAPITokenRepository, _ *mocks.MockUserRepository) {},
+			headers: map[string]string{
+				sshFingerprintHeader: "sufojfpffhhofjfpjfo",
+			},
+			allowAPITokens:     true,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "ssh fingerprint with wrong admin secret",
+			mockFn: func(_ *mocks.MockAPITokenRepository, _ *mocks.MockUserRepository) {},
+			headers: map[string]string{
+				sshFingerprintHeader: "sufojfpffhhofjfpjfo",
+				adminSecretHeader:    "wrong-secret",
+			},
+			allowAPITokens:     true,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "invalid api token",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository, _ *mocks.MockUserRepository) {
+				apiTokenRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sdump.ErrAPITokenNotFound)
+			},
+			headers: map[string]string{
+				"Authorization": "Bearer sdump_invalid",
+			},
+			allowAPITokens:     true,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "valid api token",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository, userRepo *mocks.MockUserRepository) {
+				apiTokenRepo.EXPECT().Find(gomock.Any(), &sdump.FindAPITokenOptions{
+					Hash: sdump.HashAPIToken("sdump_valid"),
+				}).
+					Times(1).
+					Return(&sdump.APIToken{UserID: userID}, nil)
+
+				userRepo.EXPECT().Find(gomock.Any(), &sdump.FindUserOptions{ID: userID}).
+					Times(1).
+					Return(&sdump.User{ID: userID}, nil)
+			},
+			headers: map[string]string{
+				"Authorization": "Bearer sdump_valid",
+			},
+			allowAPITokens:     true,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:   "api tokens cannot be used on ssh only routes",
+			mockFn: func(_ *mocks.MockAPITokenRepository, _ *mocks.MockUserRepository) {},
+			headers: map[string]string{
+				"Authorization": "Bearer sdump_valid",
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "signed request from the ssh server",
+			mockFn: func(_ *mocks.MockAPITokenRepository, userRepo *mocks.MockUserRepository) {
+				userRepo.EXPECT().Find(gomock.Any(), &sdump.FindUserOptions{
+					SSHKeyFingerprint: "sufojfpffhhofjfpjfo",
+				}).
+					Times(1).
+					Return(&sdump.User{ID: userID}, nil)
+			},
+			headers: map[string]string{
+				sshFingerprintHeader: "sufojfpffhhofjfpjfo",
+			},
+			sign:               true,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:   "fingerprint changed after the request was signed",
+			mockFn: func(_ *mocks.MockAPITokenRepository, _ *mocks.MockUserRepository) {},
+			headers: map[string]string{
+				sshFingerprintHeader: "sufojfpffhhofjfpjfo",
+			},
+			sign: true,
+			prepareFn: func(req *http.Request) {
+				req.Header.Set(sshFingerprintHeader, "someone-else")
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "banned users are rejected",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository, userRepo *mocks.MockUserRepository) {
+				apiTokenRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.APIToken{UserID: userID}, nil)
+
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: userID, IsBanned: true}, nil)
+			},
+			headers: map[string]string{
+				"Authorization": "Bearer sdump_valid",
+			},
+			allowAPITokens:     true,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "ssh server acting on behalf of a user",
+			mockFn: func(_ *mocks.MockAPITokenRepository, userRepo *mocks.MockUserRepository) {
+				userRepo.EXPECT().Find(gomock.Any(), &sdump.FindUserOptions{
+					SSHKeyFingerprint: "sufojfpffhhofjfpjfo",
+				}).
+					Times(1).
+					Return(&sdump.User{ID: userID}, nil)
+			},
+			headers: map[string]string{
+				sshFingerprintHeader: "sufojfpffhhofjfpjfo",
+				adminSecretHeader:    testAdminSecret,
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range v.headers {
+				req.Header.Set(key, value)
+			}
+
+			if v.sign {
+				require.NoError(t, signature.Sign(req, testAdminSecret, time.Now()))
+			}
+
+			if v.prepareFn != nil {
+				v.prepareFn(req)
+			}
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			apiTokenRepo := mocks.NewMockAPITokenRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			v.mockFn(apiTokenRepo, userRepo)
+
+			u := &urlHandler{
+				logger:       logger,
+				cfg:          config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				userRepo:     userRepo,
+				apiTokenRepo: apiTokenRepo,
+			}
+
+			router := chi.NewRouter()
+			router.With(u.authenticateUser(v.allowAPITokens)).
+				Get("/", func(w http.ResponseWriter, r *http.Request) {
+					require.Equal(t, userID, userFromContext(r.Context()).ID)
+					w.WriteHeader(http.StatusOK)
+				})
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestURLHandler_RequireAdmin(t *testing.T) {
+	tt := []struct {
+		name               string
+		configuredSecret   string
+		prepareFn          func(t *testing.T, req *http.Request)
+		expectedStatusCode int
+	}{
+		{
+			name:               "no secret provided",
+			configuredSecret:   testAdminSecret,
+			prepareFn:          func(_ *testing.T, _ *http.Request) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "wrong secret",
+			configuredSecret: testAdminSecret,
+			prepareFn: func(_ *testing.T, req *http.Request) {
+				req.Header.Set(adminSecretHeader, "wrong-secret")
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "secret not configured",
+			prepareFn: func(_ *testing.T, req *http.Request) {
+				req.Header.Set(adminSecretHeader, testAdminSecret)
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "valid secret",
+			configuredSecret: testAdminSecret,
+			prepareFn: func(_ *testing.T, req *http.Request) {
+				req.Header.Set(adminSecretHeader, testAdminSecret)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "signed with another secret",
+			configuredSecret: testAdminSecret,
+			prepareFn: func(t *testing.T, req *http.Request) {
+				require.NoError(t, signature.Sign(req, "wrong-secret", time.Now()))
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "signature has expired",
+			configuredSecret: testAdminSecret,
+			prepareFn: func(t *testing.T, req *http.Request) {
+				require.NoError(t, signature.Sign(req, testAdminSecret, time.Now().Add(-time.Hour)))
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "signed body was tampered with",
+			configuredSecret: testAdminSecret,
+			prepareFn: func(t *testing.T, req *http.Request) {
+				require.NoError(t, signature.Sign(req, testAdminSecret, time.Now()))
+				req.Body = io.NopCloser(strings.NewReader(`{"ssh_fingerprint":"someone-else"}`))
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "a valid signature does not fall back to the secret header",
+			configuredSecret: testAdminSecret,
+			prepareFn: func(t *testing.T, req *http.Request) {
+				require.NoError(t, signature.Sign(req, "wrong-secret", time.Now()))
+				req.Header.Set(adminSecretHeader, testAdminSecret)
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "valid signature",
+			configuredSecret: testAdminSecret,
+			prepareFn: func(t *testing.T, req *http.Request) {
+				require.NoError(t, signature.Sign(req, testAdminSecret, time.Now()))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"ssh_fingerprint":"sufojfpffhhofjfpjfo"}`))
+			v.prepareFn(t, req)
+
+			u := &urlHandler{
+				cfg: config.Config{HTTP: config.HTTPConfig{AdminSecret: v.configuredSecret}},
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireAdmin).
+				Post("/", func(w http.ResponseWriter, r *http.Request) {
+					// the body can still be read once the signature has been verified
+					b, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					require.Equal(t, `{"ssh_fingerprint":"sufojfpffhhofjfpjfo"}`, string(b))
+
+					w.WriteHeader(http.StatusOK)
+				})
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestURLHandler_RequireStreamSubscriber(t *testing.T) {
+	ownerID := uuid.New()
+
+	newToken := func(t *testing.T, subject uuid.UUID, channel string, ttl time.Duration) string {
+		token, err := signature.NewToken(testAdminSecret, signature.Claims{
+			Subject:  subject.String(),
+			Audience: channel,
+		}, ttl, time.Now())
+		require.NoError(t, err)
+		return token
+	}
+
+	tt := []struct {
+		name               string
+		mockFn             func(urlRepo *mocks.MockURLRepository)
+		stream             string
+		token              func(t *testing.T) string
+		useQuery           bool
+		expectedStatusCode int
+	}{
+		{
+			name:               "no token provided",
+			mockFn:             func(_ *mocks.MockURLRepository) {},
+			stream:             "messages.cmltfm6g330l5l1vq110",
+			token:              func(_ *testing.T) string { return "" },
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "invalid token",
+			mockFn:             func(_ *mocks.MockURLRepository) {},
+			stream:             "messages.cmltfm6g330l5l1vq110",
+			token:              func(_ *testing.T) string { return "not-a-token" },
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "expired token",
+			mockFn: func(_ *mocks.MockURLRepository) {},
+			stream: "messages.cmltfm6g330l5l1vq110",
+			token: func(t *testing.T) string {
+				return newToken(t, ownerID, "messages.cmltfm6g330l5l1vq110", -time.Minute)
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "token issued for another stream",
+			mockFn: func(_ *mocks.MockURLRepository) {},
+			stream: "messages.cmltfm6g330l5l1vq110",
+			token: func(t *testing.T) string {
+				return newToken(t, ownerID, "messages.cmu6domg330ksjspot40", streamTokenTTL)
+			},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "url does not exist",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), &sdump.FindURLOptions{
+					Reference: "cmltfm6g330l5l1vq110",
+				}).
+					Times(1).
+					Return(nil, sdump.ErrURLEndpointNotFound)
+			},
+			stream: "messages.cmltfm6g330l5l1vq110",
+			token: func(t *testing.T) string {
+				return newToken(t, ownerID, "messages.cmltfm6g330l5l1vq110", streamTokenTTL)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "url belongs to another user",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: uuid.New()}, nil)
+			},
+			stream: "messages.cmltfm6g330l5l1vq110",
+			token: func(t *testing.T) string {
+				return newToken(t, ownerID, "messages.cmltfm6g330l5l1vq110", streamTokenTTL)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "owner subscribes with the token header",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+			},
+			stream: "messages.cmltfm6g330l5l1vq110",
+			token: func(t *testing.T) string {
+				return newToken(t, ownerID, "messages.cmltfm6g330l5l1vq110", streamTokenTTL)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "owner subscribes with the token query parameter",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+			},
+			stream: "messages.cmltfm6g330l5l1vq110",
+			token: func(t *testing.T) string {
+				return newToken(t, ownerID, "messages.cmltfm6g330l5l1vq110", streamTokenTTL)
+			},
+			useQuery:           true,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			query := url.Values{}
+			query.Set("stream", v.stream)
+
+			token := v.token(t)
+			if v.useQuery {
+				query.Set("token", token)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/events?"+query.Encode(), nil)
+			if !v.useQuery && token != "" {
+				req.Header.Set(streamTokenHeader, token)
+			}
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+
+			v.mockFn(urlRepo)
+
+			u := &urlHandler{
+				logger:    logger,
+				cfg:       config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:   urlRepo,
+				sseServer: sse.New(),
+			}
+
+			var attached bool
+
+			router := chi.NewRouter()
+			router.With(u.requireStreamSubscriber).
+				Get("/events", func(w http.ResponseWriter, r *http.Request) {
+					attached = true
+					w.WriteHeader(http.StatusOK)
+				})
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			require.Equal(t, v.expectedStatusCode == http.StatusOK, attached)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package httpd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/google/uuid"
+	"github.com/r3labs/sse/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// sseEvent is what TUI sessions receive for every ingested request
+type sseEvent struct {
+	Request   sdump.RequestDefinition `json:"request"`
+	Forward   *sdump.ForwardResult    `json:"forward,omitempty"`
+	Tunnel    *sdump.ForwardResult    `json:"tunnel,omitempty"`
+	ID        string                  `json:"id"`
+	CreatedAt time.Time               `json:"created_at,omitempty"`
+}
+
+func newSSEEvent(ingestedRequest *sdump.IngestHTTPRequest) ([]byte, error) {
+	return json.Marshal(&sseEvent{
+		Request:   ingestedRequest.Request,
+		Forward:   ingestedRequest.Forward,
+		Tunnel:    ingestedRequest.Tunnel,
+		ID:        ingestedRequest.ID.String(),
+		CreatedAt: ingestedRequest.CreatedAt,
+	})
+}
+
+// droppedEvent is what TUI sessions receive for every request the endpoint
+// turned away without ingesting it
+type droppedEvent struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Relay streams the events published by every HTTP server to the TUI
+// sessions connected to this one. It runs until the context is cancelled
+func Relay(ctx context.Context, subscriber sdump.Subscriber,
+	sseServer *sse.Server,
+	ingestRepo sdump.IngestRepository,
+	logger *logrus.Entry,
+) error {
+	return subscriber.Subscribe(ctx, func(event *sdump.Event) {
+		// nobody is subscribed to the endpoint on this server
+		if !sseServer.StreamExists(event.Channel) {
+			return
+		}
+
+		data := event.Data
+
+		// only ingested requests can be loaded back
+		if len(data) == 0 && event.Type != "" {
+			return
+		}
+
+		if len(data) == 0 {
+			id, err := uuid.Parse(event.ID)
+			if err != nil {
+				logger.WithError(err).Error("could not parse published event id")
+				return
+			}
+
+			ingestedRequest, err := ingestRepo.Get(ctx, &sdump.FindIngestedRequestOptions{
+				ID: id,
+			})
+			if err != nil {
+				logger.WithError(err).Error("could not fetch published request")
+				return
+			}
+
+			data, err = newSSEEvent(ingestedRequest)
+			if err != nil {
+				logger.WithError(err).Error("could not format SSE event")
+				return
+			}
+		}
+
+		sseServer.Publish(event.Channel, &sse.Event{
+			ID:    []byte(event.ID),
+			Event: []byte(event.Type),
+			Data:  data,
+		})
+	})
+}
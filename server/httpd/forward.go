@@ -0,0 +1,94 @@
+package httpd
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	defaultForwardWorkers   = 10
+	defaultForwardQueueSize = 100
+)
+
+// Forwarder relays ingested requests in the background with a fixed number
+// of workers so senders are not held up by slow forward targets and a
+// burst of requests cannot start an unbounded number of relays
+type Forwarder struct {
+	mu       sync.RWMutex
+	isClosed bool
+	jobs     chan func()
+	pending  sync.WaitGroup
+}
+
+// NewForwarder starts the workers. Zero or negative values fall back to
+// the defaults
+func NewForwarder(workers, queueSize int) *Forwarder {
+	if workers <= 0 {
+		workers = defaultForwardWorkers
+	}
+
+	if queueSize < 0 {
+		queueSize = defaultForwardQueueSize
+	}
+
+	f := &Forwarder{
+		jobs: make(chan func(), queueSize),
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range f.jobs {
+				job()
+				f.pending.Done()
+			}
+		}()
+	}
+
+	return f
+}
+
+// enqueue returns false if every worker is busy and the queue is full or
+// the forwarder was closed
+func (f *Forwarder) enqueue(job func()) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.isClosed {
+		return false
+	}
+
+	f.pending.Add(1)
+
+	select {
+	case f.jobs <- job:
+		return true
+	default:
+		f.pending.Done()
+		return false
+	}
+}
+
+// Close stops accepting requests and waits until the queued ones have
+// been forwarded or ctx is done
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if !f.isClosed {
+		f.isClosed = true
+		close(f.jobs)
+	}
+	f.mu.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		f.pending.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,40 @@
+package httpd
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwarder(t *testing.T) {
+	f := NewForwarder(1, 1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var forwarded atomic.Int64
+
+	require.True(t, f.enqueue(func() {
+		close(started)
+		<-release
+		forwarded.Add(1)
+	}))
+
+	<-started
+
+	// the worker is busy so the job waits in the queue
+	require.True(t, f.enqueue(func() { forwarded.Add(1) }))
+
+	// and the queue is full
+	require.False(t, f.enqueue(func() { forwarded.Add(1) }))
+
+	close(release)
+	require.NoError(t, f.Close(context.Background()))
+
+	require.Equal(t, int64(2), forwarded.Load())
+
+	// nothing is accepted once closed
+	require.False(t, f.enqueue(func() { forwarded.Add(1) }))
+}
@@ -4,17 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/adelowo/sdump"
 	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/relay"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/telemetry"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/r3labs/sse/v2"
 	"github.com/riandyrn/otelchi"
-	"github.com/sethvargo/go-limiter"
-	"github.com/sethvargo/go-limiter/httplimit"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -36,17 +36,66 @@ var failedIngestedHTTPRequestsCounter = prometheus.NewCounter(prometheus.Counter
 	Help: "Total number of failed attempts to ingest HTTP requests",
 })
 
+var forwardedHTTPRequestsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sdump_forwarded_http_requests",
+	Help: "Total number of ingested HTTP requests relayed to a forward target",
+})
+
+var failedForwardedHTTPRequestsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sdump_forwarded_http_requests_failure",
+	Help: "Total number of ingested HTTP requests that could not be relayed to a forward target",
+})
+
+var droppedForwardsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sdump_dropped_forwards",
+	Help: "Total number of ingested HTTP requests not forwarded because the forward queue was full",
+})
+
+var tunneledHTTPRequestsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sdump_tunneled_http_requests",
+	Help: "Total number of ingested HTTP requests delivered through a reverse SSH tunnel",
+})
+
+var failedTunneledHTTPRequestsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sdump_tunneled_http_requests_failure",
+	Help: "Total number of ingested HTTP requests that could not be delivered through a reverse SSH tunnel",
+})
+
+var pausedHTTPRequestsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sdump_paused_http_requests",
+	Help: "Total number of HTTP requests replied to but not stored because capture was paused",
+})
+
+var rateLimitedHTTPRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sdump_rate_limited_http_requests",
+	Help: "Total number of HTTP requests rejected by the rate limiter",
+}, []string{"scope"})
+
+var prunedHTTPRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sdump_pruned_http_requests",
+	Help: "Total number of ingested HTTP requests deleted by the retention scheduler",
+}, []string{"reason"})
+
+var failedPrunesCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sdump_prune_failure",
+	Help: "Total number of retention scheduler runs that failed",
+})
+
 func New(cfg config.Config,
 	urlRepo sdump.URLRepository,
 	ingestRepo sdump.IngestRepository,
 	userRepo sdump.UserRepository,
+	apiTokenRepo sdump.APITokenRepository,
+	planRepo sdump.PlanRepository,
 	logger *logrus.Entry,
 	sseServer *sse.Server,
-	ratelimitStore limiter.Store,
+	publisher sdump.Publisher,
+	rateLimiter *RateLimiter,
+	forwarder *Forwarder,
 ) *http.Server {
 	return &http.Server{
 		Handler: buildRoutes(cfg, logger, urlRepo, ingestRepo,
-			userRepo, sseServer, ratelimitStore),
+			userRepo, apiTokenRepo, planRepo, sseServer, publisher, rateLimiter, forwarder),
 		Addr: fmt.Sprintf(":%d", cfg.HTTP.Port),
 	}
 }
@@ -56,24 +105,48 @@ func buildRoutes(cfg config.Config,
 	urlRepo sdump.URLRepository,
 	ingestRepo sdump.IngestRepository,
 	userRepo sdump.UserRepository,
+	apiTokenRepo sdump.APITokenRepository,
+	planRepo sdump.PlanRepository,
 	sseServer *sse.Server,
-	ratelimitStore limiter.Store,
+	publisher sdump.Publisher,
+	rateLimiter *RateLimiter,
+	forwarder *Forwarder,
 ) http.Handler {
 
 	router := chi.NewRouter()
 
-	router.Use(middleware.AllowContentType("application/json"))
 	router.Use(middleware.RequestID)
 	router.Use(writeRequestIDHeader)
 	router.Use(jsonResponse)
 
+	forwardTimeout := cfg.HTTP.Forwarding.Timeout
+	if forwardTimeout <= 0 {
+		forwardTimeout = 10 * time.Second
+	}
+
 	urlHandler := &urlHandler{
-		cfg:        cfg,
-		urlRepo:    urlRepo,
-		logger:     logger,
-		ingestRepo: ingestRepo,
-		userRepo:   userRepo,
-		sseServer:  sseServer,
+		cfg:          cfg,
+		urlRepo:      urlRepo,
+		logger:       logger,
+		ingestRepo:   ingestRepo,
+		userRepo:     userRepo,
+		apiTokenRepo: apiTokenRepo,
+		planRepo:     planRepo,
+		sseServer:    sseServer,
+		publisher:    publisher,
+		rateLimiter:  rateLimiter,
+		forwardClient: relay.NewClient(forwardTimeout,
+			cfg.HTTP.Forwarding.AllowPrivateNetworks),
+		// tunnel listeners live on the SSH server's private address
+		tunnelClient: relay.NewClient(forwardTimeout, true),
+		forwarder:    forwarder,
+	}
+
+	userHandler := &userHandler{}
+
+	tokenHandler := &tokenHandler{
+		logger:       logger,
+		apiTokenRepo: apiTokenRepo,
 	}
 
 	router.Use(writeRequestIDHeader)
@@ -87,20 +160,81 @@ func buildRoutes(cfg config.Config,
 		_ = prometheus.Register(ingestedHTTPRequestsCounter)
 		_ = prometheus.Register(failedIngestedHTTPRequestsCounter)
 		_ = prometheus.Register(createdURLMetrics)
+		_ = prometheus.Register(forwardedHTTPRequestsCounter)
+		_ = prometheus.Register(failedForwardedHTTPRequestsCounter)
+		_ = prometheus.Register(droppedForwardsCounter)
+		_ = prometheus.Register(tunneledHTTPRequestsCounter)
+		_ = prometheus.Register(failedTunneledHTTPRequestsCounter)
+		_ = prometheus.Register(pausedHTTPRequestsCounter)
+		_ = prometheus.Register(rateLimitedHTTPRequestsCounter)
+		_ = prometheus.Register(prunedHTTPRequestsCounter)
+		_ = prometheus.Register(failedPrunesCounter)
 	}
 
 	router.Use(otelchi.Middleware("http-router", otelchi.WithChiRoutes(router)))
 
-	mid, err := httplimit.NewMiddleware(ratelimitStore, func(r *http.Request) (string, error) {
-		return chi.URLParam(r, "reference"), nil
-	})
-	if err != nil {
-		logger.WithError(err).Fatal("could not set up HTTP middleware")
-	}
+	// webhooks can be sent with any content type so only the
+	// internal routes are limited to json
+	router.With(middleware.AllowContentType("application/json"), urlHandler.requireAdmin).
+		Post("/", urlHandler.create)
+
+	router.HandleFunc("/{reference}", urlHandler.ingest)
+	router.HandleFunc("/{reference}/*", urlHandler.ingest)
+	router.With(urlHandler.requireStreamSubscriber).Get("/events", sseServer.ServeHTTP)
+
+	router.Route("/api/v1", func(r chi.Router) {
+		r.Use(middleware.AllowContentType("application/json"))
+
+		r.With(urlHandler.requireUser).Get("/urls", urlHandler.list)
+
+		r.With(urlHandler.requireUser).Get("/users/me", userHandler.me)
+		r.With(urlHandler.requireUser).Get("/users/me/quota", urlHandler.quota)
+
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(urlHandler.requireSSHUser)
 
-	router.Post("/", urlHandler.create)
-	router.Handle("/{reference}", mid.Handle(http.HandlerFunc(urlHandler.ingest)))
-	router.Get("/events", sseServer.ServeHTTP)
+			r.Get("/", tokenHandler.list)
+			r.Post("/", tokenHandler.create)
+			r.Delete("/{id}", tokenHandler.revoke)
+		})
+
+		r.Route("/urls/{reference}", func(r chi.Router) {
+			r.Use(urlHandler.requireEndpointOwner)
+
+			r.Get("/", urlHandler.get)
+			r.Patch("/", urlHandler.rename)
+			r.Delete("/", urlHandler.delete)
+			r.Post("/deactivate", urlHandler.deactivate)
+			r.Post("/stream", urlHandler.stream)
+			r.Put("/pause", urlHandler.pause)
+			r.Delete("/pause", urlHandler.resume)
+
+			r.Get("/response", urlHandler.getResponse)
+			r.Put("/response", urlHandler.updateResponse)
+			r.Delete("/response", urlHandler.deleteResponse)
+
+			r.Get("/forward", urlHandler.getForward)
+			r.Put("/forward", urlHandler.updateForward)
+			r.Delete("/forward", urlHandler.deleteForward)
+
+			r.Get("/limits", urlHandler.getLimits)
+			r.Put("/limits", urlHandler.updateLimits)
+			r.Delete("/limits", urlHandler.deleteLimits)
+
+			r.Get("/requests", urlHandler.listRequests)
+			r.Delete("/requests", urlHandler.deleteRequests)
+
+			r.With(urlHandler.requireIngestedRequest).
+				Get("/requests/{id}", urlHandler.getRequest)
+			r.With(urlHandler.requireIngestedRequest).
+				Delete("/requests/{id}", urlHandler.deleteRequest)
+
+			r.Get("/tunnel", urlHandler.getTunnel)
+			// only the SSH server knows which tunnels are open
+			r.With(urlHandler.requireSSHSession).Put("/tunnel", urlHandler.updateTunnel)
+			r.With(urlHandler.requireSSHSession).Delete("/tunnel", urlHandler.deleteTunnel)
+		})
+	})
 
 	return router
 }
@@ -0,0 +1,181 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// userPlan returns the plan the user is on. If the plan cannot be found,
+// the user is not restricted so a misconfigured plan does not stop
+// requests from being ingested
+func (u *urlHandler) userPlan(ctx context.Context, user *sdump.User) (*sdump.Plan, error) {
+	var planID uuid.UUID
+	if user != nil {
+		planID = user.PlanID
+	}
+
+	plan, err := u.planRepo.Find(ctx, &sdump.FindPlanOptions{
+		ID: planID,
+	})
+	if errors.Is(err, sdump.ErrPlanNotFound) {
+		return &sdump.Plan{}, nil
+	}
+
+	return plan, err
+}
+
+// startOfDay is when the daily request quota resets
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func (u *urlHandler) userQuota(ctx context.Context,
+	user *sdump.User, plan *sdump.Plan,
+) (*sdump.Quota, error) {
+	endpoints, err := u.urlRepo.List(ctx, &sdump.ListURLOptions{
+		UserID:   user.ID,
+		IsActive: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := u.ingestRepo.Count(ctx, &sdump.CountIngestedRequestOptions{
+		UserID: user.ID,
+		Since:  startOfDay(time.Now()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	quota := plan.Quota(sdump.Usage{
+		Endpoints:     int64(len(endpoints)),
+		RequestsToday: requests,
+	})
+	quota.MaxRequestBodySize = plan.RequestBodySize(u.cfg.HTTP.MaxRequestBodySize)
+
+	return quota, nil
+}
+
+// takeEndpointQuota returns sdump.ErrCounterExhausted if the user cannot
+// create another endpoint. replaced is the endpoint the new one takes the
+// place of, if any. It is deactivated afterwards so it is not counted
+func (u *urlHandler) takeEndpointQuota(ctx context.Context,
+	userID uuid.UUID, plan *sdump.Plan, replaced *sdump.URLEndpoint,
+) error {
+	if plan.MaxEndpoints <= 0 {
+		return nil
+	}
+
+	// deactivated endpoints, such as rotated ones, cannot receive
+	// requests anymore so they do not count
+	endpoints, err := u.urlRepo.List(ctx, &sdump.ListURLOptions{
+		UserID:   userID,
+		IsActive: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	used := int64(len(endpoints))
+	if replaced != nil {
+		for _, endpoint := range endpoints {
+			if endpoint.ID == replaced.ID {
+				used--
+				break
+			}
+		}
+	}
+
+	limit := plan.Quota(sdump.Usage{Endpoints: used}).Endpoints
+	return limit.Take()
+}
+
+// takeRequestQuota returns sdump.ErrCounterExhausted if the user's
+// endpoints have received all the requests their plan allows today.
+// Requests are counted from the database so deleting them without
+// cron.soft_deletes gives the quota back
+func (u *urlHandler) takeRequestQuota(ctx context.Context,
+	userID uuid.UUID, plan *sdump.Plan,
+) error {
+	if plan.MaxRequestsPerDay <= 0 {
+		return nil
+	}
+
+	count, err := u.ingestRepo.Count(ctx, &sdump.CountIngestedRequestOptions{
+		UserID: userID,
+		Since:  startOfDay(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+
+	limit := plan.Quota(sdump.Usage{RequestsToday: count}).RequestsToday
+	return limit.Take()
+}
+
+// limitUpperBounds is how far an endpoint owned by a user on the plan can
+// raise its limits
+func (u *urlHandler) limitUpperBounds(plan *sdump.Plan) (int64, time.Duration) {
+	retention := u.cfg.Cron.TTL
+	if plan.RetentionDays > 0 && plan.Retention() < retention {
+		retention = plan.Retention()
+	}
+
+	return plan.RequestBodySize(u.cfg.HTTP.MaxRequestBodySize), retention
+}
+
+func newEndpointQuotaError(plan *sdump.Plan) APIError {
+	return newAPIError(http.StatusPaymentRequired,
+		fmt.Sprintf("your plan allows only %d endpoints. Delete one of your endpoints to create another",
+			plan.MaxEndpoints))
+}
+
+func newRequestQuotaError(plan *sdump.Plan) APIError {
+	return newAPIError(http.StatusTooManyRequests,
+		fmt.Sprintf("this url has received the %d requests its plan allows today. Try again tomorrow",
+			plan.MaxRequestsPerDay))
+}
+
+// quota returns what is left of the authenticated user's plan
+func (u *urlHandler) quota(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "user.quota")
+	defer span.End()
+
+	logger := u.logger.WithField("method", "user.quota").
+		WithField("request_id", requestID)
+
+	user := userFromContext(ctx)
+
+	plan, err := u.userPlan(ctx, user)
+	if err != nil {
+		logger.WithError(err).Error("could not fetch plan")
+		span.SetStatus(codes.Error, "could not fetch plan")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"could not fetch your quota"))
+		return
+	}
+
+	quota, err := u.userQuota(ctx, user, plan)
+	if err != nil {
+		logger.WithError(err).Error("could not calculate quota")
+		span.SetStatus(codes.Error, "could not calculate quota")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"could not fetch your quota"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "fetched quota")
+	_ = render.Render(w, r, &quotaResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched quota"),
+		Quota:     quota,
+	})
+}
@@ -0,0 +1,147 @@
+package httpd
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestURLHandler_Quota(t *testing.T) {
+	tt := []struct {
+		name   string
+		mockFn func(planRepo *mocks.MockPlanRepository,
+			urlRepo *mocks.MockURLRepository,
+			ingestRepo *mocks.MockIngestRepository)
+		expectedStatusCode int
+	}{
+		{
+			name: "could not fetch plan",
+			mockFn: func(planRepo *mocks.MockPlanRepository,
+				_ *mocks.MockURLRepository, _ *mocks.MockIngestRepository,
+			) {
+				planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errors.New("could not fetch plan"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "could not count requests",
+			mockFn: func(planRepo *mocks.MockPlanRepository,
+				urlRepo *mocks.MockURLRepository, ingestRepo *mocks.MockIngestRepository,
+			) {
+				planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.Plan{Name: "free"}, nil)
+
+				urlRepo.EXPECT().List(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]sdump.URLEndpoint{{}}, nil)
+
+				ingestRepo.EXPECT().Count(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), errors.New("could not count requests"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "quota is returned",
+			mockFn: func(planRepo *mocks.MockPlanRepository,
+				urlRepo *mocks.MockURLRepository, ingestRepo *mocks.MockIngestRepository,
+			) {
+				planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.Plan{
+						Name:               "free",
+						MaxEndpoints:       3,
+						MaxRequestsPerDay:  1000,
+						RetentionDays:      2,
+						MaxRequestBodySize: 500,
+					}, nil)
+
+				urlRepo.EXPECT().List(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]sdump.URLEndpoint{{}}, nil)
+
+				ingestRepo.EXPECT().Count(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(50), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "plan without limits",
+			mockFn: func(planRepo *mocks.MockPlanRepository,
+				urlRepo *mocks.MockURLRepository, ingestRepo *mocks.MockIngestRepository,
+			) {
+				planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sdump.ErrPlanNotFound)
+
+				urlRepo.EXPECT().List(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]sdump.URLEndpoint{{}}, nil)
+
+				ingestRepo.EXPECT().Count(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(50), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/quota", nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{SSHFingerPrint: "sufojfpffhhofjfpjfo"}, nil)
+
+			planRepo := mocks.NewMockPlanRepository(ctrl)
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			ingestRepo := mocks.NewMockIngestRepository(ctrl)
+
+			v.mockFn(planRepo, urlRepo, ingestRepo)
+
+			u := &urlHandler{
+				logger: logrus.WithField("module", "test"),
+				cfg: config.Config{HTTP: config.HTTPConfig{
+					AdminSecret:        testAdminSecret,
+					MaxRequestBodySize: 1024,
+				}},
+				userRepo:   userRepo,
+				planRepo:   planRepo,
+				urlRepo:    urlRepo,
+				ingestRepo: ingestRepo,
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireUser).Get("/api/v1/users/me/quota", u.quota)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/util"
+	"github.com/google/uuid"
+	"github.com/sethvargo/go-limiter"
+	"github.com/sethvargo/go-limiter/httplimit"
+)
+
+type rateLimitScope string
+
+const (
+	rateLimitScopeIngest rateLimitScope = "ingest"
+	rateLimitScopeAPI    rateLimitScope = "api"
+)
+
+// RateLimiter keeps a limiter.Store for every limit in use since plans and
+// users can have their own
+type RateLimiter struct {
+	newStore func(tokens uint64) (limiter.Store, error)
+
+	mu     sync.Mutex
+	stores map[uint64]limiter.Store
+
+	// rejected are the requests known to be over their limit until the
+	// limit resets. They are turned away without looking anything up.
+	// owners are the owners of the endpoints those requests were sent to
+	// so buckets counted by user can be found from the reference
+	rejectedMu sync.Mutex
+	rejected   map[string]rejectedUntil
+	owners     map[string]ownerUntil
+}
+
+type rejectedUntil struct {
+	tokens uint64
+	reset  uint64
+}
+
+type ownerUntil struct {
+	userID uuid.UUID
+	reset  uint64
+}
+
+// maxRejected bounds how many rejections are remembered at once
+const maxRejected = 10000
+
+// NewRateLimiter creates a RateLimiter. newStore is called the first time a
+// limit is used and should return a store that hands out tokens per minute
+func NewRateLimiter(newStore func(tokens uint64) (limiter.Store, error)) *RateLimiter {
+	return &RateLimiter{
+		newStore: newStore,
+		stores:   make(map[uint64]limiter.Store),
+		rejected: make(map[string]rejectedUntil),
+		owners:   make(map[string]ownerUntil),
+	}
+}
+
+func (l *RateLimiter) store(tokens uint64) (limiter.Store, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if store, ok := l.stores[tokens]; ok {
+		return store, nil
+	}
+
+	store, err := l.newStore(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	l.stores[tokens] = store
+	return store, nil
+}
+
+// Take uses up a token from the key's bucket. The returned values are the
+// same as limiter.Store's
+func (l *RateLimiter) Take(ctx context.Context, key string, tokens uint64) (uint64, uint64, uint64, bool, error) {
+	store, err := l.store(tokens)
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+
+	// the postgres store keeps the buckets of every limit in one table
+	return store.Take(ctx, fmt.Sprintf("%d|%s", tokens, key))
+}
+
+// reject remembers that requests identified by key are over their limit
+// until reset, along with who owns the endpoint they were sent to
+func (l *RateLimiter) reject(key, reference string, userID uuid.UUID, tokens, reset uint64) {
+	l.rejectedMu.Lock()
+	defer l.rejectedMu.Unlock()
+
+	if len(l.rejected) >= maxRejected || len(l.owners) >= maxRejected {
+		now := uint64(time.Now().UnixNano())
+
+		for k, v := range l.rejected {
+			if v.reset <= now {
+				delete(l.rejected, k)
+			}
+		}
+
+		for k, v := range l.owners {
+			if v.reset <= now {
+				delete(l.owners, k)
+			}
+		}
+
+		if len(l.rejected) >= maxRejected || len(l.owners) >= maxRejected {
+			return
+		}
+	}
+
+	l.rejected[key] = rejectedUntil{tokens: tokens, reset: reset}
+
+	if owner, ok := l.owners[reference]; !ok || owner.reset < reset {
+		l.owners[reference] = ownerUntil{userID: userID, reset: reset}
+	}
+}
+
+// owner returns who owns the endpoint if one of its requests was rejected
+// and the limit has not reset since
+func (l *RateLimiter) owner(reference string) (uuid.UUID, bool) {
+	l.rejectedMu.Lock()
+	defer l.rejectedMu.Unlock()
+
+	v, ok := l.owners[reference]
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	if v.reset <= uint64(time.Now().UnixNano()) {
+		delete(l.owners, reference)
+		return uuid.Nil, false
+	}
+
+	return v.userID, true
+}
+
+// isRejected reports whether requests identified by key are still over
+// their limit. The limit and when it resets are returned if so
+func (l *RateLimiter) isRejected(key string) (uint64, uint64, bool) {
+	l.rejectedMu.Lock()
+	defer l.rejectedMu.Unlock()
+
+	v, ok := l.rejected[key]
+	if !ok {
+		return 0, 0, false
+	}
+
+	if v.reset <= uint64(time.Now().UnixNano()) {
+		delete(l.rejected, key)
+		return 0, 0, false
+	}
+
+	return v.tokens, v.reset, true
+}
+
+// Close stops every store that has been created
+func (l *RateLimiter) Close(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs []error
+	for _, store := range l.stores {
+		errs = append(errs, store.Close(ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// rateLimitKeys are what requests of a scope are counted by. Ingested
+// requests are counted per endpoint and API requests per user by default
+func (u *urlHandler) rateLimitKeys(scope rateLimitScope) []config.RateLimitKey {
+	if scope == rateLimitScopeAPI {
+		if len(u.cfg.HTTP.RateLimit.API.Keys) == 0 {
+			return []config.RateLimitKey{config.RateLimitKeyUser}
+		}
+
+		return u.cfg.HTTP.RateLimit.API.Keys
+	}
+
+	if len(u.cfg.HTTP.RateLimit.Keys) == 0 {
+		return []config.RateLimitKey{config.RateLimitKeyEndpoint}
+	}
+
+	return u.cfg.HTTP.RateLimit.Keys
+}
+
+// rateLimitKey identifies the bucket the request is taken from. reference
+// is empty for API routes that are not about an endpoint
+func rateLimitKey(r *http.Request, scope rateLimitScope, keys []config.RateLimitKey,
+	reference string, userID uuid.UUID,
+) string {
+	parts := []string{string(scope)}
+
+	for _, key := range keys {
+		switch key {
+		case config.RateLimitKeyEndpoint:
+			parts = append(parts, "endpoint="+reference)
+		case config.RateLimitKeyIp:
+			parts = append(parts, "ip="+util.GetIP(r).String())
+		case config.RateLimitKeyUser:
+			parts = append(parts, "user="+userID.String())
+		}
+	}
+
+	return strings.Join(parts, "|")
+}
+
+// rejectedKey identifies ingested requests that share a bucket. Buckets
+// counted by endpoint or user always have the same limit, while buckets
+// only counted by ip are shared by endpoints whose owners can have
+// different limits so their rejections only hold for the endpoint
+func rejectedKey(r *http.Request, keys []config.RateLimitKey,
+	reference string, userID uuid.UUID,
+) string {
+	key := rateLimitKey(r, rateLimitScopeIngest, keys, reference, userID)
+
+	if !slices.Contains(keys, config.RateLimitKeyEndpoint) &&
+		!slices.Contains(keys, config.RateLimitKeyUser) {
+		key += "|endpoint=" + reference
+	}
+
+	return key
+}
+
+// isIngestRateLimited reports whether an ingested request can be rejected
+// before its endpoint, owner and plan are looked up because an earlier
+// request already emptied its bucket. It covers every key: endpoint and ip
+// are known from the request and the owner is remembered when a request to
+// the endpoint is rejected, so a user over their limit has each of their
+// endpoints looked up once more before it is covered too. Nothing is
+// remembered unless a limit turned a request away so it is always checked
+func (u *urlHandler) isIngestRateLimited(w http.ResponseWriter, r *http.Request,
+	reference string,
+) bool {
+	keys := u.rateLimitKeys(rateLimitScopeIngest)
+
+	var userID uuid.UUID
+
+	if slices.Contains(keys, config.RateLimitKeyUser) {
+		var ok bool
+
+		userID, ok = u.rateLimiter.owner(reference)
+		if !ok {
+			return false
+		}
+	}
+
+	tokens, reset, ok := u.rateLimiter.isRejected(rejectedKey(r, keys, reference, userID))
+	if !ok {
+		return false
+	}
+
+	writeRateLimitHeaders(w, tokens, 0, reset, false)
+	rateLimitedHTTPRequestsCounter.WithLabelValues(string(rateLimitScopeIngest)).Inc()
+	return true
+}
+
+func writeRateLimitHeaders(w http.ResponseWriter, tokens, remaining, reset uint64, ok bool) {
+	resetTime := time.Unix(0, int64(reset)).UTC().Format(time.RFC1123)
+
+	w.Header().Set(httplimit.HeaderRateLimitLimit, strconv.FormatUint(tokens, 10))
+	w.Header().Set(httplimit.HeaderRateLimitRemaining, strconv.FormatUint(remaining, 10))
+	w.Header().Set(httplimit.HeaderRateLimitReset, resetTime)
+
+	if !ok {
+		w.Header().Set(httplimit.HeaderRetryAfter, resetTime)
+	}
+}
+
+// takeRateLimit takes a token for the request and sets the X-RateLimit
+// headers. It reports whether the limit has been reached, in which case the
+// request should be rejected with newRateLimitError. A zero limit is never
+// reached
+func (u *urlHandler) takeRateLimit(w http.ResponseWriter, r *http.Request,
+	scope rateLimitScope, limit uint64,
+	reference string, userID uuid.UUID,
+) (bool, error) {
+	if limit == 0 {
+		return false, nil
+	}
+
+	key := rateLimitKey(r, scope, u.rateLimitKeys(scope), reference, userID)
+
+	tokens, remaining, reset, ok, err := u.rateLimiter.Take(r.Context(), key, limit)
+	if err != nil {
+		return false, err
+	}
+
+	writeRateLimitHeaders(w, tokens, remaining, reset, ok)
+
+	if !ok {
+		rateLimitedHTTPRequestsCounter.WithLabelValues(string(scope)).Inc()
+
+		if scope == rateLimitScopeIngest {
+			u.rateLimiter.reject(rejectedKey(r, u.rateLimitKeys(scope), reference, userID),
+				reference, userID, tokens, reset)
+		}
+	}
+
+	return !ok, nil
+}
+
+func newRateLimitError() APIError {
+	return newAPIError(http.StatusTooManyRequests,
+		"too many requests, please try again later")
+}
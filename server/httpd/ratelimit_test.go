@@ -0,0 +1,362 @@
+package httpd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/pubsub"
+	"github.com/adelowo/sdump/mocks"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/r3labs/sse/v2"
+	"github.com/sethvargo/go-limiter"
+	"github.com/sethvargo/go-limiter/memorystore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type publisherFunc func(context.Context, *sdump.Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, event *sdump.Event) error {
+	return f(ctx, event)
+}
+
+func newTestRateLimiter(t *testing.T) *RateLimiter {
+	t.Helper()
+
+	rateLimiter := NewRateLimiter(func(tokens uint64) (limiter.Store, error) {
+		return memorystore.New(&memorystore.Config{
+			Tokens:   tokens,
+			Interval: time.Minute,
+		})
+	})
+
+	t.Cleanup(func() {
+		require.NoError(t, rateLimiter.Close(context.Background()))
+	})
+
+	return rateLimiter
+}
+
+func TestRateLimit_Ingest(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	tt := []struct {
+		name          string
+		owner         *sdump.User
+		plan          *sdump.Plan
+		expectedLimit int
+	}{
+		{
+			name:          "instance limit",
+			owner:         &sdump.User{},
+			plan:          &sdump.Plan{},
+			expectedLimit: 1,
+		},
+		{
+			name:          "plan limit",
+			owner:         &sdump.User{},
+			plan:          &sdump.Plan{IngestRequestsPerMinute: 3},
+			expectedLimit: 3,
+		},
+		{
+			name:          "user limit",
+			owner:         &sdump.User{IngestRequestsPerMinute: 2},
+			plan:          &sdump.Plan{IngestRequestsPerMinute: 3},
+			expectedLimit: 2,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			endpoint := &sdump.URLEndpoint{
+				Reference: "cmltfm6g330l5l1vq110",
+				UserID:    uuid.New(),
+				IsActive:  true,
+				// paused endpoints reply without storing the request
+				IsPaused: true,
+			}
+
+			// requests after the first rejected one are turned away
+			// before the endpoint is looked up
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(v.expectedLimit+1).
+				Return(endpoint, nil)
+
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(v.owner, nil)
+
+			planRepo := mocks.NewMockPlanRepository(ctrl)
+			planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(v.plan, nil)
+
+			var cfg config.Config
+			cfg.HTTP.RateLimit.RequestsPerMinute = 1
+
+			events := make(chan *sdump.Event, 2)
+
+			publisher := publisherFunc(func(_ context.Context, event *sdump.Event) error {
+				events <- event
+				return nil
+			})
+
+			handler := buildRoutes(cfg, logger, urlRepo,
+				mocks.NewMockIngestRepository(ctrl), userRepo,
+				mocks.NewMockAPITokenRepository(ctrl), planRepo,
+				sse.New(), publisher, newTestRateLimiter(t), NewForwarder(1, 1))
+
+			limited := testutil.ToFloat64(rateLimitedHTTPRequestsCounter.WithLabelValues("ingest"))
+
+			for i := 0; i < v.expectedLimit; i++ {
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/cmltfm6g330l5l1vq110", nil))
+
+				require.Equal(t, http.StatusAccepted, recorder.Result().StatusCode)
+				require.Equal(t, strconv.Itoa(v.expectedLimit), recorder.Header().Get("X-RateLimit-Limit"))
+				require.NotEmpty(t, recorder.Header().Get("X-RateLimit-Reset"))
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/cmltfm6g330l5l1vq110", nil))
+
+			require.Equal(t, http.StatusTooManyRequests, recorder.Result().StatusCode)
+			require.Equal(t, "0", recorder.Header().Get("X-RateLimit-Remaining"))
+			require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			require.Equal(t, limited+1, testutil.ToFloat64(rateLimitedHTTPRequestsCounter.WithLabelValues("ingest")))
+
+			select {
+			case event := <-events:
+				require.Equal(t, endpoint.PubChannel(), event.Channel)
+				require.Equal(t, sdump.EventTypeDropped, event.Type)
+
+				var dropped droppedEvent
+				require.NoError(t, json.Unmarshal(event.Data, &dropped))
+				require.Equal(t, http.MethodPost, dropped.Method)
+
+			case <-time.After(5 * time.Second):
+				t.Fatal("dropped request was not published")
+			}
+
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/cmltfm6g330l5l1vq110", nil))
+
+			require.Equal(t, http.StatusTooManyRequests, recorder.Result().StatusCode)
+			require.Equal(t, strconv.Itoa(v.expectedLimit), recorder.Header().Get("X-RateLimit-Limit"))
+			require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			require.Equal(t, limited+2, testutil.ToFloat64(rateLimitedHTTPRequestsCounter.WithLabelValues("ingest")))
+		})
+	}
+}
+
+func TestRateLimit_API(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepo := mocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(&sdump.User{ID: uuid.New()}, nil)
+
+	planRepo := mocks.NewMockPlanRepository(ctrl)
+	planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(nil, sdump.ErrPlanNotFound)
+
+	var cfg config.Config
+	cfg.HTTP.AdminSecret = testAdminSecret
+	cfg.HTTP.RateLimit.API.RequestsPerMinute = 1
+
+	handler := buildRoutes(cfg, logger, mocks.NewMockURLRepository(ctrl),
+		mocks.NewMockIngestRepository(ctrl), userRepo,
+		mocks.NewMockAPITokenRepository(ctrl), planRepo,
+		sse.New(), pubsub.NewMemory(), newTestRateLimiter(t), NewForwarder(1, 1))
+
+	limited := testutil.ToFloat64(rateLimitedHTTPRequestsCounter.WithLabelValues("api"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	authenticateAsSSHUser(req, "SHA256:Jn3kyWeH4PaT/7v8T1SgVCh8MXm8b4LgFrV1zU/TJ5k")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	require.Equal(t, "1", recorder.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "0", recorder.Header().Get("X-RateLimit-Remaining"))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusTooManyRequests, recorder.Result().StatusCode)
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	require.Equal(t, limited+1, testutil.ToFloat64(rateLimitedHTTPRequestsCounter.WithLabelValues("api")))
+}
+
+func TestRateLimitKey(t *testing.T) {
+	userID := uuid.MustParse("0b9b46f5-4dd4-4a25-8a4a-58b5b1b0a5f4")
+
+	tt := []struct {
+		name     string
+		keys     []config.RateLimitKey
+		expected string
+	}{
+		{
+			name:     "endpoint",
+			keys:     []config.RateLimitKey{config.RateLimitKeyEndpoint},
+			expected: "ingest|endpoint=cmltfm6g330l5l1vq110",
+		},
+		{
+			name:     "ip",
+			keys:     []config.RateLimitKey{config.RateLimitKeyIp},
+			expected: "ingest|ip=203.0.113.7",
+		},
+		{
+			name:     "user",
+			keys:     []config.RateLimitKey{config.RateLimitKeyUser},
+			expected: "ingest|user=0b9b46f5-4dd4-4a25-8a4a-58b5b1b0a5f4",
+		},
+		{
+			name:     "endpoint and ip",
+			keys:     []config.RateLimitKey{config.RateLimitKeyEndpoint, config.RateLimitKeyIp},
+			expected: "ingest|endpoint=cmltfm6g330l5l1vq110|ip=203.0.113.7",
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/cmltfm6g330l5l1vq110", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+			require.Equal(t, v.expected, rateLimitKey(req, rateLimitScopeIngest, v.keys,
+				"cmltfm6g330l5l1vq110", userID))
+		})
+	}
+}
+
+func TestRejectedKey(t *testing.T) {
+	userID := uuid.MustParse("0b9b46f5-4dd4-4a25-8a4a-58b5b1b0a5f4")
+
+	tt := []struct {
+		name     string
+		keys     []config.RateLimitKey
+		expected string
+	}{
+		{
+			name:     "endpoint",
+			keys:     []config.RateLimitKey{config.RateLimitKeyEndpoint},
+			expected: "ingest|endpoint=cmltfm6g330l5l1vq110",
+		},
+		{
+			name:     "ip is scoped to the endpoint",
+			keys:     []config.RateLimitKey{config.RateLimitKeyIp},
+			expected: "ingest|ip=203.0.113.7|endpoint=cmltfm6g330l5l1vq110",
+		},
+		{
+			name:     "user",
+			keys:     []config.RateLimitKey{config.RateLimitKeyUser},
+			expected: "ingest|user=0b9b46f5-4dd4-4a25-8a4a-58b5b1b0a5f4",
+		},
+		{
+			name:     "ip and user",
+			keys:     []config.RateLimitKey{config.RateLimitKeyIp, config.RateLimitKeyUser},
+			expected: "ingest|ip=203.0.113.7|user=0b9b46f5-4dd4-4a25-8a4a-58b5b1b0a5f4",
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/cmltfm6g330l5l1vq110", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+			require.Equal(t, v.expected, rejectedKey(req, v.keys,
+				"cmltfm6g330l5l1vq110", userID))
+		})
+	}
+}
+
+func TestRateLimit_IngestByUser(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	owner := &sdump.User{ID: uuid.New()}
+
+	endpoints := map[string]*sdump.URLEndpoint{
+		"cmltfm6g330l5l1vq110": {Reference: "cmltfm6g330l5l1vq110", UserID: owner.ID, IsActive: true, IsPaused: true},
+		"cmltfm6g330l5l1vq111": {Reference: "cmltfm6g330l5l1vq111", UserID: owner.ID, IsActive: true, IsPaused: true},
+		"cmltfm6g330l5l1vq112": {Reference: "cmltfm6g330l5l1vq112", UserID: uuid.New(), IsActive: true, IsPaused: true},
+	}
+
+	lookups := map[string]int{}
+
+	urlRepo := mocks.NewMockURLRepository(ctrl)
+	urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, opts *sdump.FindURLOptions) (*sdump.URLEndpoint, error) {
+			lookups[opts.Reference]++
+			return endpoints[opts.Reference], nil
+		})
+
+	userRepo := mocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(owner, nil)
+
+	planRepo := mocks.NewMockPlanRepository(ctrl)
+	planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(&sdump.Plan{}, nil)
+
+	var cfg config.Config
+	cfg.HTTP.RateLimit.RequestsPerMinute = 1
+	cfg.HTTP.RateLimit.Keys = []config.RateLimitKey{config.RateLimitKeyUser}
+
+	handler := buildRoutes(cfg, logger, urlRepo,
+		mocks.NewMockIngestRepository(ctrl), userRepo,
+		mocks.NewMockAPITokenRepository(ctrl), planRepo,
+		sse.New(), pubsub.NewMemory(), newTestRateLimiter(t), NewForwarder(1, 1))
+
+	send := func(reference string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/"+reference, nil))
+		return recorder.Result().StatusCode
+	}
+
+	require.Equal(t, http.StatusAccepted, send("cmltfm6g330l5l1vq110"))
+	require.Equal(t, http.StatusTooManyRequests, send("cmltfm6g330l5l1vq110"))
+	require.Equal(t, http.StatusTooManyRequests, send("cmltfm6g330l5l1vq110"))
+	require.Equal(t, 2, lookups["cmltfm6g330l5l1vq110"])
+
+	// the owner's other endpoint shares the bucket and is looked up once
+	// before its owner is known
+	require.Equal(t, http.StatusTooManyRequests, send("cmltfm6g330l5l1vq111"))
+	require.Equal(t, http.StatusTooManyRequests, send("cmltfm6g330l5l1vq111"))
+	require.Equal(t, 1, lookups["cmltfm6g330l5l1vq111"])
+
+	// other users are not affected
+	require.Equal(t, http.StatusAccepted, send("cmltfm6g330l5l1vq112"))
+}
@@ -0,0 +1,298 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adelowo/sdump"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	defaultRequestsPerPage = 25
+	maxRequestsPerPage     = 100
+)
+
+// listRequests returns the endpoint's ingested requests that match the
+// filters in the query string, newest first
+func (u *urlHandler) listRequests(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.listRequests")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.listRequests").
+		WithField("reference", endpoint.Reference)
+
+	opts := &sdump.SearchIngestedRequestOptions{
+		URLID:  endpoint.ID,
+		Limit:  defaultRequestsPerPage,
+		Method: r.URL.Query().Get("method"),
+		Path:   r.URL.Query().Get("path"),
+		Text:   r.URL.Query().Get("q"),
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxRequestsPerPage {
+			span.SetStatus(codes.Error, "invalid limit")
+			_ = render.Render(w, r, newAPIError(http.StatusBadRequest,
+				fmt.Sprintf("limit must be a number between 1 and %d", maxRequestsPerPage)))
+			return
+		}
+
+		opts.Limit = n
+	}
+
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		id, err := uuid.Parse(cursor)
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid cursor")
+			_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "please provide a valid cursor"))
+			return
+		}
+
+		opts.Cursor = id
+	}
+
+	for key, value := range map[string]*time.Time{
+		"since": &opts.Since,
+		"until": &opts.Until,
+	} {
+		v := r.URL.Query().Get(key)
+		if v == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid time filter")
+			_ = render.Render(w, r, newAPIError(http.StatusBadRequest,
+				fmt.Sprintf("%s must be an RFC3339 timestamp", key)))
+			return
+		}
+
+		*value = t
+	}
+
+	if header := r.URL.Query().Get("header"); header != "" {
+		name, value, _ := strings.Cut(header, "=")
+		if !isValidHeaderName(name) {
+			span.SetStatus(codes.Error, "invalid header filter")
+			_ = render.Render(w, r, newAPIError(http.StatusBadRequest,
+				"header must be a header name or name=value"))
+			return
+		}
+
+		opts.HeaderName, opts.HeaderValue = name, value
+	}
+
+	if ip := r.URL.Query().Get("ip"); ip != "" {
+		network, err := parseIPNetwork(ip)
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid ip filter")
+			_ = render.Render(w, r, newAPIError(http.StatusBadRequest,
+				"ip must be an ip address or a range such as 10.0.0.0/8"))
+			return
+		}
+
+		opts.IPNetwork = network
+	}
+
+	if jsonFilter := r.URL.Query().Get("json"); jsonFilter != "" {
+		path, value, hasValue := strings.Cut(jsonFilter, "=")
+		if !hasValue || path == "" {
+			span.SetStatus(codes.Error, "invalid json filter")
+			_ = render.Render(w, r, newAPIError(http.StatusBadRequest,
+				"json must be a path and value such as data.object.id=evt_1"))
+			return
+		}
+
+		opts.JSONPath, opts.JSONValue = path, value
+	}
+
+	requests, err := u.ingestRepo.Search(ctx, opts)
+	if err != nil {
+		logger.WithError(err).Error("could not list ingested requests")
+		span.SetStatus(codes.Error, "could not list ingested requests")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while fetching requests"))
+		return
+	}
+
+	if requests == nil {
+		requests = []sdump.IngestHTTPRequest{}
+	}
+
+	var nextCursor string
+	if len(requests) == opts.Limit {
+		nextCursor = requests[len(requests)-1].ID.String()
+	}
+
+	span.SetStatus(codes.Ok, "fetched ingested requests")
+	_ = render.Render(w, r, &ingestedRequestsResponse{
+		APIStatus:  newAPIStatus(http.StatusOK, "fetched ingested requests"),
+		Requests:   requests,
+		NextCursor: nextCursor,
+	})
+}
+
+// parseIPNetwork parses a CIDR range. A single address is treated as a
+// range that only contains it
+func parseIPNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		return network, err
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.New("invalid ip address")
+	}
+
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 8*net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// isValidHeaderName checks that s only contains the characters allowed in
+// http header names
+func isValidHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r > unicode.MaxASCII ||
+			!(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deleteRequests removes every request the endpoint has ingested
+func (u *urlHandler) deleteRequests(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.deleteRequests")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.deleteRequests").
+		WithField("reference", endpoint.Reference)
+
+	_, err := u.ingestRepo.Delete(ctx, &sdump.DeleteIngestedRequestOptions{
+		URLID:          endpoint.ID,
+		UseSoftDeletes: u.cfg.Cron.SoftDeletes,
+	})
+	if err != nil {
+		logger.WithError(err).Error("could not delete ingested requests")
+		span.SetStatus(codes.Error, "could not delete ingested requests")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while deleting requests"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "deleted ingested requests")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "deleted ingested requests"))
+}
+
+// requireIngestedRequest fetches the request in the path. It must belong to
+// the endpoint in the path
+func (u *urlHandler) requireIngestedRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span, requestID := getTracer(r.Context(), r, "url.requireIngestedRequest")
+		defer span.End()
+
+		endpoint := endpointFromContext(ctx)
+
+		logger := u.logger.WithField("request_id", requestID).
+			WithField("method", "urlHandler.requireIngestedRequest").
+			WithField("reference", endpoint.Reference)
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid request id")
+			_ = render.Render(w, r, newAPIError(http.StatusNotFound, "request does not exist"))
+			return
+		}
+
+		ingestedRequest, err := u.ingestRepo.Get(ctx, &sdump.FindIngestedRequestOptions{
+			ID:    id,
+			URLID: endpoint.ID,
+		})
+		if errors.Is(err, sdump.ErrIngestedRequestNotFound) {
+			span.SetStatus(codes.Error, "request not found")
+			_ = render.Render(w, r, newAPIError(http.StatusNotFound, "request does not exist"))
+			return
+		}
+
+		if err != nil {
+			logger.WithError(err).Error("could not fetch ingested request")
+			span.SetStatus(codes.Error, "could not fetch ingested request")
+			_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+				"an error occurred while fetching request"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, ingestedRequestCtxKey, ingestedRequest)))
+	})
+}
+
+func ingestedRequestFromContext(ctx context.Context) *sdump.IngestHTTPRequest {
+	return ctx.Value(ingestedRequestCtxKey).(*sdump.IngestHTTPRequest)
+}
+
+func (u *urlHandler) getRequest(w http.ResponseWriter, r *http.Request) {
+	_, span, _ := getTracer(r.Context(), r, "url.getRequest")
+	defer span.End()
+
+	span.SetStatus(codes.Ok, "fetched ingested request")
+	_ = render.Render(w, r, &ingestedRequestResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched ingested request"),
+		Request:   ingestedRequestFromContext(r.Context()),
+	})
+}
+
+func (u *urlHandler) deleteRequest(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.deleteRequest")
+	defer span.End()
+
+	ingestedRequest := ingestedRequestFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.deleteRequest").
+		WithField("ingested_request_id", ingestedRequest.ID)
+
+	_, err := u.ingestRepo.Delete(ctx, &sdump.DeleteIngestedRequestOptions{
+		ID:             ingestedRequest.ID,
+		URLID:          ingestedRequest.UrlID,
+		UseSoftDeletes: u.cfg.Cron.SoftDeletes,
+	})
+	if err != nil {
+		logger.WithError(err).Error("could not delete ingested request")
+		span.SetStatus(codes.Error, "could not delete ingested request")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while deleting request"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "deleted ingested request")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "deleted ingested request"))
+}
@@ -0,0 +1,391 @@
+package httpd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/r3labs/sse/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestURLHandler_ListRequests(t *testing.T) {
+	ownerID := uuid.New()
+
+	createdAt := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name               string
+		mockFn             func(ingestRepo *mocks.MockIngestRepository)
+		expectedStatusCode int
+		query              string
+	}{
+		{
+			name: "invalid limit",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			query:              "?limit=1000",
+		},
+		{
+			name: "invalid cursor",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			query:              "?cursor=oops",
+		},
+		{
+			name: "invalid since filter",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			query:              "?since=yesterday",
+		},
+		{
+			name: "filters are applied",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Search(gomock.Any(), &sdump.SearchIngestedRequestOptions{
+					Limit:  defaultRequestsPerPage,
+					Method: "POST",
+					Path:   "/github",
+					Since:  createdAt,
+				}).
+					Times(1).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			query:              "?method=POST&path=/github&since=2024-03-10T12:00:00Z",
+		},
+		{
+			name: "invalid header filter",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			query:              "?header=X%20GitHub",
+		},
+		{
+			name: "invalid ip filter",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			query:              "?ip=10.0.0",
+		},
+		{
+			name: "invalid json filter",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			query:              "?json=data.object.id",
+		},
+		{
+			name: "search filters are applied",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Search(gomock.Any(), &sdump.SearchIngestedRequestOptions{
+					Limit:       defaultRequestsPerPage,
+					HeaderName:  "X-GitHub-Event",
+					HeaderValue: "push",
+					IPNetwork: &net.IPNet{
+						IP:   net.IPv4(10, 0, 1, 20).To4(),
+						Mask: net.CIDRMask(32, 32),
+					},
+					JSONPath:  "repository.name",
+					JSONValue: "sdump",
+					Text:      "opened",
+				}).
+					Times(1).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			query:              "?header=X-GitHub-Event=push&ip=10.0.1.20&json=repository.name=sdump&q=opened",
+		},
+		{
+			name: "could not list requests",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Search(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errors.New("could not list requests"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "no requests",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Search(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "full page has a next cursor",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Search(gomock.Any(), &sdump.SearchIngestedRequestOptions{
+					Limit:  1,
+					Cursor: uuid.MustParse("0b7e3fd4-2a4a-4d8f-9c9f-1f6d1a3d6f10"),
+				}).
+					Times(1).
+					Return([]sdump.IngestHTTPRequest{
+						{
+							ID: uuid.MustParse("6f7a3c2e-8f4b-4b7e-a7a1-3a2d8f9e0c11"),
+							Request: sdump.RequestDefinition{
+								Method: http.MethodPost,
+								Body:   "{}",
+							},
+							CreatedAt: createdAt,
+							UpdatedAt: createdAt,
+						},
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			query:              "?limit=1&cursor=0b7e3fd4-2a4a-4d8f-9c9f-1f6d1a3d6f10",
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/urls/cmltfm6g330l5l1vq110/requests"+v.query, nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			ingestRepo := mocks.NewMockIngestRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+			v.mockFn(ingestRepo)
+
+			u := &urlHandler{
+				logger:     logger,
+				cfg:        config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:    urlRepo,
+				userRepo:   userRepo,
+				ingestRepo: ingestRepo,
+				sseServer:  sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Get("/api/v1/urls/{reference}/requests", u.listRequests)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_GetRequest(t *testing.T) {
+	ownerID := uuid.New()
+
+	createdAt := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name               string
+		mockFn             func(ingestRepo *mocks.MockIngestRepository)
+		expectedStatusCode int
+		id                 string
+	}{
+		{
+			name: "invalid request id",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+			},
+			expectedStatusCode: http.StatusNotFound,
+			id:                 "oops",
+		},
+		{
+			name: "request not found",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sdump.ErrIngestedRequestNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+			id:                 "6f7a3c2e-8f4b-4b7e-a7a1-3a2d8f9e0c11",
+		},
+		{
+			name: "could not fetch request",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errors.New("could not fetch request"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			id:                 "6f7a3c2e-8f4b-4b7e-a7a1-3a2d8f9e0c11",
+		},
+		{
+			name: "request fetched",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Get(gomock.Any(), &sdump.FindIngestedRequestOptions{
+					ID: uuid.MustParse("6f7a3c2e-8f4b-4b7e-a7a1-3a2d8f9e0c11"),
+				}).
+					Times(1).
+					Return(&sdump.IngestHTTPRequest{
+						ID: uuid.MustParse("6f7a3c2e-8f4b-4b7e-a7a1-3a2d8f9e0c11"),
+						Request: sdump.RequestDefinition{
+							Method: http.MethodPost,
+							Body:   "{}",
+						},
+						CreatedAt: createdAt,
+						UpdatedAt: createdAt,
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			id:                 "6f7a3c2e-8f4b-4b7e-a7a1-3a2d8f9e0c11",
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/urls/cmltfm6g330l5l1vq110/requests/"+v.id, nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			ingestRepo := mocks.NewMockIngestRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+			v.mockFn(ingestRepo)
+
+			u := &urlHandler{
+				logger:     logger,
+				cfg:        config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:    urlRepo,
+				userRepo:   userRepo,
+				ingestRepo: ingestRepo,
+				sseServer:  sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner, u.requireIngestedRequest).
+				Get("/api/v1/urls/{reference}/requests/{id}", u.getRequest)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_DeleteRequests(t *testing.T) {
+	ownerID := uuid.New()
+	endpointID := uuid.New()
+
+	tt := []struct {
+		name               string
+		mockFn             func(ingestRepo *mocks.MockIngestRepository)
+		expectedStatusCode int
+	}{
+		{
+			name: "could not delete requests",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), errors.New("could not delete requests"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "requests deleted",
+			mockFn: func(ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Delete(gomock.Any(), &sdump.DeleteIngestedRequestOptions{
+					URLID: endpointID,
+				}).
+					Times(1).
+					Return(int64(3), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/urls/cmltfm6g330l5l1vq110/requests", nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			ingestRepo := mocks.NewMockIngestRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{ID: endpointID, UserID: ownerID}, nil)
+
+			v.mockFn(ingestRepo)
+
+			u := &urlHandler{
+				logger:     logger,
+				cfg:        config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:    urlRepo,
+				userRepo:   userRepo,
+				ingestRepo: ingestRepo,
+				sseServer:  sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Delete("/api/v1/urls/{reference}/requests", u.deleteRequests)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
@@ -2,7 +2,9 @@ package httpd
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/adelowo/sdump"
 	"github.com/go-chi/render"
 )
 
@@ -18,6 +20,9 @@ func (a APIStatus) Render(w http.ResponseWriter, r *http.Request) error {
 
 type APIError struct {
 	APIStatus
+
+	// Code tells errors with the same status apart
+	Code string `json:"code,omitempty"`
 }
 
 func newAPIStatus(code int, s string) APIStatus {
@@ -36,14 +41,95 @@ func newAPIError(code int, s string) APIError {
 	}
 }
 
+// streamDefinition is what is needed to subscribe to an endpoint's
+// requests
+type streamDefinition struct {
+	Channel   string    `json:"channel,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
 type createdURLEndpointResponse struct {
 	URL struct {
 		FQDN                  string `json:"fqdn,omitempty"`
 		Identifier            string `json:"identifier,omitempty"`
+		Name                  string `json:"name,omitempty"`
+		IsPaused              bool   `json:"is_paused,omitempty"`
 		HumanReadableEndpoint string `json:"human_readable_endpoint,omitempty"`
 	} `json:"url,omitempty"`
-	SSE struct {
-		Channel string `json:"channel,omitempty"`
-	} `json:"sse,omitempty"`
+	SSE streamDefinition `json:"sse,omitempty"`
+	APIStatus
+}
+
+type streamResponse struct {
+	SSE streamDefinition `json:"sse"`
+	APIStatus
+}
+
+type endpointResponseDefinition struct {
+	Response *sdump.ResponseDefinition `json:"response"`
+	APIStatus
+}
+
+type endpointForwardDefinition struct {
+	Forward *sdump.ForwardDefinition `json:"forward"`
+	APIStatus
+}
+
+type endpointTunnelDefinition struct {
+	Tunnel *sdump.ForwardDefinition `json:"tunnel"`
+	APIStatus
+}
+
+type endpointLimitsDefinition struct {
+	Limits *sdump.LimitsDefinition `json:"limits"`
+	APIStatus
+}
+
+type ingestedRequestsResponse struct {
+	Requests []sdump.IngestHTTPRequest `json:"requests"`
+
+	// NextCursor fetches the next page of older requests. It is empty
+	// when there are no more requests
+	NextCursor string `json:"next_cursor,omitempty"`
+	APIStatus
+}
+
+type ingestedRequestResponse struct {
+	Request *sdump.IngestHTTPRequest `json:"request"`
+	APIStatus
+}
+
+type urlEndpointResponse struct {
+	URL *sdump.URLEndpoint `json:"url"`
+	APIStatus
+}
+
+type urlEndpointsResponse struct {
+	URLs []sdump.URLEndpoint `json:"urls"`
+	APIStatus
+}
+
+type apiTokensResponse struct {
+	Tokens []sdump.APIToken `json:"tokens"`
+	APIStatus
+}
+
+type createdAPITokenResponse struct {
+	Token *sdump.APIToken `json:"token"`
+
+	// Secret is the plain text token. It is only available when the token
+	// is created
+	Secret string `json:"secret"`
+	APIStatus
+}
+
+type userResponse struct {
+	User *sdump.User `json:"user"`
+	APIStatus
+}
+
+type quotaResponse struct {
+	Quota *sdump.Quota `json:"quota"`
 	APIStatus
 }
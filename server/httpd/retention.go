@@ -0,0 +1,260 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/sirupsen/logrus"
+)
+
+type pruneReason string
+
+const (
+	pruneReasonTTL      pruneReason = "ttl"
+	pruneReasonPlan     pruneReason = "plan"
+	pruneReasonEndpoint pruneReason = "endpoint"
+	pruneReasonPurge    pruneReason = "purge"
+)
+
+// PruneResult is how many requests were deleted by a run of the Pruner
+type PruneResult struct {
+	// TTL is how many requests were older than cron.ttl
+	TTL int64
+	// Plan is how many requests were older than their owner's plan keeps
+	// them for
+	Plan int64
+	// Endpoint is how many requests were older than their endpoint keeps
+	// them for
+	Endpoint int64
+	// Purged is how many soft deleted requests or requests of deleted
+	// endpoints were removed for good
+	Purged int64
+}
+
+// pruneLockName is the lock held by the HTTP server that is pruning
+const pruneLockName = "sdump.prune"
+
+var ErrPruneLocked = errors.New("another process is pruning ingested requests")
+
+// Pruner deletes the requests that are older than the instance, their
+// owner's plan or their endpoint keeps them for
+type Pruner struct {
+	cfg        config.CronConfig
+	ingestRepo sdump.IngestRepository
+	planRepo   sdump.PlanRepository
+	urlRepo    sdump.URLRepository
+	locker     sdump.Locker
+	logger     *logrus.Entry
+}
+
+func NewPruner(cfg config.CronConfig,
+	ingestRepo sdump.IngestRepository,
+	planRepo sdump.PlanRepository,
+	urlRepo sdump.URLRepository,
+	locker sdump.Locker,
+	logger *logrus.Entry,
+) *Pruner {
+	return &Pruner{
+		cfg:        cfg,
+		ingestRepo: ingestRepo,
+		planRepo:   planRepo,
+		urlRepo:    urlRepo,
+		locker:     locker,
+		logger:     logger.WithField("module", "pruner"),
+	}
+}
+
+// Run prunes every cron.interval until the context is cancelled. The first
+// run happens right away. When running more than one HTTP server, only the
+// one that gets the lock prunes
+func (p *Pruner) Run(ctx context.Context) {
+	if p.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pruner) run(ctx context.Context) {
+	result, err := p.PruneWithLock(ctx)
+	if errors.Is(err, ErrPruneLocked) {
+		p.logger.Debug("another server is pruning ingested requests")
+		return
+	}
+
+	if err != nil {
+		if ctx.Err() == nil {
+			failedPrunesCounter.Inc()
+			p.logger.WithError(err).Error("could not prune ingested requests")
+		}
+
+		return
+	}
+
+	p.logger.WithField("ttl", result.TTL).
+		WithField("plan", result.Plan).
+		WithField("endpoint", result.Endpoint).
+		WithField("purged", result.Purged).
+		Info("pruned ingested requests")
+}
+
+// PruneWithLock prunes once while holding the same lock as Run so it never
+// runs alongside an HTTP server that is pruning. ErrPruneLocked is
+// returned if another process holds the lock
+func (p *Pruner) PruneWithLock(ctx context.Context) (PruneResult, error) {
+	unlock, locked, err := p.locker.TryLock(ctx, pruneLockName)
+	if err != nil {
+		return PruneResult{}, fmt.Errorf("could not take the prune lock... %w", err)
+	}
+
+	if !locked {
+		return PruneResult{}, ErrPruneLocked
+	}
+
+	defer unlock()
+
+	return p.Prune(ctx)
+}
+
+// Prune deletes expired requests once. What was deleted before an error
+// is still counted in the result. It does not take the prune lock
+func (p *Pruner) Prune(ctx context.Context) (PruneResult, error) {
+	var result PruneResult
+
+	now := time.Now()
+
+	if p.cfg.TTL > 0 {
+		n, err := p.deleteInBatches(ctx, pruneReasonTTL, &sdump.DeleteIngestedRequestOptions{
+			Before:         now.Add(-1 * p.cfg.TTL),
+			UseSoftDeletes: p.cfg.SoftDeletes,
+		})
+		result.TTL = n
+		if err != nil {
+			return result, err
+		}
+	}
+
+	plans, err := p.planRepo.List(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	// plans can only keep requests for less time than cron.ttl
+	for i := range plans {
+		plan := &plans[i]
+
+		if plan.RetentionDays <= 0 || (p.cfg.TTL > 0 && plan.Retention() >= p.cfg.TTL) {
+			continue
+		}
+
+		n, err := p.deleteInBatches(ctx, pruneReasonPlan, &sdump.DeleteIngestedRequestOptions{
+			Before:         now.Add(-1 * plan.Retention()),
+			UseSoftDeletes: p.cfg.SoftDeletes,
+			Plan:           plan,
+		})
+		result.Plan += n
+		if err != nil {
+			return result, err
+		}
+	}
+
+	endpoints, err := p.urlRepo.List(ctx, &sdump.ListURLOptions{
+		HasRetention: true,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// endpoints can also only keep requests for less time than
+	// cron.ttl and their owner's plan. The plan is enforced above
+	for _, endpoint := range endpoints {
+		retention := endpoint.Metadata.Limits.Retention()
+
+		if retention <= 0 || (p.cfg.TTL > 0 && retention >= p.cfg.TTL) {
+			continue
+		}
+
+		n, err := p.deleteInBatches(ctx, pruneReasonEndpoint, &sdump.DeleteIngestedRequestOptions{
+			Before:         now.Add(-1 * retention),
+			URLID:          endpoint.ID,
+			UseSoftDeletes: p.cfg.SoftDeletes,
+		})
+		result.Endpoint += n
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if p.cfg.PurgeAfter > 0 {
+		n, err := p.purgeInBatches(ctx, &sdump.PurgeIngestedRequestOptions{
+			Before: now.Add(-1 * p.cfg.PurgeAfter),
+		})
+		result.Purged = n
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func (p *Pruner) deleteInBatches(ctx context.Context, reason pruneReason,
+	opts *sdump.DeleteIngestedRequestOptions,
+) (int64, error) {
+	opts.Limit = p.cfg.BatchSize
+
+	return inBatches(ctx, p.cfg.BatchSize, reason, func() (int64, error) {
+		return p.ingestRepo.Delete(ctx, opts)
+	})
+}
+
+func (p *Pruner) purgeInBatches(ctx context.Context,
+	opts *sdump.PurgeIngestedRequestOptions,
+) (int64, error) {
+	opts.Limit = p.cfg.BatchSize
+
+	return inBatches(ctx, p.cfg.BatchSize, pruneReasonPurge, func() (int64, error) {
+		return p.ingestRepo.Purge(ctx, opts)
+	})
+}
+
+// inBatches calls fn until it deletes less than a full batch. fn is only
+// called once if there is no batch size
+func inBatches(ctx context.Context, batchSize int, reason pruneReason,
+	fn func() (int64, error),
+) (int64, error) {
+	var total int64
+
+	for {
+		n, err := fn()
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		prunedHTTPRequestsCounter.WithLabelValues(string(reason)).Add(float64(n))
+
+		if batchSize <= 0 || n < int64(batchSize) {
+			return total, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/mocks"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type fakeLocker struct {
+	isLockedElsewhere bool
+	unlocked          bool
+}
+
+func (l *fakeLocker) TryLock(_ context.Context, _ string) (func(), bool, error) {
+	if l.isLockedElsewhere {
+		return nil, false, nil
+	}
+
+	return func() { l.unlocked = true }, true, nil
+}
+
+func TestPruner_Prune(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.CronConfig{
+		TTL:        48 * time.Hour,
+		BatchSize:  2,
+		PurgeAfter: 7 * 24 * time.Hour,
+	}
+
+	shortPlan := sdump.Plan{ID: uuid.New(), RetentionDays: 1}
+	// plans keeping requests for longer than cron.ttl are left to it
+	longPlan := sdump.Plan{ID: uuid.New(), RetentionDays: 7}
+
+	endpoint := sdump.URLEndpoint{
+		ID: uuid.New(),
+		Metadata: sdump.URLEndpointMetadata{
+			Limits: &sdump.LimitsDefinition{RetentionHours: 6},
+		},
+	}
+
+	planRepo := mocks.NewMockPlanRepository(ctrl)
+	planRepo.EXPECT().List(gomock.Any()).
+		Times(1).
+		Return([]sdump.Plan{shortPlan, longPlan}, nil)
+
+	urlRepo := mocks.NewMockURLRepository(ctrl)
+	urlRepo.EXPECT().List(gomock.Any(), &sdump.ListURLOptions{HasRetention: true}).
+		Times(1).
+		Return([]sdump.URLEndpoint{endpoint}, nil)
+
+	ingestRepo := mocks.NewMockIngestRepository(ctrl)
+
+	isTTL := gomock.Cond(func(x any) bool {
+		opts := x.(*sdump.DeleteIngestedRequestOptions)
+		return opts.Plan == nil && opts.URLID == uuid.Nil && opts.Limit == 2
+	})
+
+	// full batches are followed by another one until a partial one
+	gomock.InOrder(
+		ingestRepo.EXPECT().Delete(gomock.Any(), isTTL).Return(int64(2), nil),
+		ingestRepo.EXPECT().Delete(gomock.Any(), isTTL).Return(int64(2), nil),
+		ingestRepo.EXPECT().Delete(gomock.Any(), isTTL).Return(int64(1), nil),
+	)
+
+	ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Cond(func(x any) bool {
+		opts := x.(*sdump.DeleteIngestedRequestOptions)
+		return opts.Plan != nil && opts.Plan.ID == shortPlan.ID
+	})).
+		Times(1).
+		Return(int64(1), nil)
+
+	ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Cond(func(x any) bool {
+		return x.(*sdump.DeleteIngestedRequestOptions).URLID == endpoint.ID
+	})).
+		Times(1).
+		Return(int64(0), nil)
+
+	ingestRepo.EXPECT().Purge(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(1), nil)
+
+	purged := testutil.ToFloat64(prunedHTTPRequestsCounter.WithLabelValues("purge"))
+
+	result, err := NewPruner(cfg, ingestRepo, planRepo, urlRepo, &fakeLocker{}, logger).
+		Prune(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, PruneResult{
+		TTL:    5,
+		Plan:   1,
+		Purged: 1,
+	}, result)
+
+	require.Equal(t, purged+1, testutil.ToFloat64(prunedHTTPRequestsCounter.WithLabelValues("purge")))
+}
+
+func TestPruner_Prune_Error(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ingestRepo := mocks.NewMockIngestRepository(ctrl)
+
+	gomock.InOrder(
+		ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(int64(10), nil),
+		ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+			Return(int64(0), errors.New("could not delete requests")),
+	)
+
+	result, err := NewPruner(config.CronConfig{TTL: time.Hour, BatchSize: 10}, ingestRepo,
+		mocks.NewMockPlanRepository(ctrl), mocks.NewMockURLRepository(ctrl), &fakeLocker{}, logger).
+		Prune(context.Background())
+	require.Error(t, err)
+
+	// what was deleted before the error is still reported
+	require.Equal(t, int64(10), result.TTL)
+}
+
+func TestPruner_PruneWithLock(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	cfg := config.CronConfig{TTL: time.Hour}
+
+	t.Run("another process holds the lock", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// nothing is deleted
+		_, err := NewPruner(cfg, mocks.NewMockIngestRepository(ctrl),
+			mocks.NewMockPlanRepository(ctrl), mocks.NewMockURLRepository(ctrl),
+			&fakeLocker{isLockedElsewhere: true}, logger).
+			PruneWithLock(context.Background())
+		require.ErrorIs(t, err, ErrPruneLocked)
+	})
+
+	t.Run("lock is released after pruning", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ingestRepo := mocks.NewMockIngestRepository(ctrl)
+		ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(int64(0), errors.New("could not delete requests"))
+
+		locker := &fakeLocker{}
+
+		_, err := NewPruner(cfg, ingestRepo, mocks.NewMockPlanRepository(ctrl),
+			mocks.NewMockURLRepository(ctrl), locker, logger).
+			PruneWithLock(context.Background())
+		require.Error(t, err)
+		require.True(t, locker.unlocked)
+	})
+}
+
+func TestPruner_Run(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	cfg := config.CronConfig{TTL: time.Hour}
+
+	t.Run("another server holds the lock", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// nothing is deleted
+		NewPruner(cfg, mocks.NewMockIngestRepository(ctrl),
+			mocks.NewMockPlanRepository(ctrl), mocks.NewMockURLRepository(ctrl),
+			&fakeLocker{isLockedElsewhere: true}, logger).
+			run(context.Background())
+	})
+
+	t.Run("lock is released after pruning", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ingestRepo := mocks.NewMockIngestRepository(ctrl)
+		ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(int64(0), errors.New("could not delete requests"))
+
+		locker := &fakeLocker{}
+
+		failed := testutil.ToFloat64(failedPrunesCounter)
+
+		NewPruner(cfg, ingestRepo, mocks.NewMockPlanRepository(ctrl),
+			mocks.NewMockURLRepository(ctrl), locker, logger).
+			run(context.Background())
+
+		require.True(t, locker.unlocked)
+		require.Equal(t, failed+1, testutil.ToFloat64(failedPrunesCounter))
+	})
+}
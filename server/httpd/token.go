@@ -0,0 +1,149 @@
+package httpd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/adelowo/sdump"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type tokenHandler struct {
+	logger       *logrus.Entry
+	apiTokenRepo sdump.APITokenRepository
+}
+
+type createTokenRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+func (t *tokenHandler) list(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "token.list")
+	defer span.End()
+
+	user := userFromContext(ctx)
+
+	logger := t.logger.WithField("request_id", requestID).
+		WithField("method", "tokenHandler.list").
+		WithField("user_id", user.ID)
+
+	tokens, err := t.apiTokenRepo.List(ctx, user.ID)
+	if err != nil {
+		logger.WithError(err).Error("could not list api tokens")
+		span.SetStatus(codes.Error, "could not list api tokens")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while fetching api tokens"))
+		return
+	}
+
+	if tokens == nil {
+		tokens = []sdump.APIToken{}
+	}
+
+	span.SetStatus(codes.Ok, "fetched api tokens")
+	_ = render.Render(w, r, &apiTokensResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched api tokens"),
+		Tokens:    tokens,
+	})
+}
+
+func (t *tokenHandler) create(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "token.create")
+	defer span.End()
+
+	user := userFromContext(ctx)
+
+	logger := t.logger.WithField("request_id", requestID).
+		WithField("method", "tokenHandler.create").
+		WithField("user_id", user.ID)
+
+	req := new(createTokenRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		span.SetStatus(codes.Error, "invalid request body")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "please provide a valid request body"))
+		return
+	}
+
+	token, plainText, err := sdump.NewAPIToken(user.ID, req.Name)
+	if errors.Is(err, sdump.ErrInvalidTokenName) {
+		span.SetStatus(codes.Error, "invalid token name")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("could not generate api token")
+		span.SetStatus(codes.Error, "could not generate api token")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while creating api token"))
+		return
+	}
+
+	if err := t.apiTokenRepo.Create(ctx, token); err != nil {
+		logger.WithError(err).Error("could not store api token")
+		span.SetStatus(codes.Error, "could not store api token")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while creating api token"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "created api token")
+	_ = render.Render(w, r, &createdAPITokenResponse{
+		APIStatus: newAPIStatus(http.StatusCreated, "created api token. It will not be shown again"),
+		Token:     token,
+		Secret:    plainText,
+	})
+}
+
+func (t *tokenHandler) revoke(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "token.revoke")
+	defer span.End()
+
+	user := userFromContext(ctx)
+
+	logger := t.logger.WithField("request_id", requestID).
+		WithField("method", "tokenHandler.revoke").
+		WithField("user_id", user.ID)
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		span.SetStatus(codes.Error, "invalid token id")
+		_ = render.Render(w, r, newAPIError(http.StatusNotFound, "api token does not exist"))
+		return
+	}
+
+	token, err := t.apiTokenRepo.Find(ctx, &sdump.FindAPITokenOptions{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sdump.ErrAPITokenNotFound) {
+		span.SetStatus(codes.Error, "api token not found")
+		_ = render.Render(w, r, newAPIError(http.StatusNotFound, "api token does not exist"))
+		return
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("could not find api token")
+		span.SetStatus(codes.Error, "could not find api token")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while revoking api token"))
+		return
+	}
+
+	if err := t.apiTokenRepo.Revoke(ctx, token); err != nil {
+		logger.WithError(err).Error("could not revoke api token")
+		span.SetStatus(codes.Error, "could not revoke api token")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while revoking api token"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "revoked api token")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "revoked api token"))
+}
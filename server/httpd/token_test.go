@@ -0,0 +1,303 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// newTokenRouter mounts the token routes behind the same authentication the
+// server uses
+func newTokenRouter(userRepo sdump.UserRepository, apiTokenRepo sdump.APITokenRepository) http.Handler {
+	logrus.SetOutput(io.Discard)
+
+	logger := logrus.WithField("module", "test")
+
+	u := &urlHandler{
+		logger:       logger,
+		cfg:          config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+		userRepo:     userRepo,
+		apiTokenRepo: apiTokenRepo,
+	}
+
+	tokenHandler := &tokenHandler{
+		logger:       logger,
+		apiTokenRepo: apiTokenRepo,
+	}
+
+	router := chi.NewRouter()
+	router.Route("/api/v1/tokens", func(r chi.Router) {
+		r.Use(u.requireSSHUser)
+
+		r.Get("/", tokenHandler.list)
+		r.Post("/", tokenHandler.create)
+		r.Delete("/{id}", tokenHandler.revoke)
+	})
+
+	return router
+}
+
+func TestTokenHandler_List(t *testing.T) {
+	userID := uuid.MustParse("6a1dd2a7-4b16-4a5d-9a52-9f0d1bd3b6c4")
+
+	tt := []struct {
+		name               string
+		mockFn             func(apiTokenRepo *mocks.MockAPITokenRepository)
+		expectedStatusCode int
+	}{
+		{
+			name: "could not list tokens",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().List(gomock.Any(), userID).
+					Times(1).
+					Return(nil, errors.New("could not list tokens"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "no tokens",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().List(gomock.Any(), userID).
+					Times(1).
+					Return(nil, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "tokens are listed",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().List(gomock.Any(), userID).
+					Times(1).
+					Return([]sdump.APIToken{
+						{
+							ID:        uuid.MustParse("0a0d8b1c-9b5e-4f8a-8a8c-2f1d1f4e5a6b"),
+							UserID:    userID,
+							Name:      "ci",
+							Hash:      "should-never-be-returned",
+							Prefix:    "sdump_abcdef",
+							CreatedAt: time.Date(2024, 3, 12, 9, 30, 0, 0, time.UTC),
+							UpdatedAt: time.Date(2024, 3, 12, 9, 30, 0, 0, time.UTC),
+						},
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/tokens", nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			apiTokenRepo := mocks.NewMockAPITokenRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: userID}, nil)
+
+			v.mockFn(apiTokenRepo)
+
+			newTokenRouter(userRepo, apiTokenRepo).ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestTokenHandler_Create(t *testing.T) {
+	userID := uuid.New()
+
+	var stored *sdump.APIToken
+
+	tt := []struct {
+		name               string
+		mockFn             func(apiTokenRepo *mocks.MockAPITokenRepository)
+		requestBody        createTokenRequest
+		expectedStatusCode int
+		hasDynamicData     bool
+	}{
+		{
+			name:               "invalid token name",
+			mockFn:             func(_ *mocks.MockAPITokenRepository) {},
+			requestBody:        createTokenRequest{Name: "  "},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "could not store token",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("could not store token"))
+			},
+			requestBody:        createTokenRequest{Name: "ci"},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "token created",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, token *sdump.APIToken) error {
+						stored = token
+						return nil
+					})
+			},
+			requestBody:        createTokenRequest{Name: "ci"},
+			expectedStatusCode: http.StatusCreated,
+			hasDynamicData:     true,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			b := new(bytes.Buffer)
+
+			err := json.NewEncoder(b).Encode(v.requestBody)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/tokens", b)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			apiTokenRepo := mocks.NewMockAPITokenRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: userID}, nil)
+
+			v.mockFn(apiTokenRepo)
+
+			newTokenRouter(userRepo, apiTokenRepo).ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+
+			if !v.hasDynamicData {
+				verifyMatch(t, recorder)
+				return
+			}
+
+			var response createdAPITokenResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			require.Equal(t, userID, response.Token.UserID)
+			require.True(t, strings.HasPrefix(response.Secret, response.Token.Prefix))
+
+			// only the hash of the token is stored
+			require.NotNil(t, stored)
+			require.Equal(t, sdump.HashAPIToken(response.Secret), stored.Hash)
+		})
+	}
+}
+
+func TestTokenHandler_Revoke(t *testing.T) {
+	userID := uuid.New()
+	tokenID := uuid.New()
+
+	tt := []struct {
+		name               string
+		mockFn             func(apiTokenRepo *mocks.MockAPITokenRepository)
+		tokenID            string
+		expectedStatusCode int
+	}{
+		{
+			name:               "invalid token id",
+			mockFn:             func(_ *mocks.MockAPITokenRepository) {},
+			tokenID:            "not-a-uuid",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "token does not belong to user",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().Find(gomock.Any(), &sdump.FindAPITokenOptions{
+					ID:     tokenID,
+					UserID: userID,
+				}).
+					Times(1).
+					Return(nil, sdump.ErrAPITokenNotFound)
+			},
+			tokenID:            tokenID.String(),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "could not revoke token",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.APIToken{ID: tokenID, UserID: userID}, nil)
+
+				apiTokenRepo.EXPECT().Revoke(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("could not revoke token"))
+			},
+			tokenID:            tokenID.String(),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "token revoked",
+			mockFn: func(apiTokenRepo *mocks.MockAPITokenRepository) {
+				apiTokenRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.APIToken{ID: tokenID, UserID: userID}, nil)
+
+				apiTokenRepo.EXPECT().Revoke(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			tokenID:            tokenID.String(),
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/tokens/"+v.tokenID, nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			apiTokenRepo := mocks.NewMockAPITokenRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: userID}, nil)
+
+			v.mockFn(apiTokenRepo)
+
+			newTokenRouter(userRepo, apiTokenRepo).ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
@@ -8,11 +8,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/adelowo/sdump"
 	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/relay"
+	"github.com/adelowo/sdump/internal/signature"
+	"github.com/adelowo/sdump/internal/tmpl"
 	"github.com/adelowo/sdump/internal/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -23,17 +30,42 @@ import (
 )
 
 type urlHandler struct {
-	logger     *logrus.Entry
-	urlRepo    sdump.URLRepository
-	ingestRepo sdump.IngestRepository
-	userRepo   sdump.UserRepository
-	cfg        config.Config
-	sseServer  *sse.Server
+	logger        *logrus.Entry
+	urlRepo       sdump.URLRepository
+	ingestRepo    sdump.IngestRepository
+	userRepo      sdump.UserRepository
+	cfg           config.Config
+	sseServer     *sse.Server
+	publisher     sdump.Publisher
+	rateLimiter   *RateLimiter
+	apiTokenRepo  sdump.APITokenRepository
+	planRepo      sdump.PlanRepository
+	forwardClient *http.Client
+	tunnelClient  *http.Client
+	forwarder     *Forwarder
 }
 
+type contextKey string
+
+const (
+	endpointCtxKey contextKey = "endpoint"
+	userCtxKey     contextKey = "user"
+
+	ingestedRequestCtxKey contextKey = "ingested_request"
+)
+
 type createURLRequest struct {
 	SSHFingerprint   string `json:"ssh_fingerprint,omitempty"`
 	ForceNewEndpoint bool   `json:"force_new_endpoint,omitempty"`
+
+	// Name and Reference are optional. Providing either always creates a
+	// new endpoint
+	Name      string `json:"name,omitempty"`
+	Reference string `json:"reference,omitempty"`
+
+	// Replace is the reference of one of the user's endpoints to
+	// deactivate once the new endpoint is created
+	Replace string `json:"replace,omitempty"`
 }
 
 func (u *urlHandler) create(w http.ResponseWriter, r *http.Request) {
@@ -59,6 +91,19 @@ func (u *urlHandler) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.Name = strings.TrimSpace(req.Name)
+	req.Reference = strings.TrimSpace(req.Reference)
+
+	// validated before any user is created
+	if _, err := sdump.NewNamedURLEndpoint(uuid.Nil, sdump.NewURLEndpointOptions{
+		Name:      req.Name,
+		Reference: req.Reference,
+	}); err != nil {
+		span.SetStatus(codes.Error, "invalid endpoint options")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
 	user, err := u.userRepo.Find(ctx, &sdump.FindUserOptions{
 		SSHKeyFingerprint: req.SSHFingerprint,
 	})
@@ -71,16 +116,18 @@ func (u *urlHandler) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var userID uuid.UUID
-
 	switch err {
 
 	default:
-		userID = user.ID
+		if user.IsBanned {
+			span.SetStatus(codes.Error, "user is banned")
+			_ = render.Render(w, r, newBannedUserError(user))
+			return
+		}
 
 	case sdump.ErrUserNotFound:
 
-		user := &sdump.User{
+		user = &sdump.User{
 			SSHFingerPrint: req.SSHFingerprint,
 			IsBanned:       false,
 		}
@@ -95,11 +142,60 @@ func (u *urlHandler) create(w http.ResponseWriter, r *http.Request) {
 			_ = render.Render(w, r, newAPIError(http.StatusInternalServerError, "an error occurred while storing your ssh fingerprint"))
 			return
 		}
+	}
+
+	userID := user.ID
+
+	var replaced *sdump.URLEndpoint
+	if req.Replace != "" {
+		replaced, err = u.urlRepo.Get(ctx, &sdump.FindURLOptions{
+			Reference: req.Replace,
+		})
+		if errors.Is(err, sdump.ErrURLEndpointNotFound) || (err == nil && replaced.UserID != userID) {
+			span.SetStatus(codes.Error, "url to replace not found")
+			_ = render.Render(w, r, newAPIError(http.StatusNotFound,
+				"the url to replace does not exist"))
+			return
+		}
 
-		userID = user.ID
+		if err != nil {
+			logger.WithError(err).Error("could not find url to replace")
+			span.SetStatus(codes.Error, "could not find url to replace")
+			_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+				"an error occurred while generating endpoint"))
+			return
+		}
+	}
+
+	plan, err := u.userPlan(ctx, user)
+	if err != nil {
+		logger.WithError(err).Error("could not fetch user plan")
+		span.SetStatus(codes.Error, "could not fetch user plan")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while generating endpoint"))
+		return
+	}
+
+	// the options were validated above
+	newEndpoint, _ := sdump.NewNamedURLEndpoint(userID, sdump.NewURLEndpointOptions{
+		Name:      req.Name,
+		Reference: req.Reference,
+	})
+
+	endpoint, err := u.createOrFetchEndpoint(ctx, newEndpoint, plan, replaced,
+		req.ForceNewEndpoint || req.Name != "" || req.Reference != "")
+	if errors.Is(err, sdump.ErrCounterExhausted) {
+		span.SetStatus(codes.Error, "endpoint quota exhausted")
+		_ = render.Render(w, r, newEndpointQuotaError(plan))
+		return
+	}
+
+	if errors.Is(err, sdump.ErrReferenceTaken) || errors.Is(err, sdump.ErrEndpointNameTaken) {
+		span.SetStatus(codes.Error, "endpoint already exists")
+		_ = render.Render(w, r, newAPIError(http.StatusConflict, err.Error()))
+		return
 	}
 
-	endpoint, err := u.createOrFetchEndpoint(ctx, sdump.NewURLEndpoint(userID), req.ForceNewEndpoint)
 	if err != nil {
 
 		logger.WithError(err).Error("could not create url endpoint")
@@ -111,38 +207,233 @@ func (u *urlHandler) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go func() {
-		_ = u.sseServer.CreateStream(endpoint.PubChannel())
-	}()
+	// the new endpoint is usable even if the old one could not be
+	// deactivated so this is not reported to the user
+	if replaced != nil && replaced.ID != endpoint.ID && replaced.IsActive {
+		if err := u.urlRepo.Deactivate(ctx, replaced); err != nil {
+			logger.WithError(err).
+				WithField("replaced_reference", replaced.Reference).
+				Error("could not deactivate replaced url")
+		}
+	}
+
+	stream, err := u.newStream(endpoint)
+	if err != nil {
+		logger.WithError(err).Error("could not issue stream token")
+		span.SetStatus(codes.Error, "could not issue stream token")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while generating endpoint"))
+		return
+	}
 
 	createdURLMetrics.Inc()
 	span.SetStatus(codes.Ok, "created url")
 	_ = render.Render(w, r, &createdURLEndpointResponse{
 		APIStatus: newAPIStatus(http.StatusOK, "created url endpoint"),
-		SSE: struct {
-			Channel string "json:\"channel,omitempty\""
-		}{
-			Channel: endpoint.PubChannel(),
-		},
+		SSE:       stream,
 		URL: struct {
 			FQDN                  string "json:\"fqdn,omitempty\""
 			Identifier            string "json:\"identifier,omitempty\""
+			Name                  string "json:\"name,omitempty\""
+			IsPaused              bool   "json:\"is_paused,omitempty\""
 			HumanReadableEndpoint string "json:\"human_readable_endpoint,omitempty\""
 		}{
 			FQDN:       u.cfg.HTTP.Domain,
 			Identifier: endpoint.Reference,
+			Name:       endpoint.Name,
+			IsPaused:   endpoint.IsPaused,
 			HumanReadableEndpoint: fmt.Sprintf("%s/%s",
 				u.cfg.HTTP.Domain, endpoint.Reference),
 		},
 	})
 }
 
+// newStream issues a short lived token the endpoint's owner can subscribe
+// to its requests with
+func (u *urlHandler) newStream(endpoint *sdump.URLEndpoint) (streamDefinition, error) {
+	now := time.Now()
+
+	token, err := signature.NewToken(u.cfg.HTTP.AdminSecret, signature.Claims{
+		Subject:  endpoint.UserID.String(),
+		Audience: endpoint.PubChannel(),
+	}, streamTokenTTL, now)
+	if err != nil {
+		return streamDefinition{}, err
+	}
+
+	go func() {
+		if !u.sseServer.StreamExists(endpoint.PubChannel()) {
+			_ = u.sseServer.CreateStream(endpoint.PubChannel())
+		}
+	}()
+
+	return streamDefinition{
+		Channel:   endpoint.PubChannel(),
+		Token:     token,
+		ExpiresAt: now.Add(streamTokenTTL),
+	}, nil
+}
+
+// stream issues a new stream token for one of the user's endpoints. The
+// TUI uses it to switch between endpoints
+func (u *urlHandler) stream(w http.ResponseWriter, r *http.Request) {
+	_, span, requestID := getTracer(r.Context(), r, "url.stream")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.stream").
+		WithField("reference", endpoint.Reference)
+
+	stream, err := u.newStream(endpoint)
+	if err != nil {
+		logger.WithError(err).Error("could not issue stream token")
+		span.SetStatus(codes.Error, "could not issue stream token")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while issuing stream token"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "issued stream token")
+	_ = render.Render(w, r, &streamResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "issued stream token"),
+		SSE:       stream,
+	})
+}
+
+type renameURLRequest struct {
+	Name string `json:"name"`
+}
+
+func (u *urlHandler) rename(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.rename")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.rename").
+		WithField("reference", endpoint.Reference)
+
+	req := new(renameURLRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		span.SetStatus(codes.Error, "invalid request body")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "please provide a valid request body"))
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	if err := sdump.ValidateEndpointName(req.Name); err != nil {
+		span.SetStatus(codes.Error, "invalid name")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	err := u.urlRepo.Rename(ctx, endpoint, req.Name)
+	if errors.Is(err, sdump.ErrEndpointNameTaken) {
+		span.SetStatus(codes.Error, "name already in use")
+		_ = render.Render(w, r, newAPIError(http.StatusConflict, err.Error()))
+		return
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("could not rename url")
+		span.SetStatus(codes.Error, "could not rename url")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while renaming url"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "renamed url")
+	_ = render.Render(w, r, &urlEndpointResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "renamed url"),
+		URL:       endpoint,
+	})
+}
+
+func (u *urlHandler) deactivate(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.deactivate")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.deactivate").
+		WithField("reference", endpoint.Reference)
+
+	if err := u.urlRepo.Deactivate(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not deactivate url")
+		span.SetStatus(codes.Error, "could not deactivate url")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while deactivating url"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "deactivated url")
+	_ = render.Render(w, r, &urlEndpointResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "deactivated url"),
+		URL:       endpoint,
+	})
+}
+
+// pause stops the endpoint from storing and streaming the requests it
+// receives until it is resumed
+func (u *urlHandler) pause(w http.ResponseWriter, r *http.Request) {
+	u.setPaused(w, r, true)
+}
+
+func (u *urlHandler) resume(w http.ResponseWriter, r *http.Request) {
+	u.setPaused(w, r, false)
+}
+
+func (u *urlHandler) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.setPaused")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.setPaused").
+		WithField("reference", endpoint.Reference).
+		WithField("paused", paused)
+
+	endpoint.IsPaused = paused
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not update url capture")
+		span.SetStatus(codes.Error, "could not update url capture")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while updating url capture"))
+		return
+	}
+
+	msg := "resumed capture"
+	if paused {
+		msg = "paused capture"
+	}
+
+	span.SetStatus(codes.Ok, msg)
+	_ = render.Render(w, r, &urlEndpointResponse{
+		APIStatus: newAPIStatus(http.StatusOK, msg),
+		URL:       endpoint,
+	})
+}
+
 func (u *urlHandler) createOrFetchEndpoint(
 	ctx context.Context,
 	endpoint *sdump.URLEndpoint,
+	plan *sdump.Plan,
+	replaced *sdump.URLEndpoint,
 	forceRefresh bool,
 ) (*sdump.URLEndpoint, error) {
 	if forceRefresh {
+		if err := u.takeEndpointQuota(ctx, endpoint.UserID, plan, replaced); err != nil {
+			return nil, err
+		}
+
 		return endpoint, u.urlRepo.Create(ctx, endpoint)
 	}
 
@@ -152,6 +443,10 @@ func (u *urlHandler) createOrFetchEndpoint(
 	}
 
 	if errors.Is(err, sdump.ErrURLEndpointNotFound) {
+		if err := u.takeEndpointQuota(ctx, endpoint.UserID, plan, replaced); err != nil {
+			return nil, err
+		}
+
 		if err := u.urlRepo.Create(ctx, endpoint); err != nil {
 			return nil, err
 		}
@@ -179,7 +474,14 @@ func (u *urlHandler) ingest(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("Ingesting http request")
 
-	r.Body = http.MaxBytesReader(w, r.Body, u.cfg.HTTP.MaxRequestBodySize)
+	// floods against an endpoint are turned away without hitting the
+	// database once its limit has been reached
+	if u.isIngestRateLimited(w, r, reference) {
+		span.SetStatus(codes.Error, "rate limited")
+		u.publishDropped(logger, &sdump.URLEndpoint{Reference: reference}, r)
+		_ = render.Render(w, r, newRateLimitError())
+		return
+	}
 
 	endpoint, err := u.urlRepo.Get(ctx, &sdump.FindURLOptions{
 		Reference: reference,
@@ -201,7 +503,68 @@ func (u *urlHandler) ingest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s := &strings.Builder{}
+	if !endpoint.IsActive {
+		span.SetStatus(codes.Error, "url is not active")
+		_ = render.Render(w, r, newAPIError(http.StatusGone,
+			"this url has been deactivated"))
+		return
+	}
+
+	owner, err := u.userRepo.Find(ctx, &sdump.FindUserOptions{
+		ID: endpoint.UserID,
+	})
+	if err != nil && !errors.Is(err, sdump.ErrUserNotFound) {
+		span.SetStatus(codes.Error, "could not find url owner")
+
+		failedIngestedHTTPRequestsCounter.Inc()
+		logger.WithError(err).Error("could not find url owner")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while ingesting HTTP request"))
+		return
+	}
+
+	if owner != nil && owner.IsBanned {
+		span.SetStatus(codes.Error, "url owner is banned")
+		_ = render.Render(w, r, newAPIError(http.StatusForbidden,
+			"this url has been disabled"))
+		return
+	}
+
+	plan, err := u.userPlan(ctx, owner)
+	if err != nil {
+		span.SetStatus(codes.Error, "could not find url owner plan")
+
+		failedIngestedHTTPRequestsCounter.Inc()
+		logger.WithError(err).Error("could not find url owner plan")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while ingesting HTTP request"))
+		return
+	}
+
+	isRateLimited, err := u.takeRateLimit(w, r, rateLimitScopeIngest,
+		plan.IngestRateLimit(owner, u.cfg.HTTP.RateLimit.RequestsPerMinute),
+		endpoint.Reference, endpoint.UserID)
+	if err != nil {
+		span.SetStatus(codes.Error, "could not check rate limit")
+
+		failedIngestedHTTPRequestsCounter.Inc()
+		logger.WithError(err).Error("could not check rate limit")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while ingesting HTTP request"))
+		return
+	}
+
+	if isRateLimited {
+		span.SetStatus(codes.Error, "rate limited")
+		u.publishDropped(logger, endpoint, r)
+		_ = render.Render(w, r, newRateLimitError())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body,
+		endpoint.Metadata.Limits.RequestBodySize(plan.RequestBodySize(u.cfg.HTTP.MaxRequestBodySize)))
+
+	s := new(bytes.Buffer)
 
 	size, err := io.Copy(s, r.Body)
 	if err != nil {
@@ -223,15 +586,47 @@ func (u *urlHandler) ingest(w http.ResponseWriter, r *http.Request) {
 	ingestedRequest := &sdump.IngestHTTPRequest{
 		UrlID: endpoint.ID,
 		Request: sdump.RequestDefinition{
-			Body:      s.String(),
-			Query:     r.URL.Query().Encode(),
-			Headers:   r.Header,
-			IPAddress: util.GetIP(r),
-			Size:      size,
-			Method:    r.Method,
+			Query:       r.URL.Query().Encode(),
+			Headers:     r.Header,
+			IPAddress:   util.GetIP(r),
+			Size:        size,
+			Method:      r.Method,
+			ContentType: r.Header.Get("Content-Type"),
+			Path:        "/" + chi.URLParam(r, "*"),
+			RawURL:      r.RequestURI,
+			Proto:       r.Proto,
+			Host:        r.Host,
 		},
 	}
 
+	ingestedRequest.Request.SetBody(s.Bytes())
+
+	if endpoint.IsPaused {
+		pausedHTTPRequestsCounter.Inc()
+		span.SetStatus(codes.Ok, "replied to request while capture is paused")
+		u.reply(w, r, logger, endpoint.Metadata.Response, ingestedRequest.Request)
+		return
+	}
+
+	// taken once the body is accepted so requests that are turned away
+	// do not count against the quota
+	err = u.takeRequestQuota(ctx, endpoint.UserID, plan)
+	if errors.Is(err, sdump.ErrCounterExhausted) {
+		span.SetStatus(codes.Error, "request quota exhausted")
+		_ = render.Render(w, r, newRequestQuotaError(plan))
+		return
+	}
+
+	if err != nil {
+		span.SetStatus(codes.Error, "could not count ingested requests")
+
+		failedIngestedHTTPRequestsCounter.Inc()
+		logger.WithError(err).Error("could not count ingested requests")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while ingesting HTTP request"))
+		return
+	}
+
 	if err := u.ingestRepo.Create(ctx, ingestedRequest); err != nil {
 		failedIngestedHTTPRequestsCounter.Inc()
 		logger.WithError(err).Error("could not ingest request")
@@ -243,35 +638,688 @@ func (u *urlHandler) ingest(w http.ResponseWriter, r *http.Request) {
 
 	ingestedHTTPRequestsCounter.Inc()
 
+	req := ingestedRequest.Request
+
+	if endpoint.Metadata.Forward != nil || endpoint.Metadata.Tunnel != nil {
+		u.forwardInBackground(ctx, logger, endpoint, ingestedRequest)
+	} else {
+		go u.publish(logger, endpoint, ingestedRequest)
+	}
+
+	span.SetStatus(codes.Ok, "ingested request")
+	u.reply(w, r, logger, endpoint.Metadata.Response, req)
+}
+
+// publish sends the ingested request to the TUI sessions subscribed to the
+// endpoint
+func (u *urlHandler) publish(logger *logrus.Entry,
+	endpoint *sdump.URLEndpoint, ingestedRequest *sdump.IngestHTTPRequest,
+) {
+	data, err := newSSEEvent(ingestedRequest)
+	if err != nil {
+		logger.WithError(err).Error("could not format SSE event")
+		return
+	}
+
+	// the TUI session could be connected to another HTTP server
+	err = u.publisher.Publish(context.Background(), &sdump.Event{
+		Channel: endpoint.PubChannel(),
+		ID:      ingestedRequest.ID.String(),
+		Data:    data,
+	})
+	if err != nil {
+		logger.WithError(err).Error("could not publish ingested request")
+	}
+}
+
+// forwardInBackground forwards the ingested request once a worker is free
+// so the sender is replied to right away. The request is published after
+// it was forwarded so TUI sessions show how the target responded
+func (u *urlHandler) forwardInBackground(ctx context.Context, logger *logrus.Entry,
+	endpoint *sdump.URLEndpoint, ingestedRequest *sdump.IngestHTTPRequest,
+) {
+	// the request is cancelled once it has been replied to
+	ctx = context.WithoutCancel(ctx)
+
+	queued := u.forwarder.enqueue(func() {
+		u.forward(ctx, logger, endpoint.Metadata, ingestedRequest)
+		u.publish(logger, endpoint, ingestedRequest)
+	})
+	if !queued {
+		droppedForwardsCounter.Inc()
+		logger.Warn("forward queue is full, request was stored without being forwarded")
+		go u.publish(logger, endpoint, ingestedRequest)
+	}
+}
+
+// publishDropped lets the TUI sessions subscribed to the endpoint know a
+// request was turned away
+func (u *urlHandler) publishDropped(logger *logrus.Entry,
+	endpoint *sdump.URLEndpoint, r *http.Request,
+) {
+	data, err := json.Marshal(&droppedEvent{
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		IPAddress: util.GetIP(r).String(),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		logger.WithError(err).Error("could not format dropped event")
+		return
+	}
+
 	go func() {
-		if !u.sseServer.StreamExists(endpoint.PubChannel()) {
-			_ = u.sseServer.CreateStream(endpoint.PubChannel())
+		err := u.publisher.Publish(context.Background(), &sdump.Event{
+			Channel: endpoint.PubChannel(),
+			Type:    sdump.EventTypeDropped,
+			Data:    data,
+		})
+		if err != nil {
+			logger.WithError(err).Error("could not publish dropped request")
 		}
+	}()
+}
+
+// forward relays the ingested request to the endpoint's forward target and
+// the owner's reverse SSH tunnel, then stores how they responded
+func (u *urlHandler) forward(ctx context.Context, logger *logrus.Entry,
+	metadata sdump.URLEndpointMetadata,
+	ingestedRequest *sdump.IngestHTTPRequest,
+) {
+	ctx, span := tracer.Start(ctx, "url.forward")
+	defer span.End()
+
+	// tunnels saved before they were checked as thoroughly are skipped
+	if metadata.Tunnel != nil && u.validateTunnel(metadata.Tunnel) != nil {
+		logger.Debug("skipping invalid tunnel")
+		metadata.Tunnel = nil
+	}
+
+	var wg sync.WaitGroup
+
+	if metadata.Forward != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ingestedRequest.Forward = relay.Do(ctx, u.forwardClient, metadata.Forward.URL,
+				ingestedRequest.Request)
+		}()
+	}
 
-		b := new(bytes.Buffer)
+	if metadata.Tunnel != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ingestedRequest.Tunnel = relay.Do(ctx, u.tunnelClient, metadata.Tunnel.URL,
+				ingestedRequest.Request)
+		}()
+	}
+
+	wg.Wait()
 
-		var sseEvent struct {
-			Request   sdump.RequestDefinition `json:"request"`
-			ID        string                  `json:"id"`
-			CreatedAt time.Time               `json:"created_at,omitempty"`
+	if f := ingestedRequest.Forward; f != nil {
+		if f.Error != "" {
+			failedForwardedHTTPRequestsCounter.Inc()
+			logger.WithField("forward_error", f.Error).
+				Debug("could not forward request")
+		} else {
+			forwardedHTTPRequestsCounter.Inc()
 		}
+	}
 
-		sseEvent.Request = ingestedRequest.Request
-		sseEvent.ID = ingestedRequest.ID.String()
-		sseEvent.CreatedAt = ingestedRequest.CreatedAt
+	if t := ingestedRequest.Tunnel; t != nil {
+		if t.Error != "" {
+			failedTunneledHTTPRequestsCounter.Inc()
+			logger.WithField("tunnel_error", t.Error).
+				Debug("could not deliver request through tunnel")
+		} else {
+			tunneledHTTPRequestsCounter.Inc()
+		}
+	}
 
-		if err := json.NewEncoder(b).Encode(&sseEvent); err != nil {
-			logger.WithError(err).Error("could not format SSE event")
-			return
+	if err := u.ingestRepo.Update(ctx, ingestedRequest); err != nil {
+		span.SetStatus(codes.Error, "could not store forward result")
+		logger.WithError(err).Error("could not store forward result")
+		return
+	}
+
+	span.SetStatus(codes.Ok, "forwarded request")
+}
+
+// reply sends the endpoint's configured response to the client. If the
+// endpoint has none or it cannot be rendered for this request, a generic
+// message is sent back. Template errors are only logged as they are the
+// owner's to fix, not the sender's
+func (u *urlHandler) reply(w http.ResponseWriter, r *http.Request,
+	logger *logrus.Entry,
+	response *sdump.ResponseDefinition,
+	req sdump.RequestDefinition,
+) {
+	if response == nil {
+		_ = render.Render(w, r, newAPIStatus(http.StatusAccepted,
+			"Request ingested"))
+		return
+	}
+
+	body, err := tmpl.Render(response.Body, req)
+	if err != nil {
+		logger.WithError(err).Warn("could not render response template, sending the default response")
+		_ = render.Render(w, r, newAPIStatus(http.StatusAccepted,
+			"Request ingested"))
+		return
+	}
+
+	select {
+	case <-time.After(response.Delay()):
+	case <-r.Context().Done():
+		return
+	}
+
+	// let net/http sniff the content type if the response does not set one
+	w.Header().Del("Content-Type")
+
+	for key, values := range response.Headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
 		}
+	}
+
+	w.WriteHeader(response.StatusCode)
+	_, _ = io.WriteString(w, body)
+}
+
+func userFromContext(ctx context.Context) *sdump.User {
+	return ctx.Value(userCtxKey).(*sdump.User)
+}
 
-		u.sseServer.Publish(endpoint.PubChannel(), &sse.Event{
-			ID:   []byte(ingestedRequest.ID.String()),
-			Data: b.Bytes(),
+func (u *urlHandler) requireEndpointOwner(next http.Handler) http.Handler {
+	return u.requireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span, requestID := getTracer(r.Context(), r, "url.requireEndpointOwner")
+		defer span.End()
+
+		reference := chi.URLParam(r, "reference")
+
+		logger := u.logger.WithField("request_id", requestID).
+			WithField("method", "urlHandler.requireEndpointOwner").
+			WithField("reference", reference)
+
+		user := userFromContext(ctx)
+
+		endpoint, err := u.urlRepo.Get(ctx, &sdump.FindURLOptions{
+			Reference: reference,
 		})
-	}()
+		if errors.Is(err, sdump.ErrURLEndpointNotFound) {
+			span.SetStatus(codes.Error, "url not found")
+			_ = render.Render(w, r, newAPIError(http.StatusNotFound, "Dump url does not exist"))
+			return
+		}
 
-	span.SetStatus(codes.Ok, "ingested request")
-	_ = render.Render(w, r, newAPIStatus(http.StatusAccepted,
-		"Request ingested"))
+		if err != nil {
+			logger.WithError(err).Error("could not find dump url by reference")
+			span.SetStatus(codes.Error, "could not find dump url by reference")
+			_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+				"an error occurred while fetching url"))
+			return
+		}
+
+		// do not leak the existence of urls owned by other users
+		if endpoint.UserID != user.ID {
+			span.SetStatus(codes.Error, "url not owned by user")
+			_ = render.Render(w, r, newAPIError(http.StatusNotFound, "Dump url does not exist"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), endpointCtxKey, endpoint)))
+	}))
+}
+
+func endpointFromContext(ctx context.Context) *sdump.URLEndpoint {
+	return ctx.Value(endpointCtxKey).(*sdump.URLEndpoint)
+}
+
+func (u *urlHandler) getResponse(w http.ResponseWriter, r *http.Request) {
+	_, span, _ := getTracer(r.Context(), r, "url.getResponse")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	span.SetStatus(codes.Ok, "fetched url response")
+	_ = render.Render(w, r, &endpointResponseDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched url response"),
+		Response:  endpoint.Metadata.Response,
+	})
+}
+
+func (u *urlHandler) updateResponse(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.updateResponse")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.updateResponse").
+		WithField("reference", endpoint.Reference)
+
+	req := new(sdump.ResponseDefinition)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		span.SetStatus(codes.Error, "invalid request body")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "please provide a valid request body"))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		span.SetStatus(codes.Error, "invalid response definition")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	if err := tmpl.Validate(req.Body); err != nil {
+		span.SetStatus(codes.Error, "invalid response template")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	endpoint.Metadata.Response = req
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not update url response")
+		span.SetStatus(codes.Error, "could not update url response")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while updating url response"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "updated url response")
+	_ = render.Render(w, r, &endpointResponseDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "updated url response"),
+		Response:  endpoint.Metadata.Response,
+	})
+}
+
+func (u *urlHandler) deleteResponse(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.deleteResponse")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.deleteResponse").
+		WithField("reference", endpoint.Reference)
+
+	endpoint.Metadata.Response = nil
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not reset url response")
+		span.SetStatus(codes.Error, "could not reset url response")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while resetting url response"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "reset url response")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "reset url response"))
+}
+
+func (u *urlHandler) getForward(w http.ResponseWriter, r *http.Request) {
+	_, span, _ := getTracer(r.Context(), r, "url.getForward")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	span.SetStatus(codes.Ok, "fetched url forward target")
+	_ = render.Render(w, r, &endpointForwardDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched url forward target"),
+		Forward:   endpoint.Metadata.Forward,
+	})
+}
+
+func (u *urlHandler) updateForward(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.updateForward")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.updateForward").
+		WithField("reference", endpoint.Reference)
+
+	req := new(sdump.ForwardDefinition)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		span.SetStatus(codes.Error, "invalid request body")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "please provide a valid request body"))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		span.SetStatus(codes.Error, "invalid forward definition")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	endpoint.Metadata.Forward = req
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not update url forward target")
+		span.SetStatus(codes.Error, "could not update url forward target")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while updating url forward target"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "updated url forward target")
+	_ = render.Render(w, r, &endpointForwardDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "updated url forward target"),
+		Forward:   endpoint.Metadata.Forward,
+	})
+}
+
+func (u *urlHandler) deleteForward(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.deleteForward")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.deleteForward").
+		WithField("reference", endpoint.Reference)
+
+	endpoint.Metadata.Forward = nil
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not remove url forward target")
+		span.SetStatus(codes.Error, "could not remove url forward target")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while removing url forward target"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "removed url forward target")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "removed url forward target"))
+}
+
+func (u *urlHandler) getLimits(w http.ResponseWriter, r *http.Request) {
+	_, span, _ := getTracer(r.Context(), r, "url.getLimits")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	span.SetStatus(codes.Ok, "fetched url limits")
+	_ = render.Render(w, r, &endpointLimitsDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched url limits"),
+		Limits:    endpoint.Metadata.Limits,
+	})
+}
+
+func (u *urlHandler) updateLimits(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.updateLimits")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.updateLimits").
+		WithField("reference", endpoint.Reference)
+
+	req := new(sdump.LimitsDefinition)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		span.SetStatus(codes.Error, "invalid request body")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "please provide a valid request body"))
+		return
+	}
+
+	plan, err := u.userPlan(ctx, userFromContext(ctx))
+	if err != nil {
+		logger.WithError(err).Error("could not fetch user plan")
+		span.SetStatus(codes.Error, "could not fetch user plan")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while updating url limits"))
+		return
+	}
+
+	maxRequestBodySize, maxRetention := u.limitUpperBounds(plan)
+
+	if err := req.Validate(maxRequestBodySize, maxRetention); err != nil {
+		span.SetStatus(codes.Error, "invalid limits definition")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest,
+			fmt.Sprintf("%v. Up to %d bytes and %d hours are allowed",
+				err, maxRequestBodySize, int64(maxRetention/time.Hour))))
+		return
+	}
+
+	endpoint.Metadata.Limits = req
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not update url limits")
+		span.SetStatus(codes.Error, "could not update url limits")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while updating url limits"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "updated url limits")
+	_ = render.Render(w, r, &endpointLimitsDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "updated url limits"),
+		Limits:    endpoint.Metadata.Limits,
+	})
+}
+
+func (u *urlHandler) deleteLimits(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.deleteLimits")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.deleteLimits").
+		WithField("reference", endpoint.Reference)
+
+	endpoint.Metadata.Limits = nil
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not remove url limits")
+		span.SetStatus(codes.Error, "could not remove url limits")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while removing url limits"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "removed url limits")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "removed url limits"))
+}
+
+// tunnelPathRegexp matches the secret token tunnel listeners expect as the
+// first path segment
+var tunnelPathRegexp = regexp.MustCompile("^/[a-f0-9]{32}$")
+
+// validateTunnel makes sure the url points to a tunnel listener on the SSH
+// server and nowhere else. Tunnels are delivered to with private networks
+// allowed so the port has to be one listeners are opened on
+func (u *urlHandler) validateTunnel(tunnel *sdump.ForwardDefinition) error {
+	if err := tunnel.Validate(); err != nil {
+		return err
+	}
+
+	tunnelURL, err := url.Parse(tunnel.URL)
+	if err != nil {
+		return sdump.ErrInvalidTunnelURL
+	}
+
+	if tunnelURL.Scheme != "http" ||
+		tunnelURL.Hostname() != u.cfg.SSH.Tunnel.AdvertiseHost ||
+		!tunnelPathRegexp.MatchString(tunnelURL.Path) ||
+		tunnelURL.RawQuery != "" || tunnelURL.User != nil {
+		return sdump.ErrInvalidTunnelURL
+	}
+
+	port, err := strconv.Atoi(tunnelURL.Port())
+	if err != nil || !u.cfg.SSH.Tunnel.IsTunnelPort(port) {
+		return sdump.ErrInvalidTunnelURL
+	}
+
+	return nil
+}
+
+func (u *urlHandler) getTunnel(w http.ResponseWriter, r *http.Request) {
+	_, span, _ := getTracer(r.Context(), r, "url.getTunnel")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	span.SetStatus(codes.Ok, "fetched url tunnel")
+	_ = render.Render(w, r, &endpointTunnelDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched url tunnel"),
+		Tunnel:    endpoint.Metadata.Tunnel,
+	})
+}
+
+func (u *urlHandler) updateTunnel(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.updateTunnel")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.updateTunnel").
+		WithField("reference", endpoint.Reference)
+
+	if !u.cfg.SSH.Tunnel.IsEnabled {
+		span.SetStatus(codes.Error, "tunnels are disabled")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "reverse SSH tunnels are not enabled"))
+		return
+	}
+
+	req := new(sdump.ForwardDefinition)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		span.SetStatus(codes.Error, "invalid request body")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, "please provide a valid request body"))
+		return
+	}
+
+	if err := u.validateTunnel(req); err != nil {
+		span.SetStatus(codes.Error, "invalid tunnel definition")
+		_ = render.Render(w, r, newAPIError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	endpoint.Metadata.Tunnel = req
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not update url tunnel")
+		span.SetStatus(codes.Error, "could not update url tunnel")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while updating url tunnel"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "updated url tunnel")
+	_ = render.Render(w, r, &endpointTunnelDefinition{
+		APIStatus: newAPIStatus(http.StatusOK, "updated url tunnel"),
+		Tunnel:    endpoint.Metadata.Tunnel,
+	})
+}
+
+func (u *urlHandler) deleteTunnel(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.deleteTunnel")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.deleteTunnel").
+		WithField("reference", endpoint.Reference)
+
+	endpoint.Metadata.Tunnel = nil
+
+	if err := u.urlRepo.Update(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not remove url tunnel")
+		span.SetStatus(codes.Error, "could not remove url tunnel")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while removing url tunnel"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "removed url tunnel")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "removed url tunnel"))
+}
+
+func (u *urlHandler) list(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.list")
+	defer span.End()
+
+	user := userFromContext(ctx)
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.list").
+		WithField("user_id", user.ID)
+
+	endpoints, err := u.urlRepo.List(ctx, &sdump.ListURLOptions{
+		UserID: user.ID,
+	})
+	if err != nil {
+		logger.WithError(err).Error("could not list urls")
+		span.SetStatus(codes.Error, "could not list urls")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while fetching urls"))
+		return
+	}
+
+	if endpoints == nil {
+		endpoints = []sdump.URLEndpoint{}
+	}
+
+	span.SetStatus(codes.Ok, "fetched urls")
+	_ = render.Render(w, r, &urlEndpointsResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched urls"),
+		URLs:      endpoints,
+	})
+}
+
+func (u *urlHandler) get(w http.ResponseWriter, r *http.Request) {
+	_, span, _ := getTracer(r.Context(), r, "url.get")
+	defer span.End()
+
+	span.SetStatus(codes.Ok, "fetched url")
+	_ = render.Render(w, r, &urlEndpointResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched url"),
+		URL:       endpointFromContext(r.Context()),
+	})
+}
+
+// delete removes the endpoint alongside every request it ingested
+func (u *urlHandler) delete(w http.ResponseWriter, r *http.Request) {
+	ctx, span, requestID := getTracer(r.Context(), r, "url.delete")
+	defer span.End()
+
+	endpoint := endpointFromContext(r.Context())
+
+	logger := u.logger.WithField("request_id", requestID).
+		WithField("method", "urlHandler.delete").
+		WithField("reference", endpoint.Reference)
+
+	_, err := u.ingestRepo.Delete(ctx, &sdump.DeleteIngestedRequestOptions{
+		URLID:          endpoint.ID,
+		UseSoftDeletes: u.cfg.Cron.SoftDeletes,
+	})
+	if err != nil {
+		logger.WithError(err).Error("could not delete url requests")
+		span.SetStatus(codes.Error, "could not delete url requests")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while deleting url"))
+		return
+	}
+
+	if err := u.urlRepo.Delete(ctx, endpoint); err != nil {
+		logger.WithError(err).Error("could not delete url")
+		span.SetStatus(codes.Error, "could not delete url")
+		_ = render.Render(w, r, newAPIError(http.StatusInternalServerError,
+			"an error occurred while deleting url"))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "deleted url")
+	_ = render.Render(w, r, newAPIStatus(http.StatusOK, "deleted url"))
 }
@@ -2,6 +2,7 @@ package httpd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -9,10 +10,16 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/adelowo/sdump"
 	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/internal/pubsub"
+	"github.com/adelowo/sdump/internal/relay"
+	"github.com/adelowo/sdump/internal/signature"
 	"github.com/adelowo/sdump/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/r3labs/sse/v2"
 	"github.com/sebdah/goldie/v2"
 	"github.com/sirupsen/logrus"
@@ -50,6 +57,9 @@ func TestURLHandler_Create(t *testing.T) {
 		// technically it can be reworked to provide an implementation that never
 		// changes during tests but I can always come back to taht
 		hasDynamicData bool
+
+		// plan the user is on. Defaults to a plan without limits
+		plan *sdump.Plan
 	}{
 		{
 			name:               "ssh fingerprint not provided",
@@ -140,6 +150,208 @@ func TestURLHandler_Create(t *testing.T) {
 				ForceNewEndpoint: true,
 			},
 		},
+		{
+			name: "user is banned",
+			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
+				userRepo *mocks.MockUserRepository,
+			) {
+				t.Helper()
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{IsBanned: true, BanReason: "spam"}, nil)
+			},
+			expectedStatusCode: http.StatusForbidden,
+			requestBody: createURLRequest{
+				SSHFingerprint: "sufojfpffhhofjfpjfo",
+			},
+		},
+		{
+			name: "vanity reference is reserved",
+			mockFn: func(t *testing.T, _ *mocks.MockURLRepository, _ *mocks.MockUserRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: createURLRequest{
+				SSHFingerprint: "sufojfpffhhofjfpjfo",
+				Reference:      "api",
+			},
+		},
+		{
+			name: "vanity reference already in use",
+			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
+				userRepo *mocks.MockUserRepository,
+			) {
+				t.Helper()
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{}, nil)
+
+				urlRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sdump.ErrReferenceTaken)
+			},
+			expectedStatusCode: http.StatusConflict,
+			requestBody: createURLRequest{
+				SSHFingerprint: "sufojfpffhhofjfpjfo",
+				Name:           "stripe-staging",
+				Reference:      "stripe-staging",
+			},
+		},
+		{
+			name:           "named url was successfully created",
+			hasDynamicData: true,
+			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
+				userRepo *mocks.MockUserRepository,
+			) {
+				t.Helper()
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{}, nil)
+
+				// a name always creates a new url rather than reusing the latest
+				urlRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, endpoint *sdump.URLEndpoint) error {
+						if endpoint.Name != "github-ci" {
+							return errors.New("unexpected name")
+						}
+
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody: createURLRequest{
+				SSHFingerprint: "sufojfpffhhofjfpjfo",
+				Name:           "github-ci",
+			},
+		},
+		{
+			name: "url to replace belongs to another user",
+			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
+				userRepo *mocks.MockUserRepository,
+			) {
+				t.Helper()
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: uuid.New()}, nil)
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: uuid.New(), IsActive: true}, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+			requestBody: createURLRequest{
+				SSHFingerprint:   "sufojfpffhhofjfpjfo",
+				ForceNewEndpoint: true,
+				Replace:          "cmltfm6g330l5l1vq110",
+			},
+		},
+		{
+			name:           "replaced url is deactivated",
+			hasDynamicData: true,
+			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
+				userRepo *mocks.MockUserRepository,
+			) {
+				t.Helper()
+				userID := uuid.New()
+
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: userID}, nil)
+
+				replaced := &sdump.URLEndpoint{
+					ID:        uuid.New(),
+					UserID:    userID,
+					Reference: "cmltfm6g330l5l1vq110",
+					IsActive:  true,
+				}
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(replaced, nil)
+
+				urlRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+
+				urlRepo.EXPECT().Deactivate(gomock.Any(), replaced).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody: createURLRequest{
+				SSHFingerprint:   "sufojfpffhhofjfpjfo",
+				ForceNewEndpoint: true,
+				Replace:          "cmltfm6g330l5l1vq110",
+			},
+		},
+		{
+			name:           "replaced url is not counted against the endpoint quota",
+			hasDynamicData: true,
+			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
+				userRepo *mocks.MockUserRepository,
+			) {
+				t.Helper()
+				userID := uuid.New()
+
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: userID}, nil)
+
+				replaced := &sdump.URLEndpoint{
+					ID:        uuid.New(),
+					UserID:    userID,
+					Reference: "cmltfm6g330l5l1vq110",
+					IsActive:  true,
+				}
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(replaced, nil)
+
+				// the user is at the limit with the endpoint being replaced
+				urlRepo.EXPECT().List(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]sdump.URLEndpoint{*replaced}, nil)
+
+				urlRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+
+				urlRepo.EXPECT().Deactivate(gomock.Any(), replaced).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody: createURLRequest{
+				SSHFingerprint:   "sufojfpffhhofjfpjfo",
+				ForceNewEndpoint: true,
+				Replace:          "cmltfm6g330l5l1vq110",
+			},
+			plan: &sdump.Plan{Name: "free", MaxEndpoints: 1},
+		},
+		{
+			name: "endpoint quota exhausted",
+			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
+				userRepo *mocks.MockUserRepository,
+			) {
+				t.Helper()
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{}, nil)
+
+				urlRepo.EXPECT().List(gomock.Any(), &sdump.ListURLOptions{
+					IsActive: true,
+				}).
+					Times(1).
+					Return([]sdump.URLEndpoint{{}, {}}, nil)
+			},
+			expectedStatusCode: http.StatusPaymentRequired,
+			requestBody: createURLRequest{
+				SSHFingerprint:   "sufojfpffhhofjfpjfo",
+				ForceNewEndpoint: true,
+			},
+			plan: &sdump.Plan{Name: "free", MaxEndpoints: 2},
+		},
 		{
 			name: "user already exists, no need to create a new url. could not fetch existing url",
 			mockFn: func(t *testing.T, urlRepo *mocks.MockURLRepository,
@@ -224,13 +436,24 @@ func TestURLHandler_Create(t *testing.T) {
 			urlRepo := mocks.NewMockURLRepository(ctrl)
 			userRepo := mocks.NewMockUserRepository(ctrl)
 
+			plan := v.plan
+			if plan == nil {
+				plan = &sdump.Plan{}
+			}
+
+			planRepo := mocks.NewMockPlanRepository(ctrl)
+			planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(plan, nil)
+
 			v.mockFn(t, urlRepo, userRepo)
 
 			u := &urlHandler{
 				logger:    logger,
-				cfg:       config.Config{},
+				cfg:       config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
 				urlRepo:   urlRepo,
 				userRepo:  userRepo,
+				planRepo:  planRepo,
 				sseServer: sse.New(),
 			}
 
@@ -240,18 +463,43 @@ func TestURLHandler_Create(t *testing.T) {
 
 			if !v.hasDynamicData {
 				verifyMatch(t, recorder)
+				return
 			}
+
+			var response createdURLEndpointResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+
+			// the stream token can only be used to subscribe to the new url
+			claims, err := signature.VerifyToken(testAdminSecret, response.SSE.Token, time.Now())
+			require.NoError(t, err)
+			require.Equal(t, response.SSE.Channel, claims.Audience)
 		})
 	}
 }
 
 func TestURLHandler_Ingest(t *testing.T) {
+	forwardTarget := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer forwardTarget.Close()
+
 	tt := []struct {
 		name               string
 		mockFn             func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository)
 		expectedStatusCode int
 		requestBody        io.Reader
 		requestBodySize    int64
+		contentType        string
+
+		// if provided, the request is sent through the router so the
+		// reference and path can be parsed
+		requestPath string
+
+		isOwnerBanned bool
+
+		// plan the url owner is on. Defaults to a plan without limits
+		plan *sdump.Plan
 	}{
 		{
 			name: "url reference not found",
@@ -263,6 +511,16 @@ func TestURLHandler_Ingest(t *testing.T) {
 			requestBody:        strings.NewReader(``),
 			requestBodySize:    10,
 		},
+		{
+			name: "url is not active",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: false}, nil)
+			},
+			expectedStatusCode: http.StatusGone,
+			requestBody:        strings.NewReader(``),
+			requestBodySize:    10,
+		},
 		{
 			name: "error while fetching url",
 			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
@@ -277,17 +535,86 @@ func TestURLHandler_Ingest(t *testing.T) {
 			name: "http request body too large",
 			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
 				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
-					Times(1).Return(&sdump.URLEndpoint{}, nil)
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
 			},
 			expectedStatusCode: http.StatusBadRequest,
 			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
 			requestBodySize:    10,
 		},
+		{
+			name: "url owner is banned",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
+			},
+			expectedStatusCode: http.StatusForbidden,
+			requestBody:        strings.NewReader(`{"name" : "Lanre"}`),
+			requestBodySize:    100,
+			isOwnerBanned:      true,
+		},
+		{
+			name: "daily request quota exhausted",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
+
+				requestRepo.EXPECT().Count(gomock.Any(), gomock.Any()).
+					Times(1).Return(int64(100), nil)
+			},
+			expectedStatusCode: http.StatusTooManyRequests,
+			requestBody:        strings.NewReader(`{"name" : "Lanre"}`),
+			requestBodySize:    100,
+			plan:               &sdump.Plan{Name: "free", MaxRequestsPerDay: 100},
+		},
+		{
+			name: "http request body larger than the plan allows",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+			plan:               &sdump.Plan{Name: "free", MaxRequestBodySize: 10},
+		},
+		{
+			name: "http request body too large is not counted against the quota",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
+
+				requestRepo.EXPECT().Count(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+			plan: &sdump.Plan{
+				Name:               "free",
+				MaxRequestsPerDay:  100,
+				MaxRequestBodySize: 10,
+			},
+		},
+		{
+			name: "http request body larger than the endpoint allows",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Limits: &sdump.LimitsDefinition{MaxRequestBodySize: 10},
+					},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+		},
 		{
 			name: "could not create ingestion",
 			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
 				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
-					Times(1).Return(&sdump.URLEndpoint{}, nil)
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
 
 				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
 					Times(1).
@@ -301,7 +628,7 @@ func TestURLHandler_Ingest(t *testing.T) {
 			name: "ingested correctly",
 			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
 				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
-					Times(1).Return(&sdump.URLEndpoint{}, nil)
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
 
 				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
 					Times(1).
@@ -311,26 +638,332 @@ func TestURLHandler_Ingest(t *testing.T) {
 			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
 			requestBodySize:    100,
 		},
-	}
-
-	for _, v := range tt {
-		t.Run(v.name, func(t *testing.T) {
-			recorder := httptest.NewRecorder()
+		{
+			name: "capture is paused",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					IsPaused: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Response: &sdump.ResponseDefinition{
+							StatusCode: http.StatusOK,
+							Body:       `{{ json "name" }}`,
+						},
+					},
+				}, nil)
 
-			req := httptest.NewRequest(http.MethodPost, "/", v.requestBody)
+				// neither counted against the quota nor stored
+				requestRepo.EXPECT().Count(gomock.Any(), gomock.Any()).Times(0)
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+			plan:               &sdump.Plan{Name: "free", MaxRequestsPerDay: 100},
+		},
+		{
+			name: "form encoded request ingested correctly",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
 
-			logrus.SetOutput(io.Discard)
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req *sdump.IngestHTTPRequest) error {
+						if req.Request.ContentType != "application/x-www-form-urlencoded" ||
+							req.Request.IsBase64Encoded ||
+							req.Request.Body != "name=Lanre&occupation=Software" {
+							return errors.New("unexpected request definition")
+						}
 
-			logger := logrus.WithField("module", "test")
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusAccepted,
+			requestBody:        strings.NewReader(`name=Lanre&occupation=Software`),
+			requestBodySize:    100,
+			contentType:        "application/x-www-form-urlencoded",
+		},
+		{
+			name: "binary request body is base64 encoded",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
 
-			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req *sdump.IngestHTTPRequest) error {
+						if req.Request.ContentType != "application/octet-stream" ||
+							!req.Request.IsBase64Encoded ||
+							req.Request.Body != "//79AA==" {
+							return errors.New("unexpected request definition")
+						}
 
-			urlRepo := mocks.NewMockURLRepository(ctrl)
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusAccepted,
+			requestBody:        bytes.NewReader([]byte{0xff, 0xfe, 0xfd, 0x00}),
+			requestBodySize:    100,
+			contentType:        "application/octet-stream",
+		},
+		{
+			name: "configured response is sent back",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Response: &sdump.ResponseDefinition{
+							StatusCode: http.StatusOK,
+							Headers: http.Header{
+								"Content-Type": []string{"text/plain"},
+							},
+							Body: "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+						},
+					},
+				}, nil)
 
-			requestRepo := mocks.NewMockIngestRepository(ctrl)
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody:        strings.NewReader(`{"challenge" : "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`),
+			requestBodySize:    100,
+		},
+		{
+			name: "templated response echoes values from the request",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Response: &sdump.ResponseDefinition{
+							StatusCode: http.StatusOK,
+							Headers: http.Header{
+								"Content-Type": []string{"application/json"},
+							},
+							Body: `{"challenge" : "{{ json "challenge" }}"}`,
+						},
+					},
+				}, nil)
 
-			v.mockFn(urlRepo, requestRepo)
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody:        strings.NewReader(`{"challenge" : "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`),
+			requestBodySize:    100,
+		},
+		{
+			name: "json lookup on a non json body renders nothing",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Response: &sdump.ResponseDefinition{
+							StatusCode: http.StatusOK,
+							Body:       `[{{ json "challenge" }}]`,
+						},
+					},
+				}, nil)
+
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody:        strings.NewReader(`challenge=yes`),
+			requestBodySize:    100,
+			contentType:        "application/x-www-form-urlencoded",
+		},
+		{
+			name: "response template could not be rendered",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Response: &sdump.ResponseDefinition{
+							StatusCode: http.StatusOK,
+							Body:       `{{ randomInt 10 1 }}`,
+						},
+					},
+				}, nil)
+
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusAccepted,
+			requestBody:        strings.NewReader(`challenge=yes`),
+			requestBodySize:    100,
+			contentType:        "application/x-www-form-urlencoded",
+		},
+		{
+			name: "request is relayed to the forward target",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Forward: &sdump.ForwardDefinition{
+							URL: forwardTarget.URL,
+						},
+					},
+				}, nil)
+
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+
+				requestRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req *sdump.IngestHTTPRequest) error {
+						if req.Forward == nil ||
+							req.Forward.StatusCode != http.StatusCreated ||
+							req.Forward.Body != `{"name" : "Lanre", "occupation" :"Software"}` {
+							return errors.New("unexpected forward result")
+						}
+
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusAccepted,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+		},
+		{
+			name: "request is delivered through the tunnel",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Tunnel: &sdump.ForwardDefinition{
+							URL: forwardTarget.URL,
+						},
+					},
+				}, nil)
+
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+
+				requestRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req *sdump.IngestHTTPRequest) error {
+						if req.Forward != nil || req.Tunnel == nil ||
+							req.Tunnel.StatusCode != http.StatusCreated {
+							return errors.New("unexpected tunnel result")
+						}
+
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusAccepted,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+		},
+		{
+			name: "forward target errors do not fail the ingestion",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).Return(&sdump.URLEndpoint{
+					IsActive: true,
+					Metadata: sdump.URLEndpointMetadata{
+						Forward: &sdump.ForwardDefinition{
+							URL: "http://127.0.0.1:1",
+						},
+					},
+				}, nil)
+
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+
+				requestRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("could not update request"))
+			},
+			expectedStatusCode: http.StatusAccepted,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+		},
+		{
+			name: "request path is stored",
+			mockFn: func(urlRepo *mocks.MockURLRepository, requestRepo *mocks.MockIngestRepository) {
+				urlRepo.EXPECT().Get(gomock.Any(), &sdump.FindURLOptions{
+					Reference: "cmltfm6g330l5l1vq110",
+				}).
+					Times(1).Return(&sdump.URLEndpoint{IsActive: true}, nil)
+
+				requestRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req *sdump.IngestHTTPRequest) error {
+						if req.Request.Path != "/github/push" ||
+							req.Request.RawURL != "/cmltfm6g330l5l1vq110/github/push?event=push" ||
+							req.Request.Proto != "HTTP/1.1" ||
+							req.Request.Host != "example.com" {
+							return errors.New("unexpected request definition")
+						}
+
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusAccepted,
+			requestBody:        strings.NewReader(`{"name" : "Lanre", "occupation" :"Software"}`),
+			requestBodySize:    100,
+			requestPath:        "/cmltfm6g330l5l1vq110/github/push?event=push",
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			path := "/"
+			if v.requestPath != "" {
+				path = v.requestPath
+			}
+
+			req := httptest.NewRequest(http.MethodPost, path, v.requestBody)
+
+			if v.contentType != "" {
+				req.Header.Set("Content-Type", v.contentType)
+			}
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+
+			requestRepo := mocks.NewMockIngestRepository(ctrl)
+
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(&sdump.User{IsBanned: v.isOwnerBanned}, nil)
+
+			plan := v.plan
+			if plan == nil {
+				plan = &sdump.Plan{}
+			}
+
+			planRepo := mocks.NewMockPlanRepository(ctrl)
+			planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(plan, nil)
+
+			v.mockFn(urlRepo, requestRepo)
 
 			u := &urlHandler{
 				logger: logger,
@@ -339,12 +972,1095 @@ func TestURLHandler_Ingest(t *testing.T) {
 						MaxRequestBodySize: v.requestBodySize,
 					},
 				},
-				urlRepo:    urlRepo,
-				ingestRepo: requestRepo,
-				sseServer:  sse.New(),
+				urlRepo:       urlRepo,
+				userRepo:      userRepo,
+				ingestRepo:    requestRepo,
+				planRepo:      planRepo,
+				sseServer:     sse.New(),
+				publisher:     pubsub.NewMemory(),
+				forwardClient: relay.NewClient(time.Second, true),
+				tunnelClient:  relay.NewClient(time.Second, true),
+				forwarder:     NewForwarder(1, 1),
+				rateLimiter:   newTestRateLimiter(t),
+			}
+
+			if v.requestPath != "" {
+				router := chi.NewRouter()
+				router.Handle("/{reference}/*", http.HandlerFunc(u.ingest))
+				router.ServeHTTP(recorder, req)
+			} else {
+				u.ingest(recorder, req)
+			}
+
+			// forwards finish after the sender was replied to
+			require.NoError(t, u.forwarder.Close(context.Background()))
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_UpdateResponse(t *testing.T) {
+	ownerID := uuid.New()
+
+	tt := []struct {
+		name   string
+		mockFn func(urlRepo *mocks.MockURLRepository,
+			userRepo *mocks.MockUserRepository)
+		expectedStatusCode int
+		requestBody        sdump.ResponseDefinition
+		sshFingerprint     string
+	}{
+		{
+			name:               "ssh fingerprint not provided",
+			expectedStatusCode: http.StatusUnauthorized,
+			mockFn: func(_ *mocks.MockURLRepository, _ *mocks.MockUserRepository) {
+			},
+		},
+		{
+			name: "url does not belong to user",
+			mockFn: func(urlRepo *mocks.MockURLRepository, userRepo *mocks.MockUserRepository) {
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: uuid.New()}, nil)
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+			sshFingerprint:     "sufojfpffhhofjfpjfo",
+			requestBody: sdump.ResponseDefinition{
+				StatusCode: http.StatusOK,
+			},
+		},
+		{
+			name: "invalid status code",
+			mockFn: func(urlRepo *mocks.MockURLRepository, userRepo *mocks.MockUserRepository) {
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: ownerID}, nil)
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			sshFingerprint:     "sufojfpffhhofjfpjfo",
+			requestBody: sdump.ResponseDefinition{
+				StatusCode: 1000,
+			},
+		},
+		{
+			name: "invalid response template",
+			mockFn: func(urlRepo *mocks.MockURLRepository, userRepo *mocks.MockUserRepository) {
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: ownerID}, nil)
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			sshFingerprint:     "sufojfpffhhofjfpjfo",
+			requestBody: sdump.ResponseDefinition{
+				StatusCode: http.StatusOK,
+				Body:       `{{ json "challenge" `,
+			},
+		},
+		{
+			name: "could not update url",
+			mockFn: func(urlRepo *mocks.MockURLRepository, userRepo *mocks.MockUserRepository) {
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: ownerID}, nil)
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+				urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("could not update url"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			sshFingerprint:     "sufojfpffhhofjfpjfo",
+			requestBody: sdump.ResponseDefinition{
+				StatusCode: http.StatusOK,
+			},
+		},
+		{
+			name: "response updated",
+			mockFn: func(urlRepo *mocks.MockURLRepository, userRepo *mocks.MockUserRepository) {
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: ownerID}, nil)
+
+				urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+				urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			sshFingerprint:     "sufojfpffhhofjfpjfo",
+			requestBody: sdump.ResponseDefinition{
+				StatusCode: http.StatusOK,
+				Headers: http.Header{
+					"Content-Type": []string{"text/plain"},
+				},
+				Body: "challenge",
+			},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			b := new(bytes.Buffer)
+
+			err := json.NewEncoder(b).Encode(v.requestBody)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/urls/cmltfm6g330l5l1vq110/response", b)
+			authenticateAsSSHUser(req, v.sshFingerprint)
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			v.mockFn(urlRepo, userRepo)
+
+			u := &urlHandler{
+				logger:    logger,
+				cfg:       config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:   urlRepo,
+				userRepo:  userRepo,
+				sseServer: sse.New(),
 			}
 
-			u.ingest(recorder, req)
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Put("/api/v1/urls/{reference}/response", u.updateResponse)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_UpdateForward(t *testing.T) {
+	ownerID := uuid.New()
+
+	tt := []struct {
+		name               string
+		mockFn             func(urlRepo *mocks.MockURLRepository)
+		expectedStatusCode int
+		requestBody        sdump.ForwardDefinition
+	}{
+		{
+			name: "invalid forward url",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.ForwardDefinition{
+				URL: "localhost:3000",
+			},
+		},
+		{
+			name: "could not update url",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("could not update url"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			requestBody: sdump.ForwardDefinition{
+				URL: "https://example.com/hooks",
+			},
+		},
+		{
+			name: "forward target updated",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody: sdump.ForwardDefinition{
+				URL: "https://example.com/hooks",
+			},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			b := new(bytes.Buffer)
+
+			err := json.NewEncoder(b).Encode(v.requestBody)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/urls/cmltfm6g330l5l1vq110/forward", b)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+			v.mockFn(urlRepo)
+
+			u := &urlHandler{
+				logger:    logger,
+				cfg:       config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:   urlRepo,
+				userRepo:  userRepo,
+				sseServer: sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Put("/api/v1/urls/{reference}/forward", u.updateForward)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_UpdateLimits(t *testing.T) {
+	ownerID := uuid.New()
+
+	tt := []struct {
+		name               string
+		mockFn             func(urlRepo *mocks.MockURLRepository)
+		expectedStatusCode int
+		requestBody        sdump.LimitsDefinition
+		plan               *sdump.Plan
+	}{
+		{
+			name: "negative limits",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.LimitsDefinition{
+				MaxRequestBodySize: -1,
+			},
+		},
+		{
+			name: "body size above the instance limit",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.LimitsDefinition{
+				MaxRequestBodySize: 10 * 1024 * 1024,
+			},
+		},
+		{
+			name: "retention above the plan limit",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.LimitsDefinition{
+				RetentionHours: 36,
+			},
+			plan: &sdump.Plan{RetentionDays: 1},
+		},
+		{
+			name: "could not update url",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("could not update url"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			requestBody: sdump.LimitsDefinition{
+				MaxRequestBodySize: 5 * 1024 * 1024,
+			},
+		},
+		{
+			name: "limits updated",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, endpoint *sdump.URLEndpoint) error {
+						if endpoint.Metadata.Limits.Retention() != 72*time.Hour {
+							return errors.New("unexpected retention")
+						}
+
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody: sdump.LimitsDefinition{
+				MaxRequestBodySize: 5 * 1024 * 1024,
+				RetentionHours:     72,
+			},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			b := new(bytes.Buffer)
+
+			err := json.NewEncoder(b).Encode(v.requestBody)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/urls/cmltfm6g330l5l1vq110/limits", b)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+			plan := v.plan
+			if plan == nil {
+				plan = &sdump.Plan{}
+			}
+
+			planRepo := mocks.NewMockPlanRepository(ctrl)
+			planRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(plan, nil)
+
+			v.mockFn(urlRepo)
+
+			u := &urlHandler{
+				logger: logger,
+				cfg: config.Config{
+					HTTP: config.HTTPConfig{
+						AdminSecret:        testAdminSecret,
+						MaxRequestBodySize: 8 * 1024 * 1024,
+					},
+					Cron: config.CronConfig{
+						TTL: 7 * 24 * time.Hour,
+					},
+				},
+				urlRepo:   urlRepo,
+				userRepo:  userRepo,
+				planRepo:  planRepo,
+				sseServer: sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Put("/api/v1/urls/{reference}/limits", u.updateLimits)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_Rename(t *testing.T) {
+	ownerID := uuid.MustParse("8511ac86-5079-42ae-a030-cb46e6dbfbda")
+
+	tt := []struct {
+		name               string
+		mockFn             func(urlRepo *mocks.MockURLRepository)
+		expectedStatusCode int
+		requestBody        renameURLRequest
+	}{
+		{
+			name: "name is too long",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: renameURLRequest{
+				Name: strings.Repeat("a", 101),
+			},
+		},
+		{
+			name: "name already in use",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Rename(gomock.Any(), gomock.Any(), "github-ci").
+					Times(1).
+					Return(sdump.ErrEndpointNameTaken)
+			},
+			expectedStatusCode: http.StatusConflict,
+			requestBody: renameURLRequest{
+				Name: "github-ci",
+			},
+		},
+		{
+			name: "could not rename url",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Rename(gomock.Any(), gomock.Any(), "github-ci").
+					Times(1).
+					Return(errors.New("could not rename url"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			requestBody: renameURLRequest{
+				Name: "github-ci",
+			},
+		},
+		{
+			name: "url renamed",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Rename(gomock.Any(), gomock.Any(), "github-ci").
+					Times(1).
+					DoAndReturn(func(_ context.Context, endpoint *sdump.URLEndpoint, name string) error {
+						endpoint.Name = name
+						return nil
+					})
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody: renameURLRequest{
+				Name: " github-ci ",
+			},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			b := new(bytes.Buffer)
+
+			err := json.NewEncoder(b).Encode(v.requestBody)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/urls/cmltfm6g330l5l1vq110", b)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{
+					UserID:    ownerID,
+					Reference: "cmltfm6g330l5l1vq110",
+					IsActive:  true,
+					CreatedAt: time.Date(2024, 1, 20, 14, 25, 28, 0, time.UTC),
+					UpdatedAt: time.Date(2024, 1, 20, 14, 25, 28, 0, time.UTC),
+				}, nil)
+
+			v.mockFn(urlRepo)
+
+			u := &urlHandler{
+				logger:   logrus.WithField("module", "test"),
+				cfg:      config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:  urlRepo,
+				userRepo: userRepo,
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Patch("/api/v1/urls/{reference}", u.rename)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_Deactivate(t *testing.T) {
+	ownerID := uuid.MustParse("8511ac86-5079-42ae-a030-cb46e6dbfbda")
+
+	tt := []struct {
+		name               string
+		deactivateErr      error
+		expectedStatusCode int
+	}{
+		{
+			name:               "could not deactivate url",
+			deactivateErr:      errors.New("could not deactivate url"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "url deactivated",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/urls/cmltfm6g330l5l1vq110/deactivate", nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{
+					UserID:    ownerID,
+					Reference: "cmltfm6g330l5l1vq110",
+					IsActive:  true,
+					CreatedAt: time.Date(2024, 1, 20, 14, 25, 28, 0, time.UTC),
+					UpdatedAt: time.Date(2024, 1, 20, 14, 25, 28, 0, time.UTC),
+				}, nil)
+
+			urlRepo.EXPECT().Deactivate(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, endpoint *sdump.URLEndpoint) error {
+					if v.deactivateErr != nil {
+						return v.deactivateErr
+					}
+
+					endpoint.IsActive = false
+					return nil
+				})
+
+			u := &urlHandler{
+				logger:   logrus.WithField("module", "test"),
+				cfg:      config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:  urlRepo,
+				userRepo: userRepo,
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Post("/api/v1/urls/{reference}/deactivate", u.deactivate)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_Pause(t *testing.T) {
+	ownerID := uuid.MustParse("8511ac86-5079-42ae-a030-cb46e6dbfbda")
+
+	tt := []struct {
+		name               string
+		method             string
+		updateErr          error
+		expectedStatusCode int
+		expectedPaused     bool
+	}{
+		{
+			name:               "could not pause capture",
+			method:             http.MethodPut,
+			updateErr:          errors.New("could not update url"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedPaused:     true,
+		},
+		{
+			name:               "capture paused",
+			method:             http.MethodPut,
+			expectedStatusCode: http.StatusOK,
+			expectedPaused:     true,
+		},
+		{
+			name:               "capture resumed",
+			method:             http.MethodDelete,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(v.method, "/api/v1/urls/cmltfm6g330l5l1vq110/pause", nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{
+					UserID:    ownerID,
+					Reference: "cmltfm6g330l5l1vq110",
+					IsActive:  true,
+					IsPaused:  v.method == http.MethodDelete,
+					CreatedAt: time.Date(2024, 1, 20, 14, 25, 28, 0, time.UTC),
+					UpdatedAt: time.Date(2024, 1, 20, 14, 25, 28, 0, time.UTC),
+				}, nil)
+
+			urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, endpoint *sdump.URLEndpoint) error {
+					require.Equal(t, v.expectedPaused, endpoint.IsPaused)
+					return v.updateErr
+				})
+
+			u := &urlHandler{
+				logger:   logrus.WithField("module", "test"),
+				cfg:      config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:  urlRepo,
+				userRepo: userRepo,
+			}
+
+			router := chi.NewRouter()
+			router.Route("/api/v1/urls/{reference}", func(r chi.Router) {
+				r.Use(u.requireEndpointOwner)
+				r.Put("/pause", u.pause)
+				r.Delete("/pause", u.resume)
+			})
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_Stream(t *testing.T) {
+	ownerID := uuid.New()
+
+	recorder := httptest.NewRecorder()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/urls/cmltfm6g330l5l1vq110/stream", nil)
+	authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+	logrus.SetOutput(io.Discard)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	urlRepo := mocks.NewMockURLRepository(ctrl)
+	userRepo := mocks.NewMockUserRepository(ctrl)
+
+	userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(&sdump.User{ID: ownerID}, nil)
+
+	urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(&sdump.URLEndpoint{
+			UserID:    ownerID,
+			Reference: "cmltfm6g330l5l1vq110",
+		}, nil)
+
+	u := &urlHandler{
+		logger:    logrus.WithField("module", "test"),
+		cfg:       config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+		urlRepo:   urlRepo,
+		userRepo:  userRepo,
+		sseServer: sse.New(),
+	}
+
+	router := chi.NewRouter()
+	router.With(u.requireEndpointOwner).
+		Post("/api/v1/urls/{reference}/stream", u.stream)
+
+	router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+	var response streamResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+
+	claims, err := signature.VerifyToken(testAdminSecret, response.SSE.Token, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, "messages.cmltfm6g330l5l1vq110", claims.Audience)
+	require.Equal(t, ownerID.String(), claims.Subject)
+}
+
+func TestURLHandler_UpdateTunnel(t *testing.T) {
+	ownerID := uuid.New()
+
+	tt := []struct {
+		name               string
+		mockFn             func(urlRepo *mocks.MockURLRepository)
+		expectedStatusCode int
+		requestBody        sdump.ForwardDefinition
+		tunnelDisabled     bool
+		useAPIToken        bool
+	}{
+		{
+			name: "tunnels are disabled",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.ForwardDefinition{
+				URL: "http://127.0.0.1:40000/0123456789abcdef0123456789abcdef",
+			},
+			tunnelDisabled: true,
+		},
+		{
+			name: "tunnel url points to another host",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.ForwardDefinition{
+				URL: "http://example.com/0123456789abcdef0123456789abcdef",
+			},
+		},
+		{
+			name: "tunnel url has no token",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.ForwardDefinition{
+				URL: "http://127.0.0.1:5432/",
+			},
+		},
+		{
+			name: "tunnel url points to a port tunnels are not opened on",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			requestBody: sdump.ForwardDefinition{
+				URL: "http://127.0.0.1:6379/0123456789abcdef0123456789abcdef",
+			},
+		},
+		{
+			name: "api tokens cannot update the tunnel",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusForbidden,
+			requestBody: sdump.ForwardDefinition{
+				URL: "http://127.0.0.1:40000/0123456789abcdef0123456789abcdef",
+			},
+			useAPIToken: true,
+		},
+		{
+			name: "tunnel updated",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			requestBody: sdump.ForwardDefinition{
+				URL: "http://127.0.0.1:40000/0123456789abcdef0123456789abcdef",
+			},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			b := new(bytes.Buffer)
+
+			err := json.NewEncoder(b).Encode(v.requestBody)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/urls/cmltfm6g330l5l1vq110/tunnel", b)
+			if v.useAPIToken {
+				req.Header.Set("Authorization", "Bearer sdump_valid")
+			} else {
+				authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+			}
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			apiTokenRepo := mocks.NewMockAPITokenRepository(ctrl)
+
+			if v.useAPIToken {
+				apiTokenRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.APIToken{UserID: ownerID}, nil)
+			}
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+			v.mockFn(urlRepo)
+
+			cfg := config.Config{}
+			cfg.HTTP.AdminSecret = testAdminSecret
+			cfg.SSH.Tunnel.IsEnabled = !v.tunnelDisabled
+			cfg.SSH.Tunnel.AdvertiseHost = "127.0.0.1"
+
+			u := &urlHandler{
+				logger:       logger,
+				cfg:          cfg,
+				urlRepo:      urlRepo,
+				userRepo:     userRepo,
+				apiTokenRepo: apiTokenRepo,
+				sseServer:    sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner, u.requireSSHSession).
+				Put("/api/v1/urls/{reference}/tunnel", u.updateTunnel)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_List(t *testing.T) {
+	ownerID := uuid.New()
+
+	createdAt := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name               string
+		mockFn             func(urlRepo *mocks.MockURLRepository)
+		expectedStatusCode int
+		fingerprint        string
+	}{
+		{
+			name: "ssh fingerprint not provided",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "could not list urls",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().List(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errors.New("could not list urls"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			fingerprint:        "sufojfpffhhofjfpjfo",
+		},
+		{
+			name: "urls listed",
+			mockFn: func(urlRepo *mocks.MockURLRepository) {
+				urlRepo.EXPECT().List(gomock.Any(), &sdump.ListURLOptions{
+					UserID: ownerID,
+				}).
+					Times(1).
+					Return([]sdump.URLEndpoint{
+						{
+							ID:        uuid.MustParse("df1f03c9-1831-442a-9035-0f77bc413ec1"),
+							Reference: "cmltfm6g330l5l1vq110",
+							IsActive:  true,
+							UserID:    ownerID,
+							CreatedAt: createdAt,
+							UpdatedAt: createdAt,
+						},
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			fingerprint:        "sufojfpffhhofjfpjfo",
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/urls", nil)
+			authenticateAsSSHUser(req, v.fingerprint)
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+
+			if v.fingerprint != "" {
+				userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&sdump.User{ID: ownerID}, nil)
+			}
+
+			v.mockFn(urlRepo)
+
+			u := &urlHandler{
+				logger:    logger,
+				cfg:       config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:   urlRepo,
+				userRepo:  userRepo,
+				sseServer: sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireUser).Get("/api/v1/urls", u.list)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+
+			if v.expectedStatusCode == http.StatusOK {
+				// user ids are random
+				return
+			}
+
+			verifyMatch(t, recorder)
+		})
+	}
+}
+
+func TestURLHandler_Delete(t *testing.T) {
+	ownerID := uuid.New()
+
+	tt := []struct {
+		name               string
+		mockFn             func(urlRepo *mocks.MockURLRepository, ingestRepo *mocks.MockIngestRepository)
+		expectedStatusCode int
+	}{
+		{
+			name: "could not delete url requests",
+			mockFn: func(urlRepo *mocks.MockURLRepository, ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), errors.New("could not delete requests"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "could not delete url",
+			mockFn: func(urlRepo *mocks.MockURLRepository, ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(3), nil)
+
+				urlRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("could not delete url"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "url deleted",
+			mockFn: func(urlRepo *mocks.MockURLRepository, ingestRepo *mocks.MockIngestRepository) {
+				ingestRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(3), nil)
+
+				urlRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/urls/cmltfm6g330l5l1vq110", nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			logger := logrus.WithField("module", "test")
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			urlRepo := mocks.NewMockURLRepository(ctrl)
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			ingestRepo := mocks.NewMockIngestRepository(ctrl)
+
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.User{ID: ownerID}, nil)
+
+			urlRepo.EXPECT().Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(&sdump.URLEndpoint{UserID: ownerID}, nil)
+
+			v.mockFn(urlRepo, ingestRepo)
+
+			u := &urlHandler{
+				logger:     logger,
+				cfg:        config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				urlRepo:    urlRepo,
+				userRepo:   userRepo,
+				ingestRepo: ingestRepo,
+				sseServer:  sse.New(),
+			}
+
+			router := chi.NewRouter()
+			router.With(u.requireEndpointOwner).
+				Delete("/api/v1/urls/{reference}", u.delete)
+
+			router.ServeHTTP(recorder, req)
 
 			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
 			verifyMatch(t, recorder)
@@ -0,0 +1,23 @@
+package httpd
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type userHandler struct{}
+
+// me returns the authenticated user. The SSH server uses it to turn away
+// banned users before starting a TUI session
+func (h *userHandler) me(w http.ResponseWriter, r *http.Request) {
+	_, span, _ := getTracer(r.Context(), r, "user.me")
+	defer span.End()
+
+	span.SetStatus(codes.Ok, "fetched user")
+	_ = render.Render(w, r, &userResponse{
+		APIStatus: newAPIStatus(http.StatusOK, "fetched user"),
+		User:      userFromContext(r.Context()),
+	})
+}
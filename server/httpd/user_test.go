@@ -0,0 +1,84 @@
+package httpd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/sdump"
+	"github.com/adelowo/sdump/config"
+	"github.com/adelowo/sdump/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestUserHandler_Me(t *testing.T) {
+	bannedAt := time.Date(2024, 3, 14, 11, 2, 50, 0, time.UTC)
+
+	tt := []struct {
+		name               string
+		user               *sdump.User
+		expectedStatusCode int
+	}{
+		{
+			name: "user is banned",
+			user: &sdump.User{
+				ID:             uuid.MustParse("8511ac86-5079-42ae-a030-cb46e6dbfbda"),
+				SSHFingerPrint: "sufojfpffhhofjfpjfo",
+				IsBanned:       true,
+				BanReason:      "spam",
+				BannedAt:       &bannedAt,
+			},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "user is returned",
+			user: &sdump.User{
+				ID:             uuid.MustParse("8511ac86-5079-42ae-a030-cb46e6dbfbda"),
+				SSHFingerPrint: "sufojfpffhhofjfpjfo",
+				CreatedAt:      time.Date(2024, 3, 12, 9, 30, 0, 0, time.UTC),
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+			authenticateAsSSHUser(req, "sufojfpffhhofjfpjfo")
+
+			logrus.SetOutput(io.Discard)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			userRepo := mocks.NewMockUserRepository(ctrl)
+			userRepo.EXPECT().Find(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(v.user, nil)
+
+			u := &urlHandler{
+				logger:   logrus.WithField("module", "test"),
+				cfg:      config.Config{HTTP: config.HTTPConfig{AdminSecret: testAdminSecret}},
+				userRepo: userRepo,
+			}
+
+			h := &userHandler{}
+
+			router := chi.NewRouter()
+			router.With(u.requireUser).Get("/api/v1/users/me", h.me)
+
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, v.expectedStatusCode, recorder.Result().StatusCode)
+			verifyMatch(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package sdump
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	ErrAPITokenNotFound = appError("api token not found")
+	ErrInvalidTokenName = appError("token name must be between 1 and 50 characters")
+)
+
+// APITokenPrefix makes tokens easy to recognize i.e when scanning for leaked
+// secrets
+const APITokenPrefix = "sdump_"
+
+// APIToken authenticates HTTP API requests on behalf of a user. Only a hash of
+// the token is stored
+type APIToken struct {
+	ID     uuid.UUID `bun:"type:uuid,pk,default:uuid_generate_v4()" json:"id,omitempty"`
+	UserID uuid.UUID `json:"user_id,omitempty"`
+	Name   string    `json:"name,omitempty"`
+	Hash   string    `json:"-"`
+
+	// Prefix is the start of the token so users can tell their tokens apart
+	Prefix string `json:"prefix,omitempty"`
+
+	CreatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"created_at,omitempty" bson:"created_at"`
+	UpdatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"updated_at,omitempty" bson:"updated_at"`
+	DeletedAt *time.Time `bun:",soft_delete,nullzero" json:"-,omitempty" bson:"deleted_at"`
+
+	bun.BaseModel `bun:"table:api_tokens"`
+}
+
+// NewAPIToken generates a token for the user. The plain text token is
+// returned alongside the model and cannot be recovered afterwards
+func NewAPIToken(userID uuid.UUID, name string) (*APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 50 {
+		return nil, "", ErrInvalidTokenName
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	return &APIToken{
+		UserID: userID,
+		Name:   name,
+		Hash:   HashAPIToken(token),
+		Prefix: token[:len(APITokenPrefix)+6],
+	}, token, nil
+}
+
+func HashAPIToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+type FindAPITokenOptions struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Hash   string
+}
+
+type APITokenRepository interface {
+	Create(context.Context, *APIToken) error
+	Find(context.Context, *FindAPITokenOptions) (*APIToken, error)
+	List(context.Context, uuid.UUID) ([]APIToken, error)
+	Revoke(context.Context, *APIToken) error
+}
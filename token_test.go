@@ -0,0 +1,22 @@
+package sdump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIToken(t *testing.T) {
+	_, _, err := NewAPIToken(uuid.New(), "  ")
+	require.ErrorIs(t, err, ErrInvalidTokenName)
+
+	token, plainText, err := NewAPIToken(uuid.New(), "ci")
+	require.NoError(t, err)
+
+	require.True(t, strings.HasPrefix(plainText, APITokenPrefix))
+	require.True(t, strings.HasPrefix(plainText, token.Prefix))
+	require.Equal(t, HashAPIToken(plainText), token.Hash)
+	require.NotContains(t, token.Hash, plainText)
+}
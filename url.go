@@ -3,7 +3,11 @@ package sdump
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/rs/xid"
@@ -15,16 +19,166 @@ type appError string
 func (a appError) Error() string { return string(a) }
 
 const (
-	ErrURLEndpointNotFound = appError("endpoint not found")
+	ErrURLEndpointNotFound     = appError("endpoint not found")
+	ErrInvalidResponseStatus   = appError("response status code must be between 100 and 599")
+	ErrResponseDelayOutOfRange = appError("response delay must be between 0 and 30 seconds")
+	ErrInvalidForwardURL       = appError("forward url must be a valid http or https url")
+	ErrInvalidTunnelURL        = appError("tunnel url must point to the SSH server")
+	ErrRequestBodySizeTooLarge = appError("max request body size is larger than allowed")
+	ErrRetentionTooLong        = appError("retention is longer than allowed")
+	ErrNegativeLimit           = appError("limits cannot be negative")
+	ErrInvalidReference        = appError("reference must be 3 to 64 lowercase letters, numbers or dashes and cannot start or end with a dash")
+	ErrReservedReference       = appError("reference is reserved")
+	ErrReferenceTaken          = appError("reference is already in use")
+	ErrInvalidEndpointName     = appError("name must be at most 100 characters")
+	ErrEndpointNameTaken       = appError("you already have an endpoint with this name")
 )
 
-type URLEndpointMetadata struct{}
+var referenceRegexp = regexp.MustCompile("^[a-z0-9]([a-z0-9-]{1,62})[a-z0-9]$")
+
+// reservedReferences cannot be used as vanity references as they clash
+// with the HTTP server's own routes
+var reservedReferences = map[string]bool{
+	"api":     true,
+	"events":  true,
+	"metrics": true,
+}
+
+// ValidateReference checks a custom vanity reference can be used in a url
+func ValidateReference(reference string) error {
+	if !referenceRegexp.MatchString(reference) {
+		return ErrInvalidReference
+	}
+
+	if reservedReferences[reference] {
+		return ErrReservedReference
+	}
+
+	return nil
+}
+
+func ValidateEndpointName(name string) error {
+	if utf8.RuneCountInString(name) > 100 {
+		return ErrInvalidEndpointName
+	}
+
+	return nil
+}
+
+// MaxResponseDelay is the longest an endpoint can be configured to wait
+// before replying to an ingested request
+const MaxResponseDelay = 30 * time.Second
+
+// ResponseDefinition describes how an endpoint replies to the requests it
+// ingests. It allows mocking the responses webhook providers expect when
+// verifying a url
+type ResponseDefinition struct {
+	StatusCode int         `json:"status_code,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	DelayInMS  int64       `json:"delay_ms,omitempty"`
+}
+
+func (r *ResponseDefinition) Delay() time.Duration {
+	return time.Duration(r.DelayInMS) * time.Millisecond
+}
+
+func (r *ResponseDefinition) Validate() error {
+	if r.StatusCode < 100 || r.StatusCode > 599 {
+		return ErrInvalidResponseStatus
+	}
+
+	if r.DelayInMS < 0 || r.Delay() > MaxResponseDelay {
+		return ErrResponseDelayOutOfRange
+	}
+
+	return nil
+}
+
+// ForwardDefinition configures where ingested requests are relayed to
+type ForwardDefinition struct {
+	URL string `json:"url,omitempty"`
+}
+
+func (f *ForwardDefinition) Validate() error {
+	u, err := url.Parse(f.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidForwardURL
+	}
+
+	return nil
+}
+
+// LimitsDefinition overrides the instance wide limits for a single
+// endpoint. Zero values fall back to the instance limits, which are also
+// the upper bounds
+type LimitsDefinition struct {
+	MaxRequestBodySize int64 `json:"max_request_body_size,omitempty"`
+	RetentionHours     int64 `json:"retention_hours,omitempty"`
+}
+
+func (l *LimitsDefinition) Retention() time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	return time.Duration(l.RetentionHours) * time.Hour
+}
+
+// RequestBodySize is the largest request body the endpoint can ingest
+// given the upper bound
+func (l *LimitsDefinition) RequestBodySize(upperBound int64) int64 {
+	if l == nil || l.MaxRequestBodySize <= 0 || l.MaxRequestBodySize > upperBound {
+		return upperBound
+	}
+
+	return l.MaxRequestBodySize
+}
+
+func (l *LimitsDefinition) Validate(maxRequestBodySize int64, maxRetention time.Duration) error {
+	if l.MaxRequestBodySize < 0 || l.RetentionHours < 0 {
+		return ErrNegativeLimit
+	}
+
+	if l.MaxRequestBodySize > maxRequestBodySize {
+		return ErrRequestBodySizeTooLarge
+	}
+
+	if l.Retention() > maxRetention {
+		return ErrRetentionTooLong
+	}
+
+	return nil
+}
+
+type URLEndpointMetadata struct {
+	// Response is what ingested requests will be replied with.
+	// If nil, a default 202 is sent
+	Response *ResponseDefinition `json:"response,omitempty"`
+
+	// Forward if provided relays every ingested request to another server
+	Forward *ForwardDefinition `json:"forward,omitempty"`
+
+	// Tunnel is where the owner's reverse SSH tunnel can be reached.
+	// It is set by the SSH server while the tunnel is open
+	Tunnel *ForwardDefinition `json:"tunnel,omitempty"`
+
+	// Limits if provided lowers the instance wide limits for this endpoint
+	Limits *LimitsDefinition `json:"limits,omitempty"`
+}
 
 type URLEndpoint struct {
-	ID        uuid.UUID `bun:"type:uuid,default:uuid_generate_v4()" json:"id,omitempty"`
+	ID        uuid.UUID `bun:"type:uuid,pk,default:uuid_generate_v4()" json:"id,omitempty"`
 	Reference string    `json:"reference,omitempty"`
-	IsActive  bool      `json:"is_active,omitempty"`
-	UserID    uuid.UUID `json:"user_id,omitempty"`
+
+	// Name helps the user tell their endpoints apart. It is unique per user
+	Name     string    `bun:",nullzero" json:"name,omitempty"`
+	IsActive bool      `json:"is_active,omitempty"`
+	UserID   uuid.UUID `json:"user_id,omitempty"`
+
+	// IsPaused stops requests from being stored or streamed. They still
+	// get the configured response
+	IsPaused bool `json:"is_paused,omitempty"`
 
 	Metadata URLEndpointMetadata `json:"metadata,omitempty"`
 
@@ -45,13 +199,68 @@ func NewURLEndpoint(userID uuid.UUID) *URLEndpoint {
 	}
 }
 
+func NewNamedURLEndpoint(userID uuid.UUID, opts NewURLEndpointOptions) (*URLEndpoint, error) {
+	endpoint := NewURLEndpoint(userID)
+
+	if err := ValidateEndpointName(opts.Name); err != nil {
+		return nil, err
+	}
+
+	endpoint.Name = opts.Name
+
+	if opts.Reference != "" {
+		if err := ValidateReference(opts.Reference); err != nil {
+			return nil, err
+		}
+
+		endpoint.Reference = opts.Reference
+	}
+
+	return endpoint, nil
+}
+
 type FindURLOptions struct {
 	Reference string
 	ID        uuid.UUID
 }
 
+// NewURLEndpointOptions customizes a new endpoint
+type NewURLEndpointOptions struct {
+	Name string
+
+	// Reference is a custom vanity reference. A random one is generated
+	// if empty
+	Reference string
+}
+
+// ListURLOptions filters endpoints. Empty options list every endpoint
+type ListURLOptions struct {
+	UserID uuid.UUID
+
+	// HasRetention limits the result to endpoints with a retention
+	// override
+	HasRetention bool
+
+	// IsActive limits the result to endpoints that still ingest requests
+	IsActive bool
+}
+
 type URLRepository interface {
+	// Create returns ErrReferenceTaken or ErrEndpointNameTaken if the
+	// reference or name is already in use
 	Create(context.Context, *URLEndpoint) error
+	Update(context.Context, *URLEndpoint) error
+	Delete(context.Context, *URLEndpoint) error
+	List(context.Context, *ListURLOptions) ([]URLEndpoint, error)
 	Get(context.Context, *FindURLOptions) (*URLEndpoint, error)
+
+	// Latest returns the user's newest active endpoint
 	Latest(context.Context, uuid.UUID) (*URLEndpoint, error)
+
+	// Rename returns ErrEndpointNameTaken if the user has another endpoint
+	// with the name
+	Rename(context.Context, *URLEndpoint, string) error
+
+	// Deactivate stops the endpoint from ingesting requests
+	Deactivate(context.Context, *URLEndpoint) error
 }
@@ -0,0 +1,83 @@
+package sdump
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsDefinition_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		limits LimitsDefinition
+		err    error
+	}{
+		{
+			name:   "negative body size",
+			limits: LimitsDefinition{MaxRequestBodySize: -1},
+			err:    ErrNegativeLimit,
+		},
+		{
+			name:   "body size above the upper bound",
+			limits: LimitsDefinition{MaxRequestBodySize: 2048},
+			err:    ErrRequestBodySizeTooLarge,
+		},
+		{
+			name:   "retention above the upper bound",
+			limits: LimitsDefinition{RetentionHours: 49},
+			err:    ErrRetentionTooLong,
+		},
+		{
+			name:   "within the upper bounds",
+			limits: LimitsDefinition{MaxRequestBodySize: 1024, RetentionHours: 48},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			err := v.limits.Validate(1024, 48*time.Hour)
+			if v.err != nil {
+				require.ErrorIs(t, err, v.err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLimitsDefinition_RequestBodySize(t *testing.T) {
+	var limits *LimitsDefinition
+	require.Equal(t, int64(100), limits.RequestBodySize(100))
+
+	require.Equal(t, int64(50), (&LimitsDefinition{MaxRequestBodySize: 50}).RequestBodySize(100))
+	require.Equal(t, int64(100), (&LimitsDefinition{MaxRequestBodySize: 500}).RequestBodySize(100))
+}
+
+func TestValidateReference(t *testing.T) {
+	tt := []struct {
+		reference string
+		err       error
+	}{
+		{reference: "stripe-staging"},
+		{reference: "cmltfm6g330l5l1vq110"},
+		{reference: "ab", err: ErrInvalidReference},
+		{reference: "-stripe", err: ErrInvalidReference},
+		{reference: "Stripe", err: ErrInvalidReference},
+		{reference: "stripe/staging", err: ErrInvalidReference},
+		{reference: "events", err: ErrReservedReference},
+	}
+
+	for _, v := range tt {
+		t.Run(v.reference, func(t *testing.T) {
+			err := ValidateReference(v.reference)
+			if v.err != nil {
+				require.ErrorIs(t, err, v.err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
@@ -11,9 +11,14 @@ import (
 const (
 	ErrPlanNotFound     = appError("plan does not exists")
 	ErrUserNotFound     = appError("user not found")
+	ErrUserBanned       = appError("your account has been banned")
 	ErrCounterExhausted = appError("no more units left")
 )
 
+// ErrorCodeUserBanned is the code of the API error returned to banned
+// users. Other authorization failures share its status code
+const ErrorCodeUserBanned = "user_banned"
+
 type Counter int64
 
 func (c *Counter) Add() {
@@ -39,10 +44,22 @@ func (c *Counter) TakeN(n int64) error {
 }
 
 type User struct {
-	ID             uuid.UUID `bun:"type:uuid,default:uuid_generate_v4()" json:"id,omitempty"`
+	ID             uuid.UUID `bun:"type:uuid,pk,default:uuid_generate_v4()" json:"id,omitempty"`
 	SSHFingerPrint string    `json:"ssh_finger_print,omitempty"`
 	IsBanned       bool      `json:"is_banned,omitempty"`
 
+	// PlanID is empty for users on the default plan
+	PlanID uuid.UUID `bun:"type:uuid,nullzero" json:"plan_id,omitempty"`
+
+	// IngestRequestsPerMinute and APIRequestsPerMinute take precedence over
+	// the limits of the user's plan when set
+	IngestRequestsPerMinute int64 `json:"ingest_requests_per_minute,omitempty"`
+	APIRequestsPerMinute    int64 `json:"api_requests_per_minute,omitempty"`
+
+	// BanReason is optionally provided by the admin that banned the user
+	BanReason string     `json:"ban_reason,omitempty"`
+	BannedAt  *time.Time `bun:",nullzero" json:"banned_at,omitempty"`
+
 	CreatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"created_at,omitempty" bson:"created_at"`
 	UpdatedAt time.Time  `bun:",nullzero,notnull,default:current_timestamp" json:"updated_at,omitempty" bson:"updated_at"`
 	DeletedAt *time.Time `bun:",soft_delete,nullzero" json:"-,omitempty" bson:"deleted_at"`
@@ -50,11 +67,51 @@ type User struct {
 	bun.BaseModel `bun:"table:users"`
 }
 
+// Ban stops the user from creating urls, using the TUI and receiving
+// requests
+func (u *User) Ban(reason string) {
+	now := time.Now()
+
+	u.IsBanned = true
+	u.BanReason = reason
+	u.BannedAt = &now
+}
+
+func (u *User) Unban() {
+	u.IsBanned = false
+	u.BanReason = ""
+	u.BannedAt = nil
+}
+
+func (u *User) ingestRequestsPerMinute() int64 {
+	if u == nil {
+		return 0
+	}
+
+	return u.IngestRequestsPerMinute
+}
+
+func (u *User) apiRequestsPerMinute() int64 {
+	if u == nil {
+		return 0
+	}
+
+	return u.APIRequestsPerMinute
+}
+
 type FindUserOptions struct {
 	SSHKeyFingerprint string
+	ID                uuid.UUID
+}
+
+type ListUserOptions struct {
+	// OnlyBanned limits the result to banned users
+	OnlyBanned bool
 }
 
 type UserRepository interface {
 	Create(context.Context, *User) error
 	Find(context.Context, *FindUserOptions) (*User, error)
+	Update(context.Context, *User) error
+	List(context.Context, *ListUserOptions) ([]User, error)
 }
@@ -74,3 +74,19 @@ func TestCounter_Add(t *testing.T) {
 		})
 	}
 }
+
+func TestUser_Ban(t *testing.T) {
+	u := &User{}
+
+	u.Ban("spam")
+
+	require.True(t, u.IsBanned)
+	require.Equal(t, "spam", u.BanReason)
+	require.NotNil(t, u.BannedAt)
+
+	u.Unban()
+
+	require.False(t, u.IsBanned)
+	require.Empty(t, u.BanReason)
+	require.Nil(t, u.BannedAt)
+}